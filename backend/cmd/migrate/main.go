@@ -0,0 +1,137 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "log"
+    "os"
+    "strconv"
+    "rideshare-backend/internal/config"
+    "rideshare-backend/internal/database"
+    "rideshare-backend/internal/migrate"
+)
+
+const usage = `Usage: migrate [-dir DIR] COMMAND
+
+Migrations are read from DIR if given, otherwise the ones built into the
+binary are used. New migrations are created in DIR.
+
+Commands:
+  up             apply all pending migrations
+  down [N]       revert the last N migrations (default 1)
+  status         list migrations and whether they are applied
+  create NAME    write empty up and down files for a new migration
+  force VERSION  mark migrations up to VERSION as applied without running them
+`
+
+func main() {
+    dir := flag.String("dir", "", "directory holding the migration files")
+    flag.Usage = func() {
+        fmt.Fprint(os.Stderr, usage)
+        flag.PrintDefaults()
+    }
+    flag.Parse()
+    
+    args := flag.Args()
+    if len(args) == 0 {
+        flag.Usage()
+        os.Exit(2)
+    }
+    
+    // Creating a migration doesn't need the database
+    if args[0] == "create" {
+        if len(args) != 2 {
+            flag.Usage()
+            os.Exit(2)
+        }
+        if *dir == "" {
+            *dir = "internal/database/migrations"
+        }
+        up, down, err := migrate.Create(*dir, args[1])
+        if err != nil {
+            log.Fatal(err)
+        }
+        fmt.Printf("Created %s\nCreated %s\n", up, down)
+        return
+    }
+    
+    cfg := config.Load()
+    
+    db, err := database.Connect(cfg.DatabaseURL, cfg.DatabasePool)
+    if err != nil {
+        log.Fatal(err)
+    }
+    defer db.Close()
+    
+    migrations := database.Migrations()
+    if *dir != "" {
+        migrations = os.DirFS(*dir)
+    }
+    
+    migrator, err := migrate.New(db, migrations)
+    if err != nil {
+        log.Fatal(err)
+    }
+    
+    ctx := context.Background()
+    
+    switch args[0] {
+    case "up":
+        count, err := migrator.Up(ctx)
+        if err != nil {
+            log.Fatal(err)
+        }
+        fmt.Printf("Applied %d migrations\n", count)
+        
+    case "down":
+        steps := 1
+        if len(args) > 1 {
+            if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+                log.Fatalf("Invalid number of steps: %s", args[1])
+            }
+        }
+        count, err := migrator.Down(ctx, steps)
+        if err != nil {
+            log.Fatal(err)
+        }
+        fmt.Printf("Reverted %d migrations\n", count)
+        
+    case "status":
+        statuses, err := migrator.Status(ctx)
+        if err != nil {
+            log.Fatal(err)
+        }
+        for _, status := range statuses {
+            state := "pending"
+            if status.AppliedAt != nil {
+                state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+            }
+            if status.Modified {
+                state += " (modified since)"
+            }
+            if status.Missing {
+                state += " (file missing)"
+            }
+            fmt.Printf("%4d  %-32s %s\n", status.Version, status.Name, state)
+        }
+        
+    case "force":
+        if len(args) != 2 {
+            flag.Usage()
+            os.Exit(2)
+        }
+        version, err := strconv.Atoi(args[1])
+        if err != nil {
+            log.Fatalf("Invalid version: %s", args[1])
+        }
+        if err := migrator.Force(ctx, version); err != nil {
+            log.Fatal(err)
+        }
+        fmt.Printf("Marked migrations up to %d as applied\n", version)
+        
+    default:
+        flag.Usage()
+        os.Exit(2)
+    }
+}
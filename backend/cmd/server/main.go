@@ -1,34 +1,128 @@
 package main
 
 import (
+    "context"
     "database/sql"
     "log"
+    "time"
+    _ "time/tzdata"
     "rideshare-backend/internal/config"
+    "rideshare-backend/internal/database"
+    "rideshare-backend/internal/events"
     "rideshare-backend/internal/handlers"
+    "rideshare-backend/internal/repository"
+    "rideshare-backend/internal/services"
 
     "github.com/gin-gonic/gin"
     "github.com/rs/cors"
-    _ "github.com/lib/pq"
 )
 
 func main() {
     // Load configuration
     cfg := config.Load()
     
-    // Connect to database (simple connection)
-    db, err := sql.Open("postgres", cfg.DatabaseURL)
+    // Connect to database
+    db, err := database.Connect(cfg.DatabaseURL, cfg.DatabasePool)
     if err != nil {
         log.Fatal("Failed to connect to database:", err)
     }
     defer db.Close()
     
-    // Test database connection
-    if err := db.Ping(); err != nil {
-        log.Fatal("Failed to ping database:", err)
+    // Searches and trip listings can go to a read replica. It is optional,
+    // so the server starts even if it is down and reads from the primary
+    // until it answers
+    var replica *sql.DB
+    if cfg.ReplicaDatabaseURL != "" {
+        replica, err = database.Open(cfg.ReplicaDatabaseURL, cfg.DatabasePool)
+        if err != nil {
+            log.Fatal("Failed to open read replica:", err)
+        }
+        defer replica.Close()
+    }
+    
+    // Bring the schema up to date if asked to, and refuse to serve on an
+    // outdated one
+    if cfg.AutoMigrate {
+        if err := database.RunMigrations(context.Background(), db); err != nil {
+            log.Fatal("Failed to run migrations:", err)
+        }
+    }
+    if err := database.CheckSchema(context.Background(), db); err != nil {
+        log.Fatal("Database schema is not up to date:", err)
+    }
+    
+    // In-process pub/sub for real-time trip events
+    broker := events.NewMemoryBroker()
+    
+    // No real payment network is wired up yet
+    paymentProvider := services.NewFakePaymentProvider()
+    
+    // Initialize services
+    store := repository.NewPostgresStore(db)
+    var replicaStore repository.Store
+    if replica != nil {
+        replicaStore = repository.NewPostgresStore(replica)
     }
+    router := repository.NewRouter(store, replicaStore, cfg.ReplicaStickiness)
+    if replica != nil {
+        router.CheckReplica(context.Background(), replica.PingContext)
+    }
+    emailService := services.NewEmailService(cfg)
+    tripService := services.NewTripService(router)
+    authService := services.NewAuthService(store, cfg)
+    auditService := services.NewAuditService(store)
+    waitlistService := services.NewWaitlistService(router, emailService, broker, cfg.WaitlistOfferTTL)
+    preferenceService := services.NewPreferenceService(store)
+    paymentService := services.NewPaymentService(store, cfg, paymentProvider)
+    vehicleService := services.NewVehicleService(store)
+    messageService := services.NewMessageService(store)
+    locationService := services.NewLocationService(store, cfg)
+    fareService := services.NewFareService(cfg)
+    
+    // Start background workers
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    
+    notifier := services.NewMessageNotifier(
+        messageService,
+        emailService,
+        cfg.MessageNotifyDelay,
+        cfg.MessageNotifyInterval,
+    )
+    go notifier.Run(ctx)
+    
+    reminder := services.NewDepartureReminder(
+        tripService,
+        broker,
+        cfg.DepartureReminderLead,
+        cfg.DepartureReminderInterval,
+    )
+    go reminder.Run(ctx)
+    
+    if replica != nil {
+        go router.WatchReplica(ctx, cfg.ReplicaCheckInterval, replica.PingContext)
+    }
+    
+    go services.NewSeatHoldSweeper(tripService, waitlistService, cfg.SeatHoldSweepInterval).Run(ctx)
+    
+    go locationService.RunRetention(ctx, time.Minute)
+    
+    go paymentService.RunRefundRetries(ctx, cfg.RefundRetryInterval)
     
     // Initialize Gin router
-    r := gin.Default()
+    r := gin.New()
+    r.Use(handlers.AccessLogger(), gin.Recovery())
+    r.Use(handlers.RequestIDMiddleware())
+    
+    // Give requests a deadline, except the event streams which stay open for
+    // as long as the client listens
+    routeTimeouts := map[string]time.Duration{}
+    for route, timeout := range cfg.RouteTimeouts {
+        routeTimeouts[route] = timeout
+    }
+    routeTimeouts["GET /api/v1/events"] = 0
+    routeTimeouts["GET /api/v1/trips/:id/location/stream"] = 0
+    r.Use(handlers.DeadlineMiddleware(cfg.RequestTimeout, routeTimeouts))
     
     // Setup CORS
     c := cors.New(cors.Options{
@@ -43,9 +137,31 @@ func main() {
     })
     
     // Initialize handlers
-    authHandler := handlers.NewAuthHandler(db, cfg)
-    tripHandler := handlers.NewTripHandler(db)
-    userHandler := handlers.NewUserHandler(db)
+    authHandler := handlers.NewAuthHandler(cfg, authService)
+    tripHandler := handlers.NewTripHandler(
+        cfg,
+        broker,
+        tripService,
+        paymentService,
+        vehicleService,
+        authService,
+        preferenceService,
+        waitlistService,
+        emailService,
+    )
+    userHandler := handlers.NewUserHandler(authService, preferenceService, tripService)
+    messageHandler := handlers.NewMessageHandler(messageService, tripService, broker)
+    eventHandler := handlers.NewEventHandler(broker, tripService)
+    locationHandler := handlers.NewLocationHandler(locationService, tripService, broker)
+    paymentHandler := handlers.NewPaymentHandler(paymentService)
+    fareHandler := handlers.NewFareHandler(fareService, vehicleService)
+    vehicleHandler := handlers.NewVehicleHandler(vehicleService)
+    auditHandler := handlers.NewAuditHandler(auditService)
+    healthHandler := handlers.NewHealthHandler(db, replica)
+    
+    // Health checks for the load balancer and orchestrator
+    r.GET("/healthz", healthHandler.Liveness)
+    r.GET("/readyz", healthHandler.Readiness)
     
     // Setup routes
     api := r.Group("/api/v1")
@@ -58,6 +174,10 @@ func main() {
             auth.GET("/me", handlers.AuthMiddleware(cfg.JWTSecret), authHandler.GetCurrentUser)
         }
         
+        // Real-time event stream (Server-Sent Events)
+        api.GET("/events", handlers.StreamAuthMiddleware(cfg.JWTSecret), eventHandler.Stream)
+        api.GET("/trips/:id/location/stream", handlers.StreamAuthMiddleware(cfg.JWTSecret), locationHandler.StreamLocation)
+        
         // Protected routes
         protected := api.Group("/")
         protected.Use(handlers.AuthMiddleware(cfg.JWTSecret))
@@ -67,11 +187,34 @@ func main() {
             {
                 trips.GET("", tripHandler.GetTrips)
                 trips.POST("", tripHandler.CreateTrip)
+                trips.GET("/timeline", tripHandler.GetTimeline)
                 trips.GET("/:id", tripHandler.GetTrip)
                 trips.PUT("/:id", tripHandler.UpdateTrip)
                 trips.DELETE("/:id", tripHandler.DeleteTrip)
+                trips.POST("/:id/hold", tripHandler.HoldSeats)
+                trips.DELETE("/:id/hold", tripHandler.ReleaseHold)
                 trips.POST("/:id/join", tripHandler.JoinTrip)
+                trips.POST("/:id/waitlist", tripHandler.JoinWaitlist)
+                trips.DELETE("/:id/waitlist", tripHandler.LeaveWaitlist)
+                trips.POST("/:id/leave", tripHandler.LeaveTrip)
+                trips.POST("/:id/passengers/:passengerId/approve", tripHandler.ApproveBooking)
+                trips.POST("/:id/passengers/:passengerId/decline", tripHandler.DeclineBooking)
+                trips.POST("/:id/passengers/:passengerId/remove", tripHandler.RemovePassenger)
+                trips.GET("/:id/changes", tripHandler.GetTripChanges)
+                trips.POST("/:id/changes/:changeId/respond", tripHandler.RespondToChange)
+                trips.POST("/:id/start", tripHandler.StartTrip)
+                trips.POST("/:id/complete", tripHandler.CompleteTrip)
                 trips.POST("/search", tripHandler.SearchTrips)
+                
+                // Trip conversation
+                trips.GET("/:id/messages", messageHandler.GetMessages)
+                trips.POST("/:id/messages", messageHandler.PostMessage)
+                trips.POST("/:id/messages/read", messageHandler.MarkRead)
+                
+                // Live location sharing
+                trips.POST("/:id/location", locationHandler.UpdateLocation)
+                trips.GET("/:id/location", locationHandler.GetLocation)
+                trips.PUT("/:id/pickup", locationHandler.SetPickupPoint)
             }
             
             // User routes
@@ -79,6 +222,38 @@ func main() {
             {
                 users.GET("/profile", userHandler.GetProfile)
                 users.PUT("/profile", userHandler.UpdateProfile)
+                users.GET("/preferences", userHandler.GetPreferences)
+                users.PUT("/preferences", userHandler.UpdatePreferences)
+            }
+            
+            // Payment routes
+            payments := protected.Group("/payments")
+            {
+                payments.GET("/balances", paymentHandler.GetBalances)
+                payments.GET("/transactions", paymentHandler.GetTransactions)
+            }
+            
+            // Fare routes
+            fares := protected.Group("/fares")
+            {
+                fares.POST("/suggest", fareHandler.SuggestFare)
+            }
+            
+            // Vehicle routes
+            vehicles := protected.Group("/vehicles")
+            {
+                vehicles.GET("", vehicleHandler.GetVehicles)
+                vehicles.POST("", vehicleHandler.CreateVehicle)
+                vehicles.GET("/:id", vehicleHandler.GetVehicle)
+                vehicles.PUT("/:id", vehicleHandler.UpdateVehicle)
+                vehicles.DELETE("/:id", vehicleHandler.DeleteVehicle)
+            }
+            
+            // Admin routes
+            admin := protected.Group("/admin")
+            admin.Use(handlers.AdminMiddleware(authService))
+            {
+                admin.GET("/audit", auditHandler.GetEvents)
             }
         }
     }
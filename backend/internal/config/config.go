@@ -1,12 +1,26 @@
 package config
 
 import (
+    "log"
     "os"
     "strconv"
+    "strings"
+    "time"
+    
+    "rideshare-backend/internal/money"
     
     "github.com/joho/godotenv"
 )
 
+// PoolConfig sizes a database connection pool. Zero values keep the
+// database/sql defaults.
+type PoolConfig struct {
+    MaxOpenConns    int
+    MaxIdleConns    int
+    ConnMaxLifetime time.Duration
+    ConnMaxIdleTime time.Duration
+}
+
 type Config struct {
     DatabaseURL    string
     JWTSecret      string
@@ -17,6 +31,61 @@ type Config struct {
     EmailPassword  string
     FrontendURL    string
     Environment    string
+    
+    // Connection pool of the database
+    DatabasePool PoolConfig
+    
+    // Optional read replica for searches and trip listings. Users who just
+    // changed a trip read from the primary for ReplicaStickiness, and
+    // everyone does while the replica doesn't answer; it is checked every
+    // ReplicaCheckInterval.
+    ReplicaDatabaseURL   string
+    ReplicaStickiness    time.Duration
+    ReplicaCheckInterval time.Duration
+    
+    // Apply pending migrations when the server starts
+    AutoMigrate bool
+    
+    // How long a request may take, zero for no limit, and limits for
+    // particular routes keyed by method and route, e.g. "GET /api/v1/trips"
+    RequestTimeout time.Duration
+    RouteTimeouts  map[string]time.Duration
+    
+    // How long a trip message may stay unread before participants are emailed
+    MessageNotifyDelay    time.Duration
+    MessageNotifyInterval time.Duration
+    
+    // How long before departure trip participants receive a reminder event
+    DepartureReminderLead     time.Duration
+    DepartureReminderInterval time.Duration
+    
+    // Live location sharing
+    LocationRetention     time.Duration
+    AverageDriverSpeedKmh float64
+    RouteDistanceFactor   float64
+    
+    // Payments and refund rules
+    PlatformFeePercent   int
+    FullRefundBefore     time.Duration
+    PartialRefundBefore  time.Duration
+    PartialRefundPercent int
+    
+    // How often refunds the payment provider failed to pay are tried again
+    RefundRetryInterval time.Duration
+    
+    // How long seats are held for a passenger while they book
+    SeatHoldTTL           time.Duration
+    SeatHoldSweepInterval time.Duration
+    
+    // How long a waitlisted passenger has to confirm seats offered to them
+    WaitlistOfferTTL time.Duration
+    
+    // Moving a departure by more than this lets passengers cancel for free
+    TripChangeMaterialShift time.Duration
+    
+    // Defaults for cost-sharing fare suggestions
+    FuelPrice      money.Rate
+    LitresPer100Km float64
 }
 
 func Load() *Config {
@@ -24,6 +93,92 @@ func Load() *Config {
     godotenv.Load()
     
     emailPort, _ := strconv.Atoi(getEnv("EMAIL_PORT", "587"))
+    autoMigrate, _ := strconv.ParseBool(getEnv("AUTO_MIGRATE", "false"))
+    maxOpenConns, _ := strconv.Atoi(getEnv("DB_MAX_OPEN_CONNS", "25"))
+    if maxOpenConns <= 0 {
+        maxOpenConns = 25
+    }
+    maxIdleConns, _ := strconv.Atoi(getEnv("DB_MAX_IDLE_CONNS", "10"))
+    if maxIdleConns < 0 {
+        maxIdleConns = 10
+    }
+    if maxIdleConns > maxOpenConns {
+        maxIdleConns = maxOpenConns
+    }
+    connMaxLifetimeMinutes, _ := strconv.Atoi(getEnv("DB_CONN_MAX_LIFETIME_MINUTES", "30"))
+    if connMaxLifetimeMinutes < 0 {
+        connMaxLifetimeMinutes = 30
+    }
+    connMaxIdleMinutes, _ := strconv.Atoi(getEnv("DB_CONN_MAX_IDLE_MINUTES", "5"))
+    if connMaxIdleMinutes < 0 {
+        connMaxIdleMinutes = 5
+    }
+    replicaStickinessSeconds, _ := strconv.Atoi(getEnv("REPLICA_STICKINESS_SECONDS", "10"))
+    if replicaStickinessSeconds < 0 {
+        replicaStickinessSeconds = 10
+    }
+    replicaCheckSeconds, _ := strconv.Atoi(getEnv("REPLICA_CHECK_INTERVAL_SECONDS", "5"))
+    if replicaCheckSeconds <= 0 {
+        replicaCheckSeconds = 5
+    }
+    requestTimeoutSeconds, _ := strconv.Atoi(getEnv("REQUEST_TIMEOUT_SECONDS", "10"))
+    if requestTimeoutSeconds < 0 {
+        requestTimeoutSeconds = 10
+    }
+    notifyDelayMinutes, _ := strconv.Atoi(getEnv("MESSAGE_NOTIFY_DELAY_MINUTES", "15"))
+    notifyIntervalSeconds, _ := strconv.Atoi(getEnv("MESSAGE_NOTIFY_INTERVAL_SECONDS", "60"))
+    if notifyIntervalSeconds <= 0 {
+        notifyIntervalSeconds = 60
+    }
+    reminderLeadMinutes, _ := strconv.Atoi(getEnv("DEPARTURE_REMINDER_LEAD_MINUTES", "30"))
+    reminderIntervalSeconds, _ := strconv.Atoi(getEnv("DEPARTURE_REMINDER_INTERVAL_SECONDS", "60"))
+    if reminderIntervalSeconds <= 0 {
+        reminderIntervalSeconds = 60
+    }
+    locationRetentionMinutes, _ := strconv.Atoi(getEnv("LOCATION_RETENTION_MINUTES", "120"))
+    averageSpeed, _ := strconv.ParseFloat(getEnv("AVERAGE_DRIVER_SPEED_KMH", "40"), 64)
+    if averageSpeed <= 0 {
+        averageSpeed = 40
+    }
+    routeFactor, _ := strconv.ParseFloat(getEnv("ROUTE_DISTANCE_FACTOR", "1.3"), 64)
+    if routeFactor < 1 {
+        routeFactor = 1
+    }
+    platformFeePercent, _ := strconv.Atoi(getEnv("PLATFORM_FEE_PERCENT", "10"))
+    fullRefundHours, _ := strconv.Atoi(getEnv("REFUND_FULL_HOURS", "24"))
+    partialRefundHours, _ := strconv.Atoi(getEnv("REFUND_PARTIAL_HOURS", "2"))
+    partialRefundPercent, _ := strconv.Atoi(getEnv("REFUND_PARTIAL_PERCENT", "50"))
+    refundRetrySeconds, _ := strconv.Atoi(getEnv("REFUND_RETRY_INTERVAL_SECONDS", "60"))
+    if refundRetrySeconds <= 0 {
+        refundRetrySeconds = 60
+    }
+    seatHoldSeconds, _ := strconv.Atoi(getEnv("SEAT_HOLD_TTL_SECONDS", "600"))
+    if seatHoldSeconds <= 0 {
+        seatHoldSeconds = 600
+    }
+    seatHoldSweepSeconds, _ := strconv.Atoi(getEnv("SEAT_HOLD_SWEEP_INTERVAL_SECONDS", "30"))
+    if seatHoldSweepSeconds <= 0 {
+        seatHoldSweepSeconds = 30
+    }
+    waitlistOfferMinutes, _ := strconv.Atoi(getEnv("WAITLIST_OFFER_MINUTES", "30"))
+    if waitlistOfferMinutes <= 0 {
+        waitlistOfferMinutes = 30
+    }
+    materialShiftMinutes, _ := strconv.Atoi(getEnv("TRIP_CHANGE_MATERIAL_SHIFT_MINUTES", "30"))
+    if materialShiftMinutes < 0 {
+        materialShiftMinutes = 30
+    }
+    fuelCurrency := getEnv("FUEL_CURRENCY", money.DefaultCurrency)
+    fuelPrice, err := money.ParseRate(getEnv("FUEL_PRICE_PER_LITRE", "1.80"), fuelCurrency)
+    if err != nil {
+        log.Printf("Invalid fuel price, using default: %v", err)
+        fuelCurrency = money.DefaultCurrency
+        fuelPrice = 180000
+    }
+    litresPer100Km, _ := strconv.ParseFloat(getEnv("LITRES_PER_100KM", "7"), 64)
+    if litresPer100Km <= 0 {
+        litresPer100Km = 7
+    }
     
     return &Config{
         DatabaseURL:   getEnv("DATABASE_URL", "postgres://localhost/rideshare_db?sslmode=disable"),
@@ -35,9 +190,83 @@ func Load() *Config {
         EmailPassword: getEnv("EMAIL_PASSWORD", ""),
         FrontendURL:   getEnv("FRONTEND_URL", "http://localhost:3000"),
         Environment:   getEnv("ENVIRONMENT", "development"),
+        
+        DatabasePool: PoolConfig{
+            MaxOpenConns:    maxOpenConns,
+            MaxIdleConns:    maxIdleConns,
+            ConnMaxLifetime: time.Duration(connMaxLifetimeMinutes) * time.Minute,
+            ConnMaxIdleTime: time.Duration(connMaxIdleMinutes) * time.Minute,
+        },
+        
+        ReplicaDatabaseURL:   getEnv("REPLICA_DATABASE_URL", ""),
+        ReplicaStickiness:    time.Duration(replicaStickinessSeconds) * time.Second,
+        ReplicaCheckInterval: time.Duration(replicaCheckSeconds) * time.Second,
+        
+        AutoMigrate: autoMigrate,
+        
+        RequestTimeout: time.Duration(requestTimeoutSeconds) * time.Second,
+        RouteTimeouts:  parseRouteTimeouts(getEnv("ROUTE_TIMEOUTS", "")),
+        
+        MessageNotifyDelay:    time.Duration(notifyDelayMinutes) * time.Minute,
+        MessageNotifyInterval: time.Duration(notifyIntervalSeconds) * time.Second,
+        
+        DepartureReminderLead:     time.Duration(reminderLeadMinutes) * time.Minute,
+        DepartureReminderInterval: time.Duration(reminderIntervalSeconds) * time.Second,
+        
+        LocationRetention:     time.Duration(locationRetentionMinutes) * time.Minute,
+        AverageDriverSpeedKmh: averageSpeed,
+        RouteDistanceFactor:   routeFactor,
+        
+        PlatformFeePercent:   platformFeePercent,
+        FullRefundBefore:     time.Duration(fullRefundHours) * time.Hour,
+        PartialRefundBefore:  time.Duration(partialRefundHours) * time.Hour,
+        PartialRefundPercent: partialRefundPercent,
+        
+        RefundRetryInterval: time.Duration(refundRetrySeconds) * time.Second,
+        
+        SeatHoldTTL:           time.Duration(seatHoldSeconds) * time.Second,
+        SeatHoldSweepInterval: time.Duration(seatHoldSweepSeconds) * time.Second,
+        
+        WaitlistOfferTTL: time.Duration(waitlistOfferMinutes) * time.Minute,
+        
+        TripChangeMaterialShift: time.Duration(materialShiftMinutes) * time.Minute,
+        
+        FuelPrice:      money.NewRate(fuelPrice, fuelCurrency),
+        LitresPer100Km: litresPer100Km,
     }
 }
 
+// parseRouteTimeouts reads per-route request limits written as
+// "METHOD /path=duration" and separated by commas, for example
+// "POST /api/v1/trips/search=20s,GET /api/v1/payments/transactions=30s".
+func parseRouteTimeouts(value string) map[string]time.Duration {
+    timeouts := map[string]time.Duration{}
+    
+    for _, entry := range strings.Split(value, ",") {
+        entry = strings.TrimSpace(entry)
+        if entry == "" {
+            continue
+        }
+        
+        separator := strings.LastIndex(entry, "=")
+        if separator < 0 {
+            log.Printf("Ignoring route timeout %q: expected METHOD /path=duration", entry)
+            continue
+        }
+        
+        route := strings.Fields(entry[:separator])
+        timeout, err := time.ParseDuration(strings.TrimSpace(entry[separator+1:]))
+        if len(route) != 2 || err != nil || timeout < 0 {
+            log.Printf("Ignoring route timeout %q: expected METHOD /path=duration", entry)
+            continue
+        }
+        
+        timeouts[strings.ToUpper(route[0])+" "+route[1]] = timeout
+    }
+    
+    return timeouts
+}
+
 func getEnv(key, defaultValue string) string {
     if value := os.Getenv(key); value != "" {
         return value
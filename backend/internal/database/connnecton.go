@@ -1,58 +1,66 @@
 package database
 
 import (
+    "context"
     "database/sql"
     "fmt"
-    "io/ioutil"
-    "path/filepath"
-    "sort"
+    
+    "rideshare-backend/internal/config"
+    "rideshare-backend/internal/migrate"
     
     _ "github.com/lib/pq"
 )
 
-func Connect(databaseURL string) (*sql.DB, error) {
-    db, err := sql.Open("postgres", databaseURL)
+// Connect opens a connection pool sized by pool and checks that the database
+// answers.
+func Connect(databaseURL string, pool config.PoolConfig) (*sql.DB, error) {
+    db, err := Open(databaseURL, pool)
     if err != nil {
-        return nil, fmt.Errorf("failed to open database: %w", err)
+        return nil, err
     }
     
     if err := db.Ping(); err != nil {
+        db.Close()
         return nil, fmt.Errorf("failed to ping database: %w", err)
     }
     
     return db, nil
 }
 
-func RunMigrations(db *sql.DB) error {
-    migrationDir := "database/migrations"
+// Open opens a connection pool sized by pool without connecting yet, for a
+// database that may be down for now.
+func Open(databaseURL string, pool config.PoolConfig) (*sql.DB, error) {
+    db, err := sql.Open("postgres", databaseURL)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open database: %w", err)
+    }
     
-    files, err := ioutil.ReadDir(migrationDir)
+    db.SetMaxOpenConns(pool.MaxOpenConns)
+    db.SetMaxIdleConns(pool.MaxIdleConns)
+    db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+    db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+    
+    return db, nil
+}
+
+// RunMigrations applies the pending migrations built into the binary.
+func RunMigrations(ctx context.Context, db *sql.DB) error {
+    migrator, err := migrate.New(db, Migrations())
     if err != nil {
-        return fmt.Errorf("failed to read migration directory: %w", err)
+        return err
     }
     
-    // Sort files to ensure they run in order
-    sort.Slice(files, func(i, j int) bool {
-        return files[i].Name() < files[j].Name()
-    })
-    
-    for _, file := range files {
-        if filepath.Ext(file.Name()) != ".sql" {
-            continue
-        }
-        
-        migrationPath := filepath.Join(migrationDir, file.Name())
-        content, err := ioutil.ReadFile(migrationPath)
-        if err != nil {
-            return fmt.Errorf("failed to read migration file %s: %w", file.Name(), err)
-        }
-        
-        if _, err := db.Exec(string(content)); err != nil {
-            return fmt.Errorf("failed to execute migration %s: %w", file.Name(), err)
-        }
-        
-        fmt.Printf("Applied migration: %s\n", file.Name())
+    _, err = migrator.Up(ctx)
+    return err
+}
+
+// CheckSchema fails if the database is missing migrations built into the
+// binary, or has ones that were changed since they were applied.
+func CheckSchema(ctx context.Context, db *sql.DB) error {
+    migrator, err := migrate.New(db, Migrations())
+    if err != nil {
+        return err
     }
     
-    return nil
+    return migrator.Check(ctx)
 }
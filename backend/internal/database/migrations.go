@@ -0,0 +1,15 @@
+package database
+
+import (
+    "embed"
+    "io/fs"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations returns the SQL migrations built into the binary.
+func Migrations() fs.FS {
+    migrations, _ := fs.Sub(migrationFiles, "migrations")
+    return migrations
+}
@@ -0,0 +1,4 @@
+-- Drop vehicle profiles
+DROP INDEX IF EXISTS idx_trips_vehicle_id;
+ALTER TABLE trips DROP COLUMN IF EXISTS vehicle_id;
+DROP TABLE IF EXISTS vehicles;
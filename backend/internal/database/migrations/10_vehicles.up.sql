@@ -0,0 +1,25 @@
+-- Migration: Vehicle profiles
+-- seats counts every seat in the car, the driver's included.
+CREATE TABLE IF NOT EXISTS vehicles (
+    id SERIAL PRIMARY KEY,
+    owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    make VARCHAR(50) NOT NULL,
+    model VARCHAR(50) NOT NULL,
+    colour VARCHAR(30) NOT NULL,
+    plate VARCHAR(20) NOT NULL,
+    seats INTEGER NOT NULL CHECK (seats BETWEEN 2 AND 9),
+    amenities TEXT[] NOT NULL DEFAULT '{}',
+    litres_per_100km NUMERIC(4, 1) CHECK (litres_per_100km > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_vehicles_owner_id ON vehicles(owner_id);
+
+CREATE TRIGGER update_vehicles_updated_at
+    BEFORE UPDATE ON vehicles
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS vehicle_id INTEGER REFERENCES vehicles(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_trips_vehicle_id ON trips(vehicle_id);
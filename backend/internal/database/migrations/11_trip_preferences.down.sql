@@ -0,0 +1,9 @@
+-- Drop trip preferences
+DROP TABLE IF EXISTS user_preferences;
+ALTER TABLE users DROP COLUMN IF EXISTS gender;
+ALTER TABLE trips DROP COLUMN IF EXISTS instant_booking;
+ALTER TABLE trips DROP COLUMN IF EXISTS women_only;
+ALTER TABLE trips DROP COLUMN IF EXISTS music;
+ALTER TABLE trips DROP COLUMN IF EXISTS luggage_size;
+ALTER TABLE trips DROP COLUMN IF EXISTS smoking_allowed;
+ALTER TABLE trips DROP COLUMN IF EXISTS pets_allowed;
@@ -0,0 +1,26 @@
+-- Migration: Trip preferences
+-- Existing trips keep instant booking; drivers who turn it off approve each
+-- booking request, which waits in trip_passengers as 'pending'.
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS pets_allowed BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS smoking_allowed BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS luggage_size VARCHAR(10) NOT NULL DEFAULT 'medium'
+    CHECK (luggage_size IN ('none', 'small', 'medium', 'large'));
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS music BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS women_only BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS instant_booking BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Women-only rides can only be booked by users who say they are women
+ALTER TABLE users ADD COLUMN IF NOT EXISTS gender VARCHAR(10)
+    CHECK (gender IN ('female', 'male', 'other'));
+
+-- A passenger's default search preferences. NULL means no preference.
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    pets_allowed BOOLEAN,
+    smoking_allowed BOOLEAN,
+    luggage_size VARCHAR(10) CHECK (luggage_size IN ('none', 'small', 'medium', 'large')),
+    music BOOLEAN,
+    women_only BOOLEAN,
+    instant_booking BOOLEAN,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
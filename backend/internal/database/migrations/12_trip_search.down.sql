@@ -0,0 +1,6 @@
+-- Drop trip search indexes
+DROP INDEX IF EXISTS idx_trips_to_trgm;
+DROP INDEX IF EXISTS idx_trips_from_trgm;
+DROP INDEX IF EXISTS idx_trips_driver_departure_id;
+DROP INDEX IF EXISTS idx_trips_price_id;
+DROP INDEX IF EXISTS idx_trips_departure_id;
@@ -0,0 +1,10 @@
+-- Migration: Paginated trip search
+-- Keyset pagination orders on (sort column, id); match score sorting uses
+-- trigram similarity of the route.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_trips_departure_id ON trips(departure_time, id);
+CREATE INDEX IF NOT EXISTS idx_trips_price_id ON trips(price_per_person_minor, id);
+CREATE INDEX IF NOT EXISTS idx_trips_driver_departure_id ON trips(driver_id, departure_time DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_trips_from_trgm ON trips USING gin (from_location gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_trips_to_trgm ON trips USING gin (to_location gin_trgm_ops);
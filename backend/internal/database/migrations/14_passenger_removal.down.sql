@@ -0,0 +1,4 @@
+-- Drop passenger removal
+ALTER TABLE trip_passengers DROP CONSTRAINT IF EXISTS trip_passengers_removed_cancelled;
+ALTER TABLE trip_passengers DROP COLUMN IF EXISTS removal_reason;
+ALTER TABLE trip_passengers DROP COLUMN IF EXISTS removed_at;
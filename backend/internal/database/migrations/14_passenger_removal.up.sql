@@ -0,0 +1,7 @@
+-- Migration: Removing passengers
+-- A passenger removed by the driver keeps a cancelled booking recording when
+-- and why, and can't book the trip again.
+ALTER TABLE trip_passengers ADD COLUMN IF NOT EXISTS removed_at TIMESTAMPTZ;
+ALTER TABLE trip_passengers ADD COLUMN IF NOT EXISTS removal_reason TEXT;
+ALTER TABLE trip_passengers ADD CONSTRAINT trip_passengers_removed_cancelled
+    CHECK (removed_at IS NULL OR status = 'cancelled');
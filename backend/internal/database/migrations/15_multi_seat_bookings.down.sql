@@ -0,0 +1,4 @@
+-- Drop multi-seat bookings
+ALTER TABLE trip_passengers DROP CONSTRAINT IF EXISTS trip_passengers_guests_fit;
+ALTER TABLE trip_passengers DROP COLUMN IF EXISTS guest_names;
+ALTER TABLE trip_passengers DROP COLUMN IF EXISTS seats;
@@ -0,0 +1,7 @@
+-- Migration: Multi-seat bookings
+-- A booking can hold several seats, the extra ones optionally named for the
+-- guests travelling with the passenger. current_passengers counts seats.
+ALTER TABLE trip_passengers ADD COLUMN IF NOT EXISTS seats INTEGER NOT NULL DEFAULT 1 CHECK (seats >= 1);
+ALTER TABLE trip_passengers ADD COLUMN IF NOT EXISTS guest_names TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE trip_passengers ADD CONSTRAINT trip_passengers_guests_fit
+    CHECK (cardinality(guest_names) < seats);
@@ -0,0 +1,3 @@
+-- Drop seat capacity constraint
+ALTER TABLE trips DROP CONSTRAINT IF EXISTS trips_seats_within_capacity;
+ALTER TABLE trips ALTER COLUMN current_passengers DROP NOT NULL;
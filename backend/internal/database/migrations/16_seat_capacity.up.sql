@@ -0,0 +1,32 @@
+-- Migration: Seat capacity constraint
+-- Seats are taken with a conditional update, and the database refuses any
+-- change that would overbook a trip or leave it with negative seats.
+UPDATE trips SET current_passengers = 0 WHERE current_passengers IS NULL;
+ALTER TABLE trips ALTER COLUMN current_passengers SET NOT NULL;
+
+-- Racing joins may have drifted the count from the confirmed bookings, or
+-- overbooked trips, before this constraint existed. Recount the seats taken,
+-- and give overbooked trips room for the passengers they have rather than
+-- dropping bookings; they are reported for the drivers to sort out.
+UPDATE trips t
+SET current_passengers = COALESCE((
+    SELECT SUM(tp.seats)
+    FROM trip_passengers tp
+    WHERE tp.trip_id = t.id AND tp.status = 'confirmed'
+), 0);
+
+DO $$
+DECLARE
+    overbooked RECORD;
+BEGIN
+    FOR overbooked IN
+        SELECT id, current_passengers, max_passengers FROM trips WHERE current_passengers > max_passengers
+    LOOP
+        RAISE NOTICE 'trip % is overbooked: % seats taken of %', overbooked.id, overbooked.current_passengers, overbooked.max_passengers;
+    END LOOP;
+END $$;
+
+UPDATE trips SET max_passengers = current_passengers WHERE current_passengers > max_passengers;
+
+ALTER TABLE trips ADD CONSTRAINT trips_seats_within_capacity
+    CHECK (current_passengers >= 0 AND current_passengers <= max_passengers);
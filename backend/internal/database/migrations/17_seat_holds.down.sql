@@ -0,0 +1,6 @@
+-- Drop seat holds
+ALTER TABLE trips DROP CONSTRAINT IF EXISTS trips_seats_within_capacity;
+ALTER TABLE trips ADD CONSTRAINT trips_seats_within_capacity
+    CHECK (current_passengers >= 0 AND current_passengers <= max_passengers);
+ALTER TABLE trips DROP COLUMN IF EXISTS held_seats;
+DROP TABLE IF EXISTS seat_holds;
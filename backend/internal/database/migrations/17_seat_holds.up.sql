@@ -0,0 +1,20 @@
+-- Migration: Seat holds
+-- A passenger starting to book holds seats for a short while. Held seats
+-- count against a trip's capacity until the booking is made or the hold
+-- expires and is swept.
+CREATE TABLE IF NOT EXISTS seat_holds (
+    id SERIAL PRIMARY KEY,
+    trip_id INTEGER NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    passenger_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    seats INTEGER NOT NULL CHECK (seats >= 1),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(trip_id, passenger_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_seat_holds_expires_at ON seat_holds(expires_at);
+
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS held_seats INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE trips DROP CONSTRAINT IF EXISTS trips_seats_within_capacity;
+ALTER TABLE trips ADD CONSTRAINT trips_seats_within_capacity
+    CHECK (current_passengers >= 0 AND held_seats >= 0 AND current_passengers + held_seats <= max_passengers);
@@ -0,0 +1,2 @@
+-- Drop trip waitlist
+DROP TABLE IF EXISTS trip_waitlist;
@@ -0,0 +1,19 @@
+-- Migration: Trip waitlist
+-- Passengers wait in line for seats on full trips. When seats free up the
+-- first waiting passengers who fit are offered them as a seat hold, which
+-- they must confirm by joining before offer_expires_at.
+CREATE TABLE IF NOT EXISTS trip_waitlist (
+    id SERIAL PRIMARY KEY,
+    trip_id INTEGER NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    passenger_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    seats INTEGER NOT NULL DEFAULT 1 CHECK (seats >= 1),
+    guest_names TEXT[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting'
+        CHECK (status IN ('waiting', 'offered', 'booked', 'expired', 'cancelled')),
+    offered_at TIMESTAMPTZ,
+    offer_expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(trip_id, passenger_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_waitlist_queue ON trip_waitlist(trip_id, created_at) WHERE status = 'waiting';
@@ -0,0 +1,3 @@
+-- Drop trip change audit
+DROP TABLE IF EXISTS trip_change_responses;
+DROP TABLE IF EXISTS trip_changes;
@@ -0,0 +1,24 @@
+-- Migration: Trip change audit
+-- Every update to a trip records what changed. Material changes (route,
+-- a large departure shift, a price rise) ask each booked passenger to keep
+-- their booking or cancel it with a full refund.
+CREATE TABLE IF NOT EXISTS trip_changes (
+    id SERIAL PRIMARY KEY,
+    trip_id INTEGER NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    changes JSONB NOT NULL,
+    material BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_changes_trip ON trip_changes(trip_id, created_at);
+
+CREATE TABLE IF NOT EXISTS trip_change_responses (
+    change_id INTEGER NOT NULL REFERENCES trip_changes(id) ON DELETE CASCADE,
+    passenger_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    response VARCHAR(10) CHECK (response IN ('keep', 'cancel')),
+    responded_at TIMESTAMPTZ,
+    PRIMARY KEY (change_id, passenger_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_change_responses_passenger ON trip_change_responses(passenger_id);
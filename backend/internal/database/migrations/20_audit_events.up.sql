@@ -0,0 +1,39 @@
+-- Migration: Audit log
+-- An append-only record of who created, changed, cancelled, joined or left a
+-- trip or account, with the request it came from. Rows can't be updated or
+-- deleted; users being deleted keeps their events, without the actor.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(20) NOT NULL CHECK (entity_type IN ('trip', 'user')),
+    entity_id INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    changes JSONB NOT NULL DEFAULT '{}',
+    request_id VARCHAR(64),
+    client_ip VARCHAR(45),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_entity ON audit_events(entity_type, entity_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor_id, id);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    -- Clearing the actor of a deleted user is the one change allowed
+    IF TG_OP = 'UPDATE' AND NEW.actor_id IS NULL
+       AND (NEW.id, NEW.entity_type, NEW.entity_id, NEW.action, NEW.changes, NEW.request_id, NEW.client_ip, NEW.created_at)
+           IS NOT DISTINCT FROM
+           (OLD.id, OLD.entity_type, OLD.entity_id, OLD.action, OLD.changes, OLD.request_id, OLD.client_ip, OLD.created_at) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+-- Admins may query the audit log
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
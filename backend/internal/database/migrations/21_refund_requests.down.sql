@@ -0,0 +1,2 @@
+-- Drop refund requests
+DROP TABLE IF EXISTS refund_requests CASCADE;
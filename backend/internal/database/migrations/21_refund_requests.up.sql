@@ -0,0 +1,22 @@
+-- Migration: Refund requests
+-- A refund is requested against the charge it pays back before the payment
+-- provider is asked to pay it, in the transaction that locks the charge, so
+-- two refunds can't both pay out the same remainder. The request stays
+-- pending until the provider paid it and the refund is in the ledger; failed
+-- ones are retried. Its ID is the idempotency key sent to the provider, so a
+-- retry never pays twice.
+CREATE TABLE IF NOT EXISTS refund_requests (
+    id SERIAL PRIMARY KEY,
+    charge_id INTEGER NOT NULL REFERENCES payment_transactions(id) ON DELETE CASCADE,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    description TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    transaction_id INTEGER REFERENCES payment_transactions(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refund_requests_charge ON refund_requests(charge_id);
+CREATE INDEX IF NOT EXISTS idx_refund_requests_pending ON refund_requests(updated_at) WHERE status = 'pending';
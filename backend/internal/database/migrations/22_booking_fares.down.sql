@@ -0,0 +1,3 @@
+-- Drop booking fares
+ALTER TABLE trip_passengers DROP COLUMN IF EXISTS fare_seats;
+ALTER TABLE trip_passengers DROP COLUMN IF EXISTS fare_minor;
@@ -0,0 +1,26 @@
+-- Migration: Booking fares
+-- fare_minor is what the passenger has paid for fare_seats seats, net of
+-- refunds. Cancellation fees kept from seats given up don't count, so
+-- shared-cost trips reprice against the seats actually paid for, and seats
+-- given up but not refunded yet are still counted in fare_seats.
+ALTER TABLE trip_passengers ADD COLUMN IF NOT EXISTS fare_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE trip_passengers ADD COLUMN IF NOT EXISTS fare_seats INTEGER NOT NULL DEFAULT 0;
+
+-- Confirmed bookings have paid what was charged since they joined, net of
+-- refunds
+UPDATE trip_passengers tp
+SET fare_minor = GREATEST(
+    COALESCE((
+        SELECT SUM(c.amount)
+        FROM payment_transactions c
+        WHERE c.booking_id = tp.id AND c.kind = 'charge' AND c.created_at >= tp.joined_at
+    ), 0)
+    - COALESCE((
+        SELECT SUM(r.amount)
+        FROM payment_transactions r
+        JOIN payment_transactions c ON c.id = r.parent_id
+        WHERE c.booking_id = tp.id AND c.kind = 'charge' AND c.created_at >= tp.joined_at AND r.kind = 'refund'
+    ), 0),
+    0),
+    fare_seats = tp.seats
+WHERE tp.status = 'confirmed';
@@ -0,0 +1,3 @@
+-- Drop trip messaging tables
+DROP TABLE IF EXISTS trip_message_reads CASCADE;
+DROP TABLE IF EXISTS trip_messages CASCADE;
@@ -0,0 +1,23 @@
+-- Migration: Create trip messaging tables
+CREATE TABLE IF NOT EXISTS trip_messages (
+    id SERIAL PRIMARY KEY,
+    trip_id INTEGER NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    sender_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL CHECK (char_length(body) BETWEEN 1 AND 2000),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One row per participant and trip, tracking how far they have read and
+-- which message they were last emailed about
+CREATE TABLE IF NOT EXISTS trip_message_reads (
+    trip_id INTEGER REFERENCES trips(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    last_read_message_id INTEGER NOT NULL DEFAULT 0,
+    last_notified_message_id INTEGER NOT NULL DEFAULT 0,
+    read_at TIMESTAMP,
+    PRIMARY KEY (trip_id, user_id)
+);
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_trip_messages_trip ON trip_messages(trip_id, id);
+CREATE INDEX IF NOT EXISTS idx_trip_messages_created_at ON trip_messages(created_at);
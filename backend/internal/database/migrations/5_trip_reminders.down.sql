@@ -0,0 +1,3 @@
+-- Drop departure reminder tracking
+DROP INDEX IF EXISTS idx_trips_reminder_pending;
+ALTER TABLE trips DROP COLUMN IF EXISTS reminder_sent_at;
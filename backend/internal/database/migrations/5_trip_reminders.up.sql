@@ -0,0 +1,4 @@
+-- Migration: Track departure reminders sent for trips
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS reminder_sent_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_trips_reminder_pending ON trips(departure_time) WHERE reminder_sent_at IS NULL;
@@ -0,0 +1,9 @@
+-- Drop live location sharing
+DROP TABLE IF EXISTS trip_locations CASCADE;
+ALTER TABLE trip_passengers DROP COLUMN IF EXISTS pickup_longitude;
+ALTER TABLE trip_passengers DROP COLUMN IF EXISTS pickup_latitude;
+ALTER TABLE trips DROP COLUMN IF EXISTS started_at;
+UPDATE trips SET status = 'active' WHERE status = 'in_progress';
+ALTER TABLE trips DROP CONSTRAINT IF EXISTS trips_status_check;
+ALTER TABLE trips ADD CONSTRAINT trips_status_check
+    CHECK (status IN ('active', 'completed', 'cancelled'));
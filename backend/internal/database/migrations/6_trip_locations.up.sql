@@ -0,0 +1,24 @@
+-- Migration: Live driver location sharing for in-progress trips
+ALTER TABLE trips DROP CONSTRAINT IF EXISTS trips_status_check;
+ALTER TABLE trips ADD CONSTRAINT trips_status_check
+    CHECK (status IN ('active', 'in_progress', 'completed', 'cancelled'));
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS started_at TIMESTAMP;
+
+-- Where each passenger wants to be picked up
+ALTER TABLE trip_passengers ADD COLUMN IF NOT EXISTS pickup_latitude DOUBLE PRECISION;
+ALTER TABLE trip_passengers ADD COLUMN IF NOT EXISTS pickup_longitude DOUBLE PRECISION;
+
+-- Driver positions are only kept for a short retention window
+CREATE TABLE IF NOT EXISTS trip_locations (
+    id BIGSERIAL PRIMARY KEY,
+    trip_id INTEGER NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    latitude DOUBLE PRECISION NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION NOT NULL CHECK (longitude BETWEEN -180 AND 180),
+    heading DOUBLE PRECISION,
+    speed_kmh DOUBLE PRECISION CHECK (speed_kmh >= 0),
+    recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_trip_locations_trip ON trip_locations(trip_id, recorded_at DESC);
+CREATE INDEX IF NOT EXISTS idx_trip_locations_recorded_at ON trip_locations(recorded_at);
@@ -0,0 +1,5 @@
+-- Drop payments ledger
+DROP TRIGGER IF EXISTS ledger_entries_balanced ON ledger_entries;
+DROP FUNCTION IF EXISTS check_ledger_balanced();
+DROP TABLE IF EXISTS ledger_entries CASCADE;
+DROP TABLE IF EXISTS payment_transactions CASCADE;
@@ -0,0 +1,58 @@
+-- Migration: Double-entry payments ledger
+CREATE TABLE IF NOT EXISTS payment_transactions (
+    id SERIAL PRIMARY KEY,
+    booking_id INTEGER REFERENCES trip_passengers(id) ON DELETE SET NULL,
+    trip_id INTEGER REFERENCES trips(id) ON DELETE SET NULL,
+    parent_id INTEGER REFERENCES payment_transactions(id),
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('charge', 'refund')),
+    amount BIGINT NOT NULL CHECK (amount >= 0),
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    provider_reference VARCHAR(255),
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Amounts are in minor units (cents) from the account holder's point of view:
+-- positive entries are owed to the holder, negative entries were paid by them.
+-- The entries of every transaction must sum to zero.
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id SERIAL PRIMARY KEY,
+    transaction_id INTEGER NOT NULL REFERENCES payment_transactions(id) ON DELETE CASCADE,
+    account VARCHAR(20) NOT NULL CHECK (account IN ('passenger', 'driver', 'platform')),
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    entry_type VARCHAR(30) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Reject unbalanced transactions at commit time
+CREATE OR REPLACE FUNCTION check_ledger_balanced()
+RETURNS TRIGGER AS $$
+DECLARE
+    total BIGINT;
+BEGIN
+    SELECT COALESCE(SUM(amount), 0) INTO total
+    FROM ledger_entries
+    WHERE transaction_id = NEW.transaction_id;
+
+    IF total <> 0 THEN
+        RAISE EXCEPTION 'ledger transaction % is unbalanced by %', NEW.transaction_id, total;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE CONSTRAINT TRIGGER ledger_entries_balanced
+    AFTER INSERT OR UPDATE ON ledger_entries
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    EXECUTE FUNCTION check_ledger_balanced();
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_payment_transactions_booking ON payment_transactions(booking_id);
+CREATE INDEX IF NOT EXISTS idx_payment_transactions_trip ON payment_transactions(trip_id);
+CREATE INDEX IF NOT EXISTS idx_payment_transactions_parent ON payment_transactions(parent_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction ON ledger_entries(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_user ON ledger_entries(user_id, created_at);
@@ -0,0 +1,6 @@
+-- Restore decimal trip prices (all prices are assumed to have two decimals)
+DROP INDEX IF EXISTS idx_trips_currency_price;
+ALTER TABLE trips DROP COLUMN IF EXISTS currency;
+ALTER TABLE trips ALTER COLUMN price_per_person_minor TYPE DECIMAL(10,2)
+    USING (price_per_person_minor / 100.0)::DECIMAL(10,2);
+ALTER TABLE trips RENAME COLUMN price_per_person_minor TO price_per_person;
@@ -0,0 +1,7 @@
+-- Migration: Store trip prices as integer minor units with a currency
+ALTER TABLE trips RENAME COLUMN price_per_person TO price_per_person_minor;
+ALTER TABLE trips ALTER COLUMN price_per_person_minor TYPE BIGINT
+    USING ROUND(price_per_person_minor * 100)::BIGINT;
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+CREATE INDEX IF NOT EXISTS idx_trips_currency_price ON trips(currency, price_per_person_minor);
@@ -0,0 +1,4 @@
+-- Drop cost-sharing fares
+ALTER TABLE trips DROP CONSTRAINT IF EXISTS trips_shared_fare_total;
+ALTER TABLE trips DROP COLUMN IF EXISTS total_cost_minor;
+ALTER TABLE trips DROP COLUMN IF EXISTS fare_mode;
@@ -0,0 +1,8 @@
+-- Migration: Cost-sharing fares
+-- In 'shared' mode the per-person price is recomputed from total_cost_minor as
+-- passengers join, splitting the cost between the driver and passengers.
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS fare_mode VARCHAR(10) NOT NULL DEFAULT 'fixed'
+    CHECK (fare_mode IN ('fixed', 'shared'));
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS total_cost_minor BIGINT CHECK (total_cost_minor >= 0);
+ALTER TABLE trips ADD CONSTRAINT trips_shared_fare_total
+    CHECK (fare_mode <> 'shared' OR total_cost_minor IS NOT NULL);
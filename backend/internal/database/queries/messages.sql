@@ -0,0 +1,25 @@
+-- name: CreateTripMessage :one
+INSERT INTO trip_messages (trip_id, sender_id, body, created_at)
+VALUES ($1, $2, $3, NOW())
+RETURNING id, created_at;
+
+-- name: GetTripMessages :many
+SELECT m.id, m.trip_id, m.sender_id, m.body, m.created_at,
+       u.id, u.name, u.profile_image
+FROM trip_messages m
+JOIN users u ON m.sender_id = u.id
+WHERE m.trip_id = $1 AND ($2 = 0 OR m.id < $2)
+ORDER BY m.id DESC
+LIMIT $3;
+
+-- name: GetTripMessageReceipts :many
+SELECT trip_id, user_id, last_read_message_id, read_at
+FROM trip_message_reads
+WHERE trip_id = $1 AND last_read_message_id > 0;
+
+-- name: MarkTripMessagesRead :exec
+INSERT INTO trip_message_reads (trip_id, user_id, last_read_message_id, read_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (trip_id, user_id) DO UPDATE
+SET last_read_message_id = GREATEST(trip_message_reads.last_read_message_id, EXCLUDED.last_read_message_id),
+    read_at = NOW();
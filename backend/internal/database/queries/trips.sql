@@ -1,6 +1,7 @@
 -- name: GetTripByID :one
-SELECT t.id, t.driver_id, t.from_location, t.to_location, t.departure_time,
-       t.max_passengers, t.current_passengers, t.price_per_person, t.description,
+SELECT t.id, t.driver_id, t.from_location, t.to_location, t.departure_time, t.timezone,
+       t.max_passengers, t.current_passengers, t.held_seats, t.price_per_person_minor, t.currency, t.fare_mode, t.total_cost_minor, t.vehicle_id,
+       t.pets_allowed, t.smoking_allowed, t.luggage_size, t.music, t.women_only, t.instant_booking, t.description,
        t.status, t.created_at, t.updated_at,
        u.id, u.name, u.email, u.phone
 FROM trips t
@@ -8,47 +9,107 @@ LEFT JOIN users u ON t.driver_id = u.id
 WHERE t.id = $1;
 
 -- name: GetUserTrips :many
-SELECT t.id, t.driver_id, t.from_location, t.to_location, t.departure_time,
-       t.max_passengers, t.current_passengers, t.price_per_person, t.description,
-       t.status, t.created_at, t.updated_at
+SELECT t.id, t.driver_id, t.from_location, t.to_location, t.departure_time, t.timezone,
+       t.max_passengers, t.current_passengers, t.held_seats, t.price_per_person_minor, t.currency, t.fare_mode, t.total_cost_minor, t.vehicle_id,
+       t.pets_allowed, t.smoking_allowed, t.luggage_size, t.music, t.women_only, t.instant_booking, t.description,
+       t.status, t.created_at, t.updated_at,
+       u.id, u.name, u.email, u.phone, t.departure_time::text
 FROM trips t
+LEFT JOIN users u ON t.driver_id = u.id
 WHERE t.driver_id = $1
-ORDER BY t.departure_time DESC;
+AND ($2::timestamptz IS NULL OR (t.departure_time, t.id) < ($2::timestamptz, $3))
+ORDER BY t.departure_time DESC, t.id DESC
+LIMIT $4;
+
+-- name: CountUserTrips :one
+SELECT COUNT(*) FROM trips t WHERE t.driver_id = $1;
 
 -- name: CreateTrip :one
-INSERT INTO trips (driver_id, from_location, to_location, departure_time, max_passengers, price_per_person, description, status, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, 'active', NOW(), NOW())
+INSERT INTO trips (driver_id, from_location, to_location, departure_time, timezone, max_passengers, price_per_person_minor, currency, fare_mode, total_cost_minor, vehicle_id,
+    pets_allowed, smoking_allowed, luggage_size, music, women_only, instant_booking, description, status, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, 'active', NOW(), NOW())
 RETURNING id, created_at, updated_at;
 
 -- name: UpdateTrip :one
 UPDATE trips
-SET from_location = $1, to_location = $2, departure_time = $3,
-    max_passengers = $4, price_per_person = $5, description = $6, updated_at = NOW()
-WHERE id = $7 AND driver_id = $8
+SET from_location = $1, to_location = $2, departure_time = $3, timezone = $4,
+    max_passengers = $5, price_per_person_minor = $6, currency = $7,
+    fare_mode = $8, total_cost_minor = $9, vehicle_id = $10, pets_allowed = $11, smoking_allowed = $12,
+    luggage_size = $13, music = $14, women_only = $15, instant_booking = $16, description = $17, updated_at = NOW()
+WHERE id = $18 AND driver_id = $19
 RETURNING updated_at;
 
--- name: DeleteTrip :exec
+-- name: UpdateSharedFare :exec
 UPDATE trips
+SET price_per_person_minor = (total_cost_minor + GREATEST(current_passengers, 1)) / (GREATEST(current_passengers, 1) + 1)
+WHERE id = $1 AND fare_mode = 'shared';
+
+-- name: DeleteTrip :one
+UPDATE trips t
 SET status = 'cancelled', updated_at = NOW()
-WHERE id = $1 AND driver_id = $2;
+FROM (SELECT id, status FROM trips WHERE id = $1 AND driver_id = $2 FOR UPDATE) prev
+WHERE t.id = prev.id
+RETURNING prev.status;
 
 -- name: SearchTrips :many
-SELECT t.id, t.driver_id, t.from_location, t.to_location, t.departure_time,
-       t.max_passengers, t.current_passengers, t.price_per_person, t.description,
+SELECT t.id, t.driver_id, t.from_location, t.to_location, t.departure_time, t.timezone,
+       t.max_passengers, t.current_passengers, t.held_seats, t.price_per_person_minor, t.currency, t.fare_mode, t.total_cost_minor, t.vehicle_id,
+       t.pets_allowed, t.smoking_allowed, t.luggage_size, t.music, t.women_only, t.instant_booking, t.description,
        t.status, t.created_at, t.updated_at,
-       u.id, u.name, u.email, u.phone
+       u.id, u.name, u.email, u.phone, t.departure_time::text
 FROM trips t
 LEFT JOIN users u ON t.driver_id = u.id
 WHERE t.status = 'active' 
-AND t.current_passengers < t.max_passengers
+AND t.current_passengers + t.held_seats < t.max_passengers
 AND t.departure_time > NOW()
-ORDER BY t.departure_time ASC;
+AND ($1::timestamptz IS NULL OR (t.departure_time, t.id) > ($1::timestamptz, $2))
+ORDER BY t.departure_time ASC, t.id ASC
+LIMIT $3;
 
 -- name: JoinTrip :exec
-INSERT INTO trip_passengers (trip_id, passenger_id, status, joined_at)
-VALUES ($1, $2, 'confirmed', NOW());
+INSERT INTO trip_passengers (trip_id, passenger_id, status, seats, guest_names, joined_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+ON CONFLICT (trip_id, passenger_id) DO UPDATE
+SET status = EXCLUDED.status, seats = EXCLUDED.seats, guest_names = EXCLUDED.guest_names, joined_at = NOW();
+
+-- name: ApproveBooking :one
+UPDATE trip_passengers
+SET status = 'confirmed'
+WHERE trip_id = $1 AND passenger_id = $2 AND status = 'pending'
+RETURNING seats;
 
--- name: UpdatePassengerCount :exec
+-- name: DeclineBooking :one
+UPDATE trip_passengers tp
+SET status = 'cancelled'
+FROM trips t
+WHERE t.id = tp.trip_id AND tp.trip_id = $1 AND t.driver_id = $2
+AND tp.passenger_id = $3 AND tp.status = 'pending'
+RETURNING tp.seats;
+
+-- name: UpdatePassengerCount :execrows
+UPDATE trips
+SET current_passengers = current_passengers + $2, updated_at = NOW()
+WHERE id = $1 AND current_passengers + held_seats + $2 <= max_passengers;
+
+-- name: LeaveTrip :execrows
+UPDATE trip_passengers
+SET status = 'cancelled'
+WHERE trip_id = $1 AND passenger_id = $2 AND status <> 'cancelled';
+
+-- name: CancelSeats :exec
+UPDATE trip_passengers
+SET seats = $3, guest_names = $4
+WHERE trip_id = $1 AND passenger_id = $2;
+
+-- name: ClaimDueReminders :many
 UPDATE trips
-SET current_passengers = current_passengers + 1, updated_at = NOW()
-WHERE id = $1;
+SET reminder_sent_at = NOW()
+WHERE status = 'active'
+AND reminder_sent_at IS NULL
+AND departure_time > NOW()
+AND departure_time <= NOW() + make_interval(secs => $1)
+RETURNING id, driver_id, from_location, to_location, departure_time, timezone;
+
+-- name: RecordAuditEvent :exec
+INSERT INTO audit_events (entity_type, entity_id, action, actor_id, changes, request_id, client_ip, created_at)
+VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NOW());
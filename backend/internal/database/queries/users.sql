@@ -1,22 +1,22 @@
 -- name: GetUserByID :one
-SELECT id, name, email, phone, profile_image, is_verified, created_at, updated_at
+SELECT id, name, email, phone, profile_image, gender, timezone, is_verified, created_at, updated_at
 FROM users
 WHERE id = $1;
 
 -- name: GetUserByEmail :one
-SELECT id, name, email, password, phone, profile_image, is_verified, created_at, updated_at
+SELECT id, name, email, password, phone, profile_image, gender, timezone, is_verified, created_at, updated_at
 FROM users
 WHERE email = $1;
 
 -- name: CreateUser :one
-INSERT INTO users (name, email, password, phone, created_at, updated_at)
-VALUES ($1, $2, $3, $4, NOW(), NOW())
+INSERT INTO users (name, email, password, phone, timezone, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
 RETURNING id, created_at, updated_at;
 
 -- name: UpdateUser :one
 UPDATE users
-SET name = $1, phone = $2, profile_image = $3, updated_at = NOW()
-WHERE id = $4
+SET name = $1, phone = $2, profile_image = $3, gender = $4, timezone = $5, updated_at = NOW()
+WHERE id = $6
 RETURNING updated_at;
 
 -- name: UpdateUserPassword :exec
@@ -36,3 +36,20 @@ SELECT
     COUNT(CASE WHEN status = 'cancelled' THEN 1 END) as cancelled_trips
 FROM trips 
 WHERE driver_id = $1;
+
+-- name: GetUserPreferences :one
+SELECT pets_allowed, smoking_allowed, luggage_size, music, women_only, instant_booking
+FROM user_preferences
+WHERE user_id = $1;
+
+-- name: SaveUserPreferences :exec
+INSERT INTO user_preferences (user_id, pets_allowed, smoking_allowed, luggage_size, music, women_only, instant_booking, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+ON CONFLICT (user_id) DO UPDATE SET
+    pets_allowed = EXCLUDED.pets_allowed,
+    smoking_allowed = EXCLUDED.smoking_allowed,
+    luggage_size = EXCLUDED.luggage_size,
+    music = EXCLUDED.music,
+    women_only = EXCLUDED.women_only,
+    instant_booking = EXCLUDED.instant_booking,
+    updated_at = NOW();
@@ -0,0 +1,38 @@
+-- name: CreateVehicle :one
+INSERT INTO vehicles (owner_id, make, model, colour, plate, seats, amenities, litres_per_100km, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+RETURNING id, created_at, updated_at;
+
+-- name: GetUserVehicles :many
+SELECT v.id, v.owner_id, v.make, v.model, v.colour, v.plate, v.seats, v.amenities,
+       v.litres_per_100km, v.created_at, v.updated_at
+FROM vehicles v
+WHERE v.owner_id = $1
+ORDER BY v.created_at;
+
+-- name: GetVehicle :one
+SELECT v.id, v.owner_id, v.make, v.model, v.colour, v.plate, v.seats, v.amenities,
+       v.litres_per_100km, v.created_at, v.updated_at
+FROM vehicles v
+WHERE v.id = $1 AND v.owner_id = $2;
+
+-- name: GetTripVehicle :one
+SELECT v.id, v.owner_id, v.make, v.model, v.colour, v.plate, v.seats, v.amenities,
+       v.litres_per_100km, v.created_at, v.updated_at
+FROM vehicles v
+JOIN trips t ON t.vehicle_id = v.id
+WHERE t.id = $1;
+
+-- name: UpdateVehicle :one
+UPDATE vehicles
+SET make = $1, model = $2, colour = $3, plate = $4, seats = $5,
+    amenities = $6, litres_per_100km = $7, updated_at = NOW()
+WHERE id = $8 AND owner_id = $9
+RETURNING created_at, updated_at;
+
+-- name: DeleteVehicle :exec
+DELETE FROM vehicles
+WHERE id = $1 AND owner_id = $2
+AND NOT EXISTS (
+    SELECT 1 FROM trips WHERE vehicle_id = $1 AND status IN ('active', 'in_progress')
+);
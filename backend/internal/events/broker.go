@@ -0,0 +1,99 @@
+package events
+
+import (
+    "log"
+    "sync"
+)
+
+// Broker fans events out to subscribers by topic. The in-process MemoryBroker
+// only reaches clients connected to this server; a PostgreSQL LISTEN/NOTIFY
+// implementation can replace it when running several instances.
+type Broker interface {
+    Publish(topic string, event Event) error
+    Subscribe(topics ...string) (Subscription, error)
+}
+
+type Subscription interface {
+    Events() <-chan Event
+    Close()
+}
+
+const subscriberBufferSize = 32
+
+type MemoryBroker struct {
+    mu          sync.RWMutex
+    subscribers map[string]map[*memorySubscription]struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+    return &MemoryBroker{
+        subscribers: make(map[string]map[*memorySubscription]struct{}),
+    }
+}
+
+func (b *MemoryBroker) Publish(topic string, event Event) error {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+
+    for sub := range b.subscribers[topic] {
+        select {
+        case sub.events <- event:
+        default:
+            // Never let a slow client block publishers
+            log.Printf("Dropping %s event on %s for slow subscriber", event.Type, topic)
+        }
+    }
+
+    return nil
+}
+
+func (b *MemoryBroker) Subscribe(topics ...string) (Subscription, error) {
+    sub := &memorySubscription{
+        broker: b,
+        topics: topics,
+        events: make(chan Event, subscriberBufferSize),
+    }
+
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    for _, topic := range topics {
+        if b.subscribers[topic] == nil {
+            b.subscribers[topic] = make(map[*memorySubscription]struct{})
+        }
+        b.subscribers[topic][sub] = struct{}{}
+    }
+
+    return sub, nil
+}
+
+func (b *MemoryBroker) unsubscribe(sub *memorySubscription) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    for _, topic := range sub.topics {
+        delete(b.subscribers[topic], sub)
+        if len(b.subscribers[topic]) == 0 {
+            delete(b.subscribers, topic)
+        }
+    }
+
+    close(sub.events)
+}
+
+type memorySubscription struct {
+    broker *MemoryBroker
+    topics []string
+    events chan Event
+    once   sync.Once
+}
+
+func (s *memorySubscription) Events() <-chan Event {
+    return s.events
+}
+
+func (s *memorySubscription) Close() {
+    s.once.Do(func() {
+        s.broker.unsubscribe(s)
+    })
+}
@@ -0,0 +1,60 @@
+package events
+
+import (
+    "fmt"
+    "time"
+)
+
+const (
+    TypePassengerJoined   = "passenger_joined"
+    TypePassengerLeft     = "passenger_left"
+    TypeTripUpdated       = "trip_updated"
+    TypeTripCancelled     = "trip_cancelled"
+    TypeMessagePosted     = "message_posted"
+    TypeDepartureReminder = "departure_reminder"
+    TypeTripStarted       = "trip_started"
+    TypeTripCompleted     = "trip_completed"
+    TypeLocationUpdated   = "location_updated"
+    TypeBookingRequested  = "booking_requested"
+    TypeBookingDeclined   = "booking_declined"
+    TypePassengerRemoved  = "passenger_removed"
+    TypeSeatsCancelled    = "seats_cancelled"
+    TypeWaitlistOffered   = "waitlist_offered"
+    TypeTripChanged       = "trip_changed"
+)
+
+// Event is a notification pushed to subscribed clients. It must stay JSON
+// serialisable so a broker can forward it between server instances.
+type Event struct {
+    Type       string      `json:"type"`
+    TripID     int         `json:"tripId,omitempty"`
+    UserID     int         `json:"userId,omitempty"`
+    Data       interface{} `json:"data,omitempty"`
+    OccurredAt time.Time   `json:"occurredAt"`
+}
+
+func New(eventType string, tripID, userID int, data interface{}) Event {
+    return Event{
+        Type:       eventType,
+        TripID:     tripID,
+        UserID:     userID,
+        Data:       data,
+        OccurredAt: time.Now().UTC(),
+    }
+}
+
+// TripTopic carries events about a single trip to its driver and passengers.
+func TripTopic(tripID int) string {
+    return fmt.Sprintf("trip:%d", tripID)
+}
+
+// UserTopic carries events addressed to a single user.
+func UserTopic(userID int) string {
+    return fmt.Sprintf("user:%d", userID)
+}
+
+// LocationTopic carries the driver's live position for a trip. It is kept
+// apart from TripTopic because updates are frequent.
+func LocationTopic(tripID int) string {
+    return fmt.Sprintf("trip:%d:location", tripID)
+}
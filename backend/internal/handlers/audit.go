@@ -0,0 +1,72 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/services"
+    "rideshare-backend/internal/utils"
+
+    "github.com/gin-gonic/gin"
+    "github.com/go-playground/validator/v10"
+)
+
+type AuditHandler struct {
+    auditService *services.AuditService
+    validator    *validator.Validate
+}
+
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+    return &AuditHandler{
+        auditService: auditService,
+        validator:    validator.New(),
+    }
+}
+
+// AuditRequest filters the audit log, read from the query string. Either an
+// entity or a user must be given.
+type AuditRequest struct {
+    EntityType string `validate:"required_without=UserID,omitempty,oneof=trip user"`
+    EntityID   int    `validate:"required_with=EntityType,omitempty,min=1"`
+    UserID     int    `validate:"omitempty,min=1"`
+    Limit      int    `validate:"omitempty,min=1,max=200"`
+}
+
+// GetEvents lists audit events for an entity, or by or about a user, most
+// recent first.
+func (h *AuditHandler) GetEvents(c *gin.Context) {
+    var req AuditRequest
+    var err error
+    
+    req.EntityType = c.Query("entityType")
+    for name, dest := range map[string]*int{"entityId": &req.EntityID, "userId": &req.UserID, "limit": &req.Limit} {
+        if *dest, err = strconv.Atoi(c.DefaultQuery(name, "0")); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+            return
+        }
+    }
+    
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return
+    }
+    
+    page, err := h.auditService.ListEvents(c.Request.Context(), models.AuditFilter{
+        EntityType: req.EntityType,
+        EntityID:   req.EntityID,
+        UserID:     req.UserID,
+        Cursor:     c.Query("cursor"),
+        Limit:      req.Limit,
+    })
+    if err != nil {
+        if errors.Is(err, services.ErrInvalidCursor) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit events"})
+        return
+    }
+    
+    c.JSON(http.StatusOK, page)
+}
@@ -9,22 +9,19 @@ import (
     
     "github.com/gin-gonic/gin"
     "github.com/go-playground/validator/v10"
-    "database/sql"
     "log"
 )
 
 type AuthHandler struct {
-    db          *sql.DB
     config      *config.Config
     authService *services.AuthService
     validator   *validator.Validate
 }
 
-func NewAuthHandler(db *sql.DB, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(cfg *config.Config, authService *services.AuthService) *AuthHandler {
     return &AuthHandler{
-        db:          db,
         config:      cfg,
-        authService: services.NewAuthService(db, cfg),
+        authService: authService,
         validator:   validator.New(),
     }
 }
@@ -34,6 +31,7 @@ type RegisterRequest struct {
     Email    string `json:"email" validate:"required,email"`
     Password string `json:"password" validate:"required,min=6"`
     Phone    string `json:"phone" validate:"required"`
+    Timezone string `json:"timezone"`
 }
 
 type LoginRequest struct {
@@ -61,7 +59,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
     }
     
     // Check if user already exists
-    existingUser, err := h.authService.GetUserByEmail(req.Email)
+    existingUser, err := h.authService.GetUserByEmail(c.Request.Context(), req.Email)
     if err != nil && err.Error() != "user not found" {
         // Only log if it's an unexpected error, not just "user not found"
         log.Printf("Error checking existing user: %v", err)
@@ -88,9 +86,18 @@ func (h *AuthHandler) Register(c *gin.Context) {
         Email:    req.Email,
         Password: hashedPassword,
         Phone:    req.Phone,
+        Timezone: "UTC",
     }
     
-    if err := h.authService.CreateUser(user); err != nil {
+    if req.Timezone != "" {
+        if !utils.ValidTimezone(req.Timezone) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone"})
+            return
+        }
+        user.Timezone = req.Timezone
+    }
+    
+    if err := h.authService.ForRequest(requestInfo(c)).CreateUser(c.Request.Context(), user); err != nil {
         log.Printf("User creation error: %v", err) // Add logging
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
         return
@@ -133,7 +140,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
     }
     
     // Get user by email
-    user, err := h.authService.GetUserByEmail(req.Email)
+    user, err := h.authService.GetUserByEmail(c.Request.Context(), req.Email)
     if err != nil {
         c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
         return
@@ -168,7 +175,7 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
         return
     }
     
-    user, err := h.authService.GetUserByID(userID.(int))
+    user, err := h.authService.GetUserByID(c.Request.Context(), userID.(int))
     if err != nil {
         c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
         return
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+    "context"
+    "io"
+    "log"
+    "net/http"
+    "strconv"
+    "time"
+    "rideshare-backend/internal/events"
+    "rideshare-backend/internal/services"
+
+    "github.com/gin-gonic/gin"
+)
+
+const streamHeartbeatInterval = 25 * time.Second
+
+type EventHandler struct {
+    broker      events.Broker
+    tripService *services.TripService
+}
+
+func NewEventHandler(broker events.Broker, tripService *services.TripService) *EventHandler {
+    return &EventHandler{
+        broker:      broker,
+        tripService: tripService,
+    }
+}
+
+// Stream pushes trip events to the current user as Server-Sent Events. By
+// default the user receives events for every active trip they take part in;
+// a tripId query parameter narrows the stream to one trip. Trips joined after
+// the stream opened are picked up when the client reconnects.
+func (h *EventHandler) Stream(c *gin.Context) {
+    userID, _ := c.Get("userID")
+    
+    topics := []string{events.UserTopic(userID.(int))}
+    
+    // The one trip followed, if the client asked for one
+    var tripID int
+    if rawTripID := c.Query("tripId"); rawTripID != "" {
+        var err error
+        tripID, err = strconv.Atoi(rawTripID)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+            return
+        }
+        
+        ok, err := h.tripService.IsParticipant(c.Request.Context(), tripID, userID.(int))
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check trip participation"})
+            return
+        }
+        if !ok {
+            c.JSON(http.StatusForbidden, gin.H{"error": "You are not part of this trip"})
+            return
+        }
+        
+        topics = append(topics, events.TripTopic(tripID))
+    } else {
+        tripIDs, err := h.tripService.GetActiveTripIDsForUser(c.Request.Context(), userID.(int))
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trips"})
+            return
+        }
+        
+        for _, tripID := range tripIDs {
+            topics = append(topics, events.TripTopic(tripID))
+        }
+    }
+    
+    sub, err := h.broker.Subscribe(topics...)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe to events"})
+        return
+    }
+    defer sub.Close()
+    
+    c.Header("Cache-Control", "no-cache")
+    c.Header("Connection", "keep-alive")
+    c.Header("X-Accel-Buffering", "no")
+    
+    heartbeat := time.NewTicker(streamHeartbeatInterval)
+    defer heartbeat.Stop()
+    
+    c.Stream(func(w io.Writer) bool {
+        select {
+        case <-c.Request.Context().Done():
+            return false
+        case event, ok := <-sub.Events():
+            if !ok {
+                return false
+            }
+            c.SSEvent(event.Type, event)
+            
+            // The stream can't drop a single trip, so it ends; the client
+            // reconnects to the trips it is still on
+            return !removesViewer(event, userID.(int))
+        case <-heartbeat.C:
+            if tripID != 0 && !h.stillParticipant(c.Request.Context(), tripID, userID.(int)) {
+                return false
+            }
+            c.SSEvent("ping", gin.H{"time": time.Now().UTC()})
+            return true
+        }
+    })
+}
+
+// removesViewer reports whether the event takes the user off the trip it is
+// about, which ends their streams of it: they left or the driver removed
+// them.
+func removesViewer(event events.Event, userID int) bool {
+    switch event.Type {
+    case events.TypePassengerLeft:
+        return event.UserID == userID
+    case events.TypePassengerRemoved:
+        // Only the removed passenger gets it, the driver is the actor
+        return true
+    default:
+        return false
+    }
+}
+
+// stillParticipant checks again that the user is on the trip, in case the
+// event taking them off it was missed. A failed check keeps the stream open.
+func (h *EventHandler) stillParticipant(ctx context.Context, tripID, userID int) bool {
+    ok, err := h.tripService.IsParticipant(ctx, tripID, userID)
+    if err != nil {
+        log.Printf("Failed to check participation of user %d in trip %d: %v", userID, tripID, err)
+        return true
+    }
+    return ok
+}
+
+// publishEvent delivers an event on a best-effort basis; a failed publish must
+// never fail the request that triggered it.
+func publishEvent(broker events.Broker, topic string, event events.Event) {
+    if err := broker.Publish(topic, event); err != nil {
+        log.Printf("Failed to publish %s event: %v", event.Type, err)
+    }
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "rideshare-backend/internal/money"
+    "rideshare-backend/internal/services"
+    "rideshare-backend/internal/utils"
+    
+    "github.com/gin-gonic/gin"
+    "github.com/go-playground/validator/v10"
+)
+
+type FareHandler struct {
+    fareService    *services.FareService
+    vehicleService *services.VehicleService
+    validator      *validator.Validate
+}
+
+func NewFareHandler(fareService *services.FareService, vehicleService *services.VehicleService) *FareHandler {
+    return &FareHandler{
+        fareService:    fareService,
+        vehicleService: vehicleService,
+        validator:      validator.New(),
+    }
+}
+
+// SuggestFareRequest takes either a route distance or the origin and
+// destination coordinates. Consumption and seats come from the driver's
+// vehicle when one is given, and fuel price and consumption fall back to the
+// server defaults.
+type SuggestFareRequest struct {
+    FromLatitude   *float64    `json:"fromLatitude" validate:"omitempty,min=-90,max=90"`
+    FromLongitude  *float64    `json:"fromLongitude" validate:"omitempty,min=-180,max=180"`
+    ToLatitude     *float64    `json:"toLatitude" validate:"omitempty,min=-90,max=90"`
+    ToLongitude    *float64    `json:"toLongitude" validate:"omitempty,min=-180,max=180"`
+    DistanceKm     *float64    `json:"distanceKm" validate:"omitempty,gt=0"`
+    FuelPrice      json.Number `json:"fuelPrice"`
+    Currency       string      `json:"currency" validate:"omitempty,len=3"`
+    LitresPer100Km float64     `json:"litresPer100Km" validate:"omitempty,gt=0,max=50"`
+    VehicleID      *int        `json:"vehicleId" validate:"omitempty,min=1"`
+    Seats          int         `json:"seats" validate:"required_without=VehicleID,omitempty,min=1,max=8"`
+}
+
+func (h *FareHandler) SuggestFare(c *gin.Context) {
+    var req SuggestFareRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+        return
+    }
+    
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return
+    }
+    
+    input := services.FareInput{
+        LitresPer100Km: req.LitresPer100Km,
+        Seats:          req.Seats,
+    }
+    
+    if req.VehicleID != nil {
+        userID, _ := c.Get("userID")
+        
+        vehicle, err := h.vehicleService.GetVehicle(c.Request.Context(), *req.VehicleID, userID.(int))
+        if err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+            return
+        }
+        
+        if input.LitresPer100Km == 0 && vehicle.LitresPer100Km != nil {
+            input.LitresPer100Km = *vehicle.LitresPer100Km
+        }
+        if input.Seats == 0 {
+            input.Seats = vehicle.PassengerSeats()
+        }
+    }
+    
+    switch {
+    case req.DistanceKm != nil:
+        input.DistanceKm = *req.DistanceKm
+    case req.FromLatitude != nil && req.FromLongitude != nil && req.ToLatitude != nil && req.ToLongitude != nil:
+        input.DistanceKm = h.fareService.RouteDistanceKm(*req.FromLatitude, *req.FromLongitude, *req.ToLatitude, *req.ToLongitude)
+    default:
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Provide distanceKm or origin and destination coordinates"})
+        return
+    }
+    
+    if req.FuelPrice != "" {
+        currency := req.Currency
+        if currency == "" {
+            currency = money.DefaultCurrency
+        }
+        
+        // Fuel is quoted more precisely than money.Parse allows, e.g. 1.759
+        amount, err := money.ParseRate(req.FuelPrice.String(), currency)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "fuelPrice: " + err.Error()})
+            return
+        }
+        input.FuelPrice = money.NewRate(amount, currency)
+    }
+    
+    c.JSON(http.StatusOK, h.fareService.Suggest(input))
+}
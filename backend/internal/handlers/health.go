@@ -0,0 +1,87 @@
+package handlers
+
+import (
+    "database/sql"
+    "log"
+    "net/http"
+    "rideshare-backend/internal/database"
+    
+    "github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+    db      *sql.DB
+    replica *sql.DB
+}
+
+// NewHealthHandler checks the database and, if not nil, its read replica.
+func NewHealthHandler(db, replica *sql.DB) *HealthHandler {
+    return &HealthHandler{db: db, replica: replica}
+}
+
+// Liveness reports that the server is up. It doesn't touch the database, so
+// a database outage doesn't get the server restarted.
+func (h *HealthHandler) Liveness(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness reports whether the server can take traffic: the database
+// answers and has every migration this build expects. Reads fall back to the
+// primary while the read replica is down, so an unreachable replica only
+// degrades the server rather than taking it out of rotation. The connection
+// pools' statistics are included for monitoring.
+func (h *HealthHandler) Readiness(c *gin.Context) {
+    ctx := c.Request.Context()
+    checks := gin.H{"database": "ok", "schema": "ok"}
+    ready, degraded := true, false
+    
+    if err := h.db.PingContext(ctx); err != nil {
+        log.Printf("Readiness check failed to reach the database: %v", err)
+        checks["database"] = "unreachable"
+        checks["schema"] = "unknown"
+        ready = false
+    } else if err := database.CheckSchema(ctx, h.db); err != nil {
+        checks["schema"] = err.Error()
+        ready = false
+    }
+    
+    pools := gin.H{"primary": poolStats(h.db.Stats())}
+    
+    if h.replica != nil {
+        checks["replica"] = "ok"
+        if err := h.replica.PingContext(ctx); err != nil {
+            log.Printf("Readiness check failed to reach the read replica: %v", err)
+            checks["replica"] = "unreachable"
+            degraded = true
+        }
+        pools["replica"] = poolStats(h.replica.Stats())
+    }
+    
+    status, code := "ready", http.StatusOK
+    if !ready {
+        status, code = "unavailable", http.StatusServiceUnavailable
+    } else if degraded {
+        status = "degraded"
+    }
+    
+    c.JSON(code, gin.H{
+        "status": status,
+        "checks": checks,
+        "pools":  pools,
+    })
+}
+
+// poolStats describes a connection pool's usage.
+func poolStats(stats sql.DBStats) gin.H {
+    return gin.H{
+        "maxOpenConnections": stats.MaxOpenConnections,
+        "openConnections":    stats.OpenConnections,
+        "inUse":              stats.InUse,
+        "idle":               stats.Idle,
+        "waitCount":          stats.WaitCount,
+        "waitDurationMs":     stats.WaitDuration.Milliseconds(),
+        "maxIdleClosed":      stats.MaxIdleClosed,
+        "maxIdleTimeClosed":  stats.MaxIdleTimeClosed,
+        "maxLifetimeClosed":  stats.MaxLifetimeClosed,
+    }
+}
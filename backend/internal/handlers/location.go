@@ -0,0 +1,292 @@
+package handlers
+
+import (
+    "encoding/json"
+    "io"
+    "net/http"
+    "strconv"
+    "time"
+    "rideshare-backend/internal/events"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/services"
+    "rideshare-backend/internal/utils"
+
+    "github.com/gin-gonic/gin"
+    "github.com/go-playground/validator/v10"
+)
+
+type LocationHandler struct {
+    locationService *services.LocationService
+    tripService     *services.TripService
+    broker          events.Broker
+    validator       *validator.Validate
+}
+
+func NewLocationHandler(locationService *services.LocationService, tripService *services.TripService, broker events.Broker) *LocationHandler {
+    return &LocationHandler{
+        locationService: locationService,
+        tripService:     tripService,
+        broker:          broker,
+        validator:       validator.New(),
+    }
+}
+
+type LocationUpdateRequest struct {
+    Latitude  *float64 `json:"latitude" validate:"required,min=-90,max=90"`
+    Longitude *float64 `json:"longitude" validate:"required,min=-180,max=180"`
+    Heading   *float64 `json:"heading" validate:"omitempty,min=0,max=360"`
+    SpeedKmh  *float64 `json:"speedKmh" validate:"omitempty,min=0"`
+}
+
+type PickupPointRequest struct {
+    Latitude  *float64 `json:"latitude" validate:"required,min=-90,max=90"`
+    Longitude *float64 `json:"longitude" validate:"required,min=-180,max=180"`
+}
+
+// viewer identifies who is following a trip's location. Drivers see every
+// passenger's ETA, passengers only their own.
+type viewer struct {
+    tripID   int
+    userID   int
+    isDriver bool
+}
+
+func (h *LocationHandler) authorizeViewer(c *gin.Context) (*viewer, bool) {
+    tripID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+        return nil, false
+    }
+
+    userID, _ := c.Get("userID")
+
+    trip, err := h.tripService.GetTripByID(c.Request.Context(), tripID, userID.(int))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Trip not found"})
+        return nil, false
+    }
+
+    if trip.DriverID == userID.(int) {
+        return &viewer{tripID: tripID, userID: userID.(int), isDriver: true}, true
+    }
+
+    ok, err := h.tripService.IsParticipant(c.Request.Context(), tripID, userID.(int))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check trip participation"})
+        return nil, false
+    }
+
+    if !ok {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the driver and confirmed passengers can follow this trip"})
+        return nil, false
+    }
+
+    return &viewer{tripID: tripID, userID: userID.(int)}, true
+}
+
+func (h *LocationHandler) liveLocation(v *viewer, position *models.TripLocation, pickups []models.PickupPoint) models.LiveLocation {
+    live := models.LiveLocation{Position: position, ETAs: []models.PickupETA{}}
+    if position == nil {
+        return live
+    }
+
+    for _, eta := range h.locationService.EstimateArrivals(position, pickups) {
+        if v.isDriver || eta.PassengerID == v.userID {
+            live.ETAs = append(live.ETAs, eta)
+        }
+    }
+
+    return live
+}
+
+func (h *LocationHandler) UpdateLocation(c *gin.Context) {
+    tripID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+        return
+    }
+
+    var req LocationUpdateRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+        return
+    }
+
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return
+    }
+
+    userID, _ := c.Get("userID")
+
+    location := &models.TripLocation{
+        TripID:    tripID,
+        Latitude:  *req.Latitude,
+        Longitude: *req.Longitude,
+        Heading:   req.Heading,
+        SpeedKmh:  req.SpeedKmh,
+    }
+
+    if err := h.locationService.RecordPosition(c.Request.Context(), location, userID.(int)); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    publishEvent(h.broker, events.LocationTopic(tripID), events.New(events.TypeLocationUpdated, tripID, userID.(int), location))
+
+    c.JSON(http.StatusCreated, location)
+}
+
+func (h *LocationHandler) GetLocation(c *gin.Context) {
+    v, ok := h.authorizeViewer(c)
+    if !ok {
+        return
+    }
+
+    position, err := h.locationService.GetLatestPosition(c.Request.Context(), v.tripID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch location"})
+        return
+    }
+
+    pickups, err := h.locationService.GetPickupPoints(c.Request.Context(), v.tripID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pickup points"})
+        return
+    }
+
+    c.JSON(http.StatusOK, h.liveLocation(v, position, pickups))
+}
+
+// StreamLocation pushes the driver's position and the viewer's ETA as
+// Server-Sent Events while the trip is in progress.
+func (h *LocationHandler) StreamLocation(c *gin.Context) {
+    v, ok := h.authorizeViewer(c)
+    if !ok {
+        return
+    }
+
+    pickups, err := h.locationService.GetPickupPoints(c.Request.Context(), v.tripID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pickup points"})
+        return
+    }
+
+    sub, err := h.broker.Subscribe(events.LocationTopic(v.tripID), events.TripTopic(v.tripID), events.UserTopic(v.userID))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe to location updates"})
+        return
+    }
+    defer sub.Close()
+
+    c.Header("Cache-Control", "no-cache")
+    c.Header("Connection", "keep-alive")
+    c.Header("X-Accel-Buffering", "no")
+
+    // Send the last known position straight away so the map isn't empty
+    if position, err := h.locationService.GetLatestPosition(c.Request.Context(), v.tripID); err == nil && position != nil {
+        c.SSEvent(events.TypeLocationUpdated, h.liveLocation(v, position, pickups))
+        c.Writer.Flush()
+    }
+
+    heartbeat := time.NewTicker(streamHeartbeatInterval)
+    defer heartbeat.Stop()
+
+    c.Stream(func(w io.Writer) bool {
+        select {
+        case <-c.Request.Context().Done():
+            return false
+        case event, ok := <-sub.Events():
+            if !ok {
+                return false
+            }
+
+            // The viewer's own topic carries events about other trips too
+            if event.TripID != v.tripID {
+                return true
+            }
+
+            switch event.Type {
+            case events.TypeLocationUpdated:
+                if position, err := decodeLocation(event.Data); err == nil {
+                    c.SSEvent(event.Type, h.liveLocation(v, position, pickups))
+                }
+                return true
+            case events.TypeTripCompleted, events.TypeTripCancelled:
+                c.SSEvent(event.Type, event)
+                return false
+            case events.TypePassengerLeft, events.TypePassengerRemoved:
+                // A passenger who is off the trip stops seeing the driver
+                if !v.isDriver && removesViewer(event, v.userID) {
+                    c.SSEvent(event.Type, event)
+                    return false
+                }
+                return true
+            default:
+                return true
+            }
+        case <-heartbeat.C:
+            // In case the event taking the passenger off the trip was missed
+            if !v.isDriver {
+                ok, err := h.tripService.IsParticipant(c.Request.Context(), v.tripID, v.userID)
+                if err == nil && !ok {
+                    return false
+                }
+            }
+            c.SSEvent("ping", gin.H{"time": time.Now().UTC()})
+            return true
+        }
+    })
+}
+
+// decodeLocation recovers a position from event data. The in-process broker
+// hands over the original value while serialising brokers deliver a map.
+func decodeLocation(data interface{}) (*models.TripLocation, error) {
+    if position, ok := data.(*models.TripLocation); ok {
+        return position, nil
+    }
+
+    raw, err := json.Marshal(data)
+    if err != nil {
+        return nil, err
+    }
+
+    position := &models.TripLocation{}
+    if err := json.Unmarshal(raw, position); err != nil {
+        return nil, err
+    }
+
+    return position, nil
+}
+
+func (h *LocationHandler) SetPickupPoint(c *gin.Context) {
+    tripID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+        return
+    }
+
+    var req PickupPointRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+        return
+    }
+
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return
+    }
+
+    userID, _ := c.Get("userID")
+
+    if err := h.locationService.SetPickupPoint(c.Request.Context(), tripID, userID.(int), *req.Latitude, *req.Longitude); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    c.JSON(http.StatusOK, models.PickupPoint{
+        PassengerID: userID.(int),
+        Latitude:    *req.Latitude,
+        Longitude:   *req.Longitude,
+    })
+}
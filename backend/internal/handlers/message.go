@@ -0,0 +1,143 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+    "rideshare-backend/internal/events"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/services"
+    "rideshare-backend/internal/utils"
+
+    "github.com/gin-gonic/gin"
+    "github.com/go-playground/validator/v10"
+)
+
+type MessageHandler struct {
+    messageService *services.MessageService
+    tripService    *services.TripService
+    broker         events.Broker
+    validator      *validator.Validate
+}
+
+func NewMessageHandler(messageService *services.MessageService, tripService *services.TripService, broker events.Broker) *MessageHandler {
+    return &MessageHandler{
+        messageService: messageService,
+        tripService:    tripService,
+        broker:         broker,
+        validator:      validator.New(),
+    }
+}
+
+type PostMessageRequest struct {
+    Body string `json:"body" validate:"required,min=1,max=2000"`
+}
+
+type MarkReadRequest struct {
+    MessageID int `json:"messageId" validate:"required,min=1"`
+}
+
+// authorizeParticipant resolves the trip ID from the path and makes sure the
+// current user may take part in its conversation.
+func (h *MessageHandler) authorizeParticipant(c *gin.Context) (int, int, bool) {
+    tripID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+        return 0, 0, false
+    }
+
+    userID, _ := c.Get("userID")
+
+    ok, err := h.tripService.IsParticipant(c.Request.Context(), tripID, userID.(int))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check trip participation"})
+        return 0, 0, false
+    }
+
+    if !ok {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the driver and confirmed passengers can access trip messages"})
+        return 0, 0, false
+    }
+
+    return tripID, userID.(int), true
+}
+
+func (h *MessageHandler) GetMessages(c *gin.Context) {
+    tripID, _, ok := h.authorizeParticipant(c)
+    if !ok {
+        return
+    }
+
+    before, _ := strconv.Atoi(c.Query("before"))
+    limit, _ := strconv.Atoi(c.Query("limit"))
+
+    page, err := h.messageService.GetMessages(c.Request.Context(), tripID, before, limit)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+        return
+    }
+
+    c.JSON(http.StatusOK, page)
+}
+
+func (h *MessageHandler) PostMessage(c *gin.Context) {
+    tripID, userID, ok := h.authorizeParticipant(c)
+    if !ok {
+        return
+    }
+
+    var req PostMessageRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+        return
+    }
+
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return
+    }
+
+    message := &models.TripMessage{
+        TripID:   tripID,
+        SenderID: userID,
+        Body:     req.Body,
+    }
+
+    if err := h.messageService.PostMessage(c.Request.Context(), message); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post message"})
+        return
+    }
+
+    publishEvent(h.broker, events.TripTopic(tripID), events.New(events.TypeMessagePosted, tripID, userID, message))
+
+    c.JSON(http.StatusCreated, message)
+}
+
+func (h *MessageHandler) MarkRead(c *gin.Context) {
+    tripID, userID, ok := h.authorizeParticipant(c)
+    if !ok {
+        return
+    }
+
+    var req MarkReadRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+        return
+    }
+
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return
+    }
+
+    if err := h.messageService.MarkRead(c.Request.Context(), tripID, userID, req.MessageID); err != nil {
+        if errors.Is(err, services.ErrMessageNotFound) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark messages as read"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Messages marked as read"})
+}
@@ -1,9 +1,19 @@
 package handlers
 
 import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
     "net/http"
+    "regexp"
     "strings"
+    "time"
     
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/services"
     "rideshare-backend/internal/utils"
     
     "github.com/gin-gonic/gin"
@@ -39,6 +49,171 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
     }
 }
 
+// StreamAuthMiddleware authenticates long-lived streaming requests. Browsers
+// cannot set headers on an EventSource, so the same JWT may also be passed in
+// the access_token query parameter. The token is taken out of the request URL
+// so nothing after this logs it.
+func StreamAuthMiddleware(jwtSecret string) gin.HandlerFunc {
+    authenticate := AuthMiddleware(jwtSecret)
+    
+    return func(c *gin.Context) {
+        if c.GetHeader("Authorization") == "" {
+            if token := c.Query("access_token"); token != "" {
+                c.Request.Header.Set("Authorization", "Bearer "+token)
+            }
+        }
+        c.Request.URL.RawQuery = redactAccessToken(c.Request.URL.RawQuery)
+        
+        authenticate(c)
+    }
+}
+
+var accessTokenParam = regexp.MustCompile(`(^|[?&])access_token=[^&]*`)
+
+// redactAccessToken hides the value of the access_token parameter in a query
+// string or a path with one.
+func redactAccessToken(s string) string {
+    return accessTokenParam.ReplaceAllString(s, "${1}access_token=REDACTED")
+}
+
+// AccessLogger logs every request like gin's default logger, except that
+// stream tokens passed in the URL are redacted. The logger reads the URL
+// before any middleware runs, so it can't rely on StreamAuthMiddleware.
+func AccessLogger() gin.HandlerFunc {
+    return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+        return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+            param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+            param.StatusCode,
+            param.Latency,
+            param.ClientIP,
+            param.Method,
+            redactAccessToken(param.Path),
+            param.ErrorMessage,
+        )
+    })
+}
+
+// requestIDPattern is what we accept as a request ID from the client or a
+// proxy in front of us.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestIDMiddleware tags every request with an ID, kept from the
+// X-Request-ID header if one was sent, and echoes it back so a request can be
+// traced through the logs and the audit log.
+func RequestIDMiddleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        requestID := c.GetHeader("X-Request-ID")
+        if !requestIDPattern.MatchString(requestID) {
+            raw := make([]byte, 16)
+            rand.Read(raw)
+            requestID = hex.EncodeToString(raw)
+        }
+        
+        c.Set("requestID", requestID)
+        c.Writer.Header().Set("X-Request-ID", requestID)
+        c.Next()
+    }
+}
+
+// DeadlineMiddleware cancels requests that run longer than timeout, or than
+// the limit set in routeTimeouts for their method and route, e.g.
+// "GET /api/v1/trips/:id". A limit of zero lets the request run until the
+// client goes away. A request that fails once its deadline has passed, or
+// once the client has gone away, answers 504 or 503 instead of whatever error
+// the handler made of its cancelled queries.
+func DeadlineMiddleware(timeout time.Duration, routeTimeouts map[string]time.Duration) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        limit := timeout
+        if routeTimeout, ok := routeTimeouts[c.Request.Method+" "+c.FullPath()]; ok {
+            limit = routeTimeout
+        }
+        
+        if limit <= 0 {
+            c.Next()
+            return
+        }
+        
+        ctx, cancel := context.WithTimeout(c.Request.Context(), limit)
+        defer cancel()
+        
+        c.Request = c.Request.WithContext(ctx)
+        c.Writer = &deadlineWriter{ResponseWriter: c.Writer, ctx: ctx}
+        c.Next()
+    }
+}
+
+// deadlineWriter replaces an error response written after the request's
+// context ended.
+type deadlineWriter struct {
+    gin.ResponseWriter
+    ctx      context.Context
+    replaced bool
+}
+
+func (w *deadlineWriter) WriteHeader(code int) {
+    if code < http.StatusBadRequest || w.ctx.Err() == nil || w.Written() {
+        w.ResponseWriter.WriteHeader(code)
+        return
+    }
+    
+    status, message := http.StatusGatewayTimeout, "Request timed out"
+    if !errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+        status, message = http.StatusServiceUnavailable, "Request cancelled"
+    }
+    
+    body, _ := json.Marshal(gin.H{"error": message})
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.ResponseWriter.WriteHeader(status)
+    w.ResponseWriter.Write(body)
+    w.replaced = true
+}
+
+// Write drops the body of a replaced response.
+func (w *deadlineWriter) Write(data []byte) (int, error) {
+    if w.replaced {
+        return len(data), nil
+    }
+    return w.ResponseWriter.Write(data)
+}
+
+func (w *deadlineWriter) WriteString(s string) (int, error) {
+    if w.replaced {
+        return len(s), nil
+    }
+    return w.ResponseWriter.WriteString(s)
+}
+
+// requestInfo identifies the request for the audit log.
+func requestInfo(c *gin.Context) models.RequestInfo {
+    return models.RequestInfo{
+        RequestID: c.GetString("requestID"),
+        ClientIP:  c.ClientIP(),
+    }
+}
+
+// AdminMiddleware only lets administrators through. It must run after
+// AuthMiddleware.
+func AdminMiddleware(authService *services.AuthService) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        userID, _ := c.Get("userID")
+        
+        isAdmin, err := authService.IsAdmin(c.Request.Context(), userID.(int))
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check admin rights"})
+            c.Abort()
+            return
+        }
+        
+        if !isAdmin {
+            c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+            c.Abort()
+            return
+        }
+        
+        c.Next()
+    }
+}
+
 func CORSMiddleware() gin.HandlerFunc {
     return func(c *gin.Context) {
         c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+    "bytes"
+    "net/http"
+    "net/http/httptest"
+    "rideshare-backend/internal/utils"
+    "strings"
+    "testing"
+
+    "github.com/gin-gonic/gin"
+)
+
+func TestStreamTokenIsNotLogged(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+
+    var logged bytes.Buffer
+    defaultWriter := gin.DefaultWriter
+    gin.DefaultWriter = &logged
+    defer func() { gin.DefaultWriter = defaultWriter }()
+
+    const secret = "test-secret"
+    token, err := utils.GenerateJWT(42, "rider@example.com", "Rider", secret)
+    if err != nil {
+        t.Fatalf("GenerateJWT: %v", err)
+    }
+
+    var userID interface{}
+    var rawQuery string
+    r := gin.New()
+    r.Use(AccessLogger())
+    r.GET("/stream", StreamAuthMiddleware(secret), func(c *gin.Context) {
+        userID, _ = c.Get("userID")
+        rawQuery = c.Request.URL.RawQuery
+        c.Status(http.StatusOK)
+    })
+
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream?since=3&access_token="+token, nil))
+
+    if w.Code != http.StatusOK {
+        t.Fatalf("status = %d, want 200", w.Code)
+    }
+    if userID != 42 {
+        t.Errorf("userID = %v, want 42", userID)
+    }
+    if strings.Contains(rawQuery, token) {
+        t.Errorf("handler saw the token in the query %q", rawQuery)
+    }
+    if strings.Contains(logged.String(), token) {
+        t.Errorf("token was logged: %s", logged.String())
+    }
+    if !strings.Contains(logged.String(), "/stream?since=3&access_token=REDACTED") {
+        t.Errorf("request not logged as expected: %s", logged.String())
+    }
+}
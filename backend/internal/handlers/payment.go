@@ -0,0 +1,53 @@
+package handlers
+
+import (
+    "net/http"
+    "strconv"
+    "rideshare-backend/internal/services"
+    
+    "github.com/gin-gonic/gin"
+)
+
+type PaymentHandler struct {
+    paymentService *services.PaymentService
+}
+
+func NewPaymentHandler(paymentService *services.PaymentService) *PaymentHandler {
+    return &PaymentHandler{
+        paymentService: paymentService,
+    }
+}
+
+func (h *PaymentHandler) GetBalances(c *gin.Context) {
+    userID, _ := c.Get("userID")
+    
+    balances, err := h.paymentService.GetBalances(c.Request.Context(), userID.(int))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch balances"})
+        return
+    }
+    
+    c.JSON(http.StatusOK, balances)
+}
+
+func (h *PaymentHandler) GetTransactions(c *gin.Context) {
+    userID, _ := c.Get("userID")
+    
+    limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+    if err != nil || limit <= 0 || limit > 100 {
+        limit = 50
+    }
+    
+    offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+    if err != nil || offset < 0 {
+        offset = 0
+    }
+    
+    transactions, err := h.paymentService.GetTransactions(c.Request.Context(), userID.(int), limit, offset)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
+        return
+    }
+    
+    c.JSON(http.StatusOK, transactions)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "rideshare-backend/internal/config"
+    "rideshare-backend/internal/events"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/money"
+    "rideshare-backend/internal/repository"
+    "rideshare-backend/internal/services"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// streamFixture is a trip in progress with one passenger, served by the
+// stream handlers.
+type streamFixture struct {
+    broker      *events.MemoryBroker
+    trips       *services.TripService
+    server      *httptest.Server
+    tripID      int
+    passengerID int
+}
+
+func newStreamFixture(t *testing.T) *streamFixture {
+    t.Helper()
+    gin.SetMode(gin.TestMode)
+
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    broker := events.NewMemoryBroker()
+    trips := services.NewTripService(repository.NewRouter(store, nil, 0))
+    locations := services.NewLocationService(store, &config.Config{LocationRetention: time.Hour})
+
+    var users []int
+    for _, name := range []string{"driver", "passenger"} {
+        user := &models.User{Name: name, Email: name + "@example.com", Password: "hash", Phone: "+10000000000", Timezone: "UTC"}
+        if err := store.Users().Create(ctx, user); err != nil {
+            t.Fatalf("create user: %v", err)
+        }
+        users = append(users, user.ID)
+    }
+    driverID, passengerID := users[0], users[1]
+
+    trip := &models.Trip{
+        DriverID:       driverID,
+        FromLocation:   "Berlin",
+        ToLocation:     "Hamburg",
+        DepartureTime:  time.Now().Add(time.Hour),
+        Timezone:       "UTC",
+        MaxPassengers:  3,
+        PricePerPerson: money.New(1500, "EUR"),
+        FareMode:       models.FareModeFixed,
+        Preferences:    models.TripPreferences{LuggageSize: models.LuggageMedium, InstantBooking: true},
+    }
+    if err := trips.CreateTrip(ctx, trip); err != nil {
+        t.Fatalf("create trip: %v", err)
+    }
+    if _, err := trips.JoinTrip(ctx, trip.ID, passengerID, 1, nil); err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+    if err := trips.StartTrip(ctx, trip.ID, driverID); err != nil {
+        t.Fatalf("StartTrip: %v", err)
+    }
+
+    r := gin.New()
+    r.Use(func(c *gin.Context) {
+        c.Set("userID", passengerID)
+        c.Next()
+    })
+    r.GET("/events", NewEventHandler(broker, trips).Stream)
+    r.GET("/trips/:id/location/stream", NewLocationHandler(locations, trips, broker).StreamLocation)
+
+    server := httptest.NewServer(r)
+    t.Cleanup(server.Close)
+
+    return &streamFixture{broker: broker, trips: trips, server: server, tripID: trip.ID, passengerID: passengerID}
+}
+
+// assertStreamEnds opens the stream and publishes the event until the server
+// closes it, failing if it stays open.
+func (f *streamFixture) assertStreamEnds(t *testing.T, path, topic string, event events.Event) {
+    t.Helper()
+
+    // Headers only arrive with the first event, so read in the background
+    done := make(chan string, 1)
+    go func() {
+        resp, err := http.Get(f.server.URL + path)
+        if err != nil {
+            t.Errorf("GET %s: %v", path, err)
+            done <- ""
+            return
+        }
+        defer resp.Body.Close()
+        body, _ := io.ReadAll(resp.Body)
+        done <- string(body)
+    }()
+
+    // The handler may not have subscribed yet, so keep publishing
+    ticker := time.NewTicker(10 * time.Millisecond)
+    defer ticker.Stop()
+    timeout := time.After(2 * time.Second)
+    for {
+        select {
+        case body := <-done:
+            if !strings.Contains(body, "event:"+event.Type) {
+                t.Errorf("stream ended without the %s event: %q", event.Type, body)
+            }
+            return
+        case <-ticker.C:
+            f.broker.Publish(topic, event)
+        case <-timeout:
+            t.Fatalf("%s stayed open after %s", path, event.Type)
+        }
+    }
+}
+
+func TestLocationStreamEndsWhenPassengerLeaves(t *testing.T) {
+    f := newStreamFixture(t)
+
+    f.assertStreamEnds(t, fmt.Sprintf("/trips/%d/location/stream", f.tripID), events.TripTopic(f.tripID),
+        events.New(events.TypePassengerLeft, f.tripID, f.passengerID, nil))
+}
+
+func TestLocationStreamEndsWhenPassengerIsRemoved(t *testing.T) {
+    f := newStreamFixture(t)
+
+    f.assertStreamEnds(t, fmt.Sprintf("/trips/%d/location/stream", f.tripID), events.UserTopic(f.passengerID),
+        events.New(events.TypePassengerRemoved, f.tripID, 1, nil))
+}
+
+func TestEventStreamEndsWhenPassengerLeaves(t *testing.T) {
+    f := newStreamFixture(t)
+
+    f.assertStreamEnds(t, fmt.Sprintf("/events?tripId=%d", f.tripID), events.TripTopic(f.tripID),
+        events.New(events.TypePassengerLeft, f.tripID, f.passengerID, nil))
+}
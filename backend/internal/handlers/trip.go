@@ -1,46 +1,271 @@
 package handlers
 
 import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
     "net/http"
+    "log"
     "strconv"
     "time"
+    "rideshare-backend/internal/config"
+    "rideshare-backend/internal/events"
     "rideshare-backend/internal/models"
+    "rideshare-backend/internal/money"
     "rideshare-backend/internal/services"
     "rideshare-backend/internal/utils"
     
     "github.com/gin-gonic/gin"
     "github.com/go-playground/validator/v10"
-    "database/sql"
 )
 
 type TripHandler struct {
-    db          *sql.DB
-    tripService *services.TripService
-    validator   *validator.Validate
+    tripService    *services.TripService
+    paymentService *services.PaymentService
+    vehicleService *services.VehicleService
+    authService    *services.AuthService
+    preferences    *services.PreferenceService
+    broker         events.Broker
+    waitlist       *services.WaitlistService
+    emailService   *services.EmailService
+    validator      *validator.Validate
+    seatHoldTTL    time.Duration
+    materialShift  time.Duration
 }
 
-func NewTripHandler(db *sql.DB) *TripHandler {
+func NewTripHandler(
+    cfg *config.Config,
+    broker events.Broker,
+    tripService *services.TripService,
+    paymentService *services.PaymentService,
+    vehicleService *services.VehicleService,
+    authService *services.AuthService,
+    preferences *services.PreferenceService,
+    waitlist *services.WaitlistService,
+    emailService *services.EmailService,
+) *TripHandler {
     return &TripHandler{
-        db:          db,
-        tripService: services.NewTripService(db),
-        validator:   validator.New(),
+        tripService:    tripService,
+        paymentService: paymentService,
+        vehicleService: vehicleService,
+        authService:    authService,
+        preferences:    preferences,
+        broker:         broker,
+        waitlist:       waitlist,
+        emailService:   emailService,
+        validator:      validator.New(),
+        seatHoldTTL:    cfg.SeatHoldTTL,
+        materialShift:  cfg.TripChangeMaterialShift,
     }
 }
 
 type CreateTripRequest struct {
-    From            string  `json:"from" validate:"required"`
-    To              string  `json:"to" validate:"required"`
-    DepartureTime   string  `json:"departureTime" validate:"required"`
-    MaxPassengers   int     `json:"maxPassengers" validate:"required,min=1,max=8"`
-    PricePerPerson  float64 `json:"pricePerPerson" validate:"required,min=0"`
-    Description     string  `json:"description"`
+    From           string                 `json:"from" validate:"required"`
+    To             string                 `json:"to" validate:"required"`
+    DepartureTime  string                 `json:"departureTime" validate:"required"`
+    MaxPassengers  int                    `json:"maxPassengers" validate:"required,min=1,max=8"`
+    PricePerPerson json.Number            `json:"pricePerPerson" validate:"required_unless=FareMode shared"`
+    Currency       string                 `json:"currency" validate:"omitempty,len=3"`
+    FareMode       string                 `json:"fareMode" validate:"omitempty,oneof=fixed shared"`
+    TotalCost      json.Number            `json:"totalCost" validate:"required_if=FareMode shared"`
+    VehicleID      *int                   `json:"vehicleId" validate:"omitempty,min=1"`
+    Preferences    TripPreferencesRequest `json:"preferences"`
+    Description    string                 `json:"description"`
+    Timezone       string                 `json:"timezone"`
 }
 
+// TripPreferencesRequest sets the rules of a trip. Trips are booked
+// instantly and take medium luggage unless the driver says otherwise.
+type TripPreferencesRequest struct {
+    PetsAllowed    bool   `json:"petsAllowed"`
+    SmokingAllowed bool   `json:"smokingAllowed"`
+    LuggageSize    string `json:"luggageSize" validate:"omitempty,oneof=none small medium large"`
+    Music          *bool  `json:"music"`
+    WomenOnly      bool   `json:"womenOnly"`
+    InstantBooking *bool  `json:"instantBooking"`
+}
+
+func (r *TripPreferencesRequest) preferences() models.TripPreferences {
+    prefs := models.TripPreferences{
+        PetsAllowed:    r.PetsAllowed,
+        SmokingAllowed: r.SmokingAllowed,
+        LuggageSize:    r.LuggageSize,
+        Music:          true,
+        WomenOnly:      r.WomenOnly,
+        InstantBooking: true,
+    }
+    
+    if prefs.LuggageSize == "" {
+        prefs.LuggageSize = models.LuggageMedium
+    }
+    if r.Music != nil {
+        prefs.Music = *r.Music
+    }
+    if r.InstantBooking != nil {
+        prefs.InstantBooking = *r.InstantBooking
+    }
+    
+    return prefs
+}
+
+// ApplyPricing parses the fare exactly, without passing through float64, and
+// sets it on the trip. Shared-cost trips are priced from their total cost.
+func (r *CreateTripRequest) ApplyPricing(trip *models.Trip) error {
+    currency := r.Currency
+    if currency == "" {
+        currency = money.DefaultCurrency
+    }
+    
+    trip.FareMode = r.FareMode
+    if trip.FareMode == "" {
+        trip.FareMode = models.FareModeFixed
+    }
+    
+    if trip.FareMode == models.FareModeShared {
+        total, err := money.Parse(r.TotalCost.String(), currency)
+        if err != nil {
+            return fmt.Errorf("totalCost: %w", err)
+        }
+        
+        totalCost := money.New(total, currency)
+        trip.TotalCost = &totalCost
+        trip.PricePerPerson = money.New(services.SharedFare(total, trip.CurrentPassengers), currency)
+        return nil
+    }
+    
+    amount, err := money.Parse(r.PricePerPerson.String(), currency)
+    if err != nil {
+        return fmt.Errorf("pricePerPerson: %w", err)
+    }
+    
+    trip.TotalCost = nil
+    trip.PricePerPerson = money.New(amount, currency)
+    return nil
+}
+
+// SearchTripsRequest filters open trips. Preferences left out of the request
+// fall back to the ones the passenger saved on their profile.
+// Departure times are read in the caller's timezone, given as timezone, the
+// X-Timezone header or their profile, unless they carry an offset. TimeOfDay names a
+// window; FromHour and ToHour set a custom one.
 type SearchTripsRequest struct {
-    From          string `json:"from"`
-    To            string `json:"to"`
-    DepartureDate string `json:"departureDate"`
-    MaxPrice      float64 `json:"maxPrice"`
+    From          string                  `json:"from"`
+    To            string                  `json:"to"`
+    DepartureDate string                  `json:"departureDate"`
+    DepartAfter   string                  `json:"departAfter"`
+    DepartBefore  string                  `json:"departBefore"`
+    TimeOfDay     string                  `json:"timeOfDay" validate:"omitempty,oneof=morning afternoon evening night"`
+    FromHour      *int                    `json:"fromHour" validate:"required_with=ToHour,omitempty,min=0,max=23"`
+    ToHour        *int                    `json:"toHour" validate:"required_with=FromHour,omitempty,min=0,max=24"`
+    Timezone      string                  `json:"timezone"`
+    MaxPrice      json.Number             `json:"maxPrice"`
+    Currency      string                  `json:"currency" validate:"omitempty,len=3"`
+    Preferences   models.PreferenceFilter `json:"preferences"`
+    Sort          string                  `json:"sort" validate:"omitempty,oneof=departure_time price seats_left match_score"`
+    Cursor        string                  `json:"cursor"`
+    Limit         int                     `json:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+// DepartureRanges resolves the requested departure window into UTC ranges.
+func (r *SearchTripsRequest) DepartureRanges(loc *time.Location, now time.Time) ([]models.TimeRange, error) {
+    var after, before time.Time
+    
+    if r.DepartureDate != "" {
+        date, err := time.ParseInLocation("2006-01-02", r.DepartureDate, loc)
+        if err != nil {
+            return nil, fmt.Errorf("departureDate: expected YYYY-MM-DD")
+        }
+        after, before = date, date.AddDate(0, 0, 1)
+    }
+    
+    if r.DepartAfter != "" {
+        t, err := parseLocalTime(r.DepartAfter, loc)
+        if err != nil {
+            return nil, fmt.Errorf("departAfter: %w", err)
+        }
+        after = t
+    }
+    
+    if r.DepartBefore != "" {
+        t, err := parseLocalTime(r.DepartBefore, loc)
+        if err != nil {
+            return nil, fmt.Errorf("departBefore: %w", err)
+        }
+        before = t
+    }
+    
+    var window *services.TimeOfDay
+    if r.TimeOfDay != "" {
+        named := services.TimesOfDay[r.TimeOfDay]
+        window = &named
+    }
+    if r.FromHour != nil && r.ToHour != nil {
+        window = &services.TimeOfDay{FromHour: *r.FromHour, ToHour: *r.ToHour}
+    }
+    
+    return services.DepartureRanges(after, before, window, loc, now)
+}
+
+// parseLocalTime accepts an RFC 3339 timestamp, or a date and time without
+// offset which is read in loc.
+func parseLocalTime(value string, loc *time.Location) (time.Time, error) {
+    if t, err := time.Parse(time.RFC3339, value); err == nil {
+        return t, nil
+    }
+    
+    for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+        if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+            return t, nil
+        }
+    }
+    
+    return time.Time{}, fmt.Errorf("expected an RFC 3339 or local date and time")
+}
+
+// callerLocation returns the timezone the caller asked for, falling back to
+// the one on their profile.
+func (h *TripHandler) callerLocation(c *gin.Context, name string, userID int) (*time.Location, error) {
+    if name == "" {
+        name = c.GetHeader("X-Timezone")
+    }
+    if name == "" {
+        user, err := h.authService.GetUserByID(c.Request.Context(), userID)
+        if err != nil {
+            return nil, err
+        }
+        name = user.Timezone
+    }
+    
+    if !utils.ValidTimezone(name) {
+        return nil, fmt.Errorf("unknown timezone %q", name)
+    }
+    
+    return utils.LoadLocation(name), nil
+}
+
+// MaxPriceLimit returns the price ceiling, or nil when the search has none.
+func (r *SearchTripsRequest) MaxPriceLimit() (*money.Money, error) {
+    if r.MaxPrice == "" {
+        return nil, nil
+    }
+    
+    currency := r.Currency
+    if currency == "" {
+        currency = money.DefaultCurrency
+    }
+    
+    amount, err := money.Parse(r.MaxPrice.String(), currency)
+    if err != nil {
+        return nil, fmt.Errorf("maxPrice: %w", err)
+    }
+    
+    if amount == 0 {
+        return nil, nil
+    }
+    
+    limit := money.New(amount, currency)
+    return &limit, nil
 }
 
 func (h *TripHandler) CreateTrip(c *gin.Context) {
@@ -57,10 +282,8 @@ func (h *TripHandler) CreateTrip(c *gin.Context) {
     
     userID, _ := c.Get("userID")
     
-    // Parse departure time
-    departureTime, err := time.Parse(time.RFC3339, req.DepartureTime)
-    if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid departure time format"})
+    departureTime, timezone, ok := h.departure(c, &req, userID.(int))
+    if !ok {
         return
     }
 
@@ -75,13 +298,24 @@ func (h *TripHandler) CreateTrip(c *gin.Context) {
         FromLocation:    req.From,
         ToLocation:      req.To,
         DepartureTime:   departureTime,
+        Timezone:        timezone,
         MaxPassengers:   req.MaxPassengers,
-        PricePerPerson:  req.PricePerPerson,
         Description:     description,
+        VehicleID:       req.VehicleID,
+        Preferences:     req.Preferences.preferences(),
         Status:          "active",
     }
     
-    if err := h.tripService.CreateTrip(trip); err != nil {
+    if !h.checkVehicle(c, trip) {
+        return
+    }
+    
+    if err := req.ApplyPricing(trip); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    if err := h.tripService.ForRequest(requestInfo(c)).CreateTrip(c.Request.Context(), trip); err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create trip"})
         return
     }
@@ -92,13 +326,78 @@ func (h *TripHandler) CreateTrip(c *gin.Context) {
 func (h *TripHandler) GetTrips(c *gin.Context) {
     userID, _ := c.Get("userID")
     
-    trips, err := h.tripService.GetUserTrips(userID.(int))
+    limit, _ := strconv.Atoi(c.Query("limit"))
+    
+    page, err := h.tripService.GetUserTrips(c.Request.Context(), userID.(int), c.Query("cursor"), limit)
     if err != nil {
+        if errors.Is(err, services.ErrInvalidCursor) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+            return
+        }
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trips"})
         return
     }
     
-    c.JSON(http.StatusOK, trips)
+    c.JSON(http.StatusOK, page)
+}
+
+// TimelineRequest filters the timeline, read from the query string. When is
+// upcoming unless past is asked for.
+type TimelineRequest struct {
+    When          string `validate:"omitempty,oneof=upcoming past"`
+    Role          string `validate:"omitempty,oneof=driver passenger"`
+    Status        string `validate:"omitempty,oneof=active in_progress completed cancelled"`
+    BookingStatus string `validate:"omitempty,oneof=pending confirmed cancelled"`
+    Limit         int    `validate:"omitempty,min=1,max=100"`
+}
+
+// GetTimeline lists the trips the user drives or has booked, upcoming or
+// past, with their role and booking status on each.
+func (h *TripHandler) GetTimeline(c *gin.Context) {
+    userID, _ := c.Get("userID")
+    
+    limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+        return
+    }
+    
+    req := TimelineRequest{
+        When:          c.Query("when"),
+        Role:          c.Query("role"),
+        Status:        c.Query("status"),
+        BookingStatus: c.Query("bookingStatus"),
+        Limit:         limit,
+    }
+    
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return
+    }
+    
+    if req.Role == models.RoleDriver && req.BookingStatus != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "bookingStatus only applies to trips booked as a passenger"})
+        return
+    }
+    
+    page, err := h.tripService.GetTimeline(c.Request.Context(), userID.(int), models.TimelineFilter{
+        When:          req.When,
+        Role:          req.Role,
+        Status:        req.Status,
+        BookingStatus: req.BookingStatus,
+        Cursor:        c.Query("cursor"),
+        Limit:         req.Limit,
+    })
+    if err != nil {
+        if errors.Is(err, services.ErrInvalidCursor) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timeline"})
+        return
+    }
+    
+    c.JSON(http.StatusOK, page)
 }
 
 func (h *TripHandler) GetTrip(c *gin.Context) {
@@ -108,15 +407,75 @@ func (h *TripHandler) GetTrip(c *gin.Context) {
         return
     }
     
-    trip, err := h.tripService.GetTripByID(tripID)
+    userID, _ := c.Get("userID")
+    
+    trip, err := h.tripService.GetTripByID(c.Request.Context(), tripID, userID.(int))
     if err != nil {
         c.JSON(http.StatusNotFound, gin.H{"error": "Trip not found"})
         return
     }
     
+    // The car is only revealed to people riding in it, to find it at pickup
+    if trip.VehicleID != nil {
+        ok, err := h.tripService.IsParticipant(c.Request.Context(), tripID, userID.(int))
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check trip participation"})
+            return
+        }
+        
+        if ok {
+            trip.Vehicle, err = h.vehicleService.GetTripVehicle(c.Request.Context(), tripID)
+            if err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vehicle"})
+                return
+            }
+        }
+    }
+    
     c.JSON(http.StatusOK, trip)
 }
 
+// departure resolves the trip's timezone, defaulting to the driver's, and
+// reads the departure time in it unless it carries its own offset.
+func (h *TripHandler) departure(c *gin.Context, req *CreateTripRequest, driverID int) (time.Time, string, bool) {
+    timezone := req.Timezone
+    if timezone == "" {
+        driver, err := h.authService.GetUserByID(c.Request.Context(), driverID)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch driver"})
+            return time.Time{}, "", false
+        }
+        timezone = driver.Timezone
+    }
+    
+    if !utils.ValidTimezone(timezone) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone"})
+        return time.Time{}, "", false
+    }
+    
+    departureTime, err := parseLocalTime(req.DepartureTime, utils.LoadLocation(timezone))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid departure time format"})
+        return time.Time{}, "", false
+    }
+    
+    return departureTime.In(utils.LoadLocation(timezone)), timezone, true
+}
+
+// checkVehicle rejects trips offering more seats than the chosen vehicle has.
+func (h *TripHandler) checkVehicle(c *gin.Context, trip *models.Trip) bool {
+    if trip.VehicleID == nil {
+        return true
+    }
+    
+    if err := h.vehicleService.CheckCapacity(c.Request.Context(), *trip.VehicleID, trip.DriverID, trip.MaxPassengers); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return false
+    }
+    
+    return true
+}
+
 func (h *TripHandler) UpdateTrip(c *gin.Context) {
     tripID, err := strconv.Atoi(c.Param("id"))
     if err != nil {
@@ -137,10 +496,8 @@ func (h *TripHandler) UpdateTrip(c *gin.Context) {
     
     userID, _ := c.Get("userID")
     
-    // Parse departure time
-    departureTime, err := time.Parse(time.RFC3339, req.DepartureTime)
-    if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid departure time format"})
+    departureTime, timezone, ok := h.departure(c, &req, userID.(int))
+    if !ok {
         return
     }
 
@@ -156,16 +513,50 @@ func (h *TripHandler) UpdateTrip(c *gin.Context) {
         FromLocation:    req.From,
         ToLocation:      req.To,
         DepartureTime:   departureTime,
+        Timezone:        timezone,
         MaxPassengers:   req.MaxPassengers,
-        PricePerPerson:  req.PricePerPerson,
         Description:     description,
+        VehicleID:       req.VehicleID,
+        Preferences:     req.Preferences.preferences(),
     }
     
-    if err := h.tripService.UpdateTrip(trip); err != nil {
+    if !h.checkVehicle(c, trip) {
+        return
+    }
+    
+    if err := req.ApplyPricing(trip); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    change, err := h.tripService.ForRequest(requestInfo(c)).UpdateTrip(c.Request.Context(), trip, h.materialShift)
+    if err != nil {
+        if errors.Is(err, services.ErrSeatsBooked) {
+            c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+            return
+        }
+        if errors.Is(err, services.ErrTripNotActive) {
+            c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+            return
+        }
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update trip"})
         return
     }
     
+    publishEvent(h.broker, events.TripTopic(trip.ID), events.New(events.TypeTripUpdated, trip.ID, trip.DriverID, trip))
+    
+    if change != nil && change.Material {
+        go h.notifyTripChange(trip, change)
+    }
+    
+    // Raising the capacity may let waiting passengers in
+    h.promoteWaitlist(c.Request.Context(), trip.ID)
+    
+    // A new total cost reprices a shared-cost trip. Passengers asked about
+    // a material change are only charged an increase once they keep their
+    // booking.
+    h.settleSharedFare(c.Request.Context(), trip.ID)
+    
     c.JSON(http.StatusOK, trip)
 }
 
@@ -178,11 +569,21 @@ func (h *TripHandler) DeleteTrip(c *gin.Context) {
     
     userID, _ := c.Get("userID")
     
-    if err := h.tripService.DeleteTrip(tripID, userID.(int)); err != nil {
+    if err := h.tripService.ForRequest(requestInfo(c)).DeleteTrip(c.Request.Context(), tripID, userID.(int)); err != nil {
+        if errors.Is(err, services.ErrTripNotActive) {
+            c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+            return
+        }
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete trip"})
         return
     }
     
+    if err := h.paymentService.RefundTrip(c.Request.Context(), tripID); err != nil {
+        log.Printf("Failed to refund passengers of trip %d: %v", tripID, err)
+    }
+    
+    publishEvent(h.broker, events.TripTopic(tripID), events.New(events.TypeTripCancelled, tripID, userID.(int), nil))
+    
     c.JSON(http.StatusOK, gin.H{"message": "Trip deleted successfully"})
 }
 
@@ -193,13 +594,192 @@ func (h *TripHandler) SearchTrips(c *gin.Context) {
         return
     }
     
-    trips, err := h.tripService.SearchTrips(req.From, req.To, req.DepartureDate, req.MaxPrice)
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return
+    }
+    
+    maxPrice, err := req.MaxPriceLimit()
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    userID, _ := c.Get("userID")
+    
+    loc, err := h.callerLocation(c, req.Timezone, userID.(int))
     if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    departures, err := req.DepartureRanges(loc, time.Now())
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    saved, err := h.preferences.GetUserPreferences(c.Request.Context(), userID.(int))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch preferences"})
+        return
+    }
+    
+    page, err := h.tripService.SearchTrips(c.Request.Context(), userID.(int), models.TripSearchCriteria{
+        From:          req.From,
+        To:            req.To,
+        Departures:    departures,
+        MaxPrice:      maxPrice,
+        Preferences:   req.Preferences.Or(saved),
+        Sort:          req.Sort,
+        Cursor:        req.Cursor,
+        Limit:         req.Limit,
+    })
+    if err != nil {
+        if errors.Is(err, services.ErrInvalidCursor) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+            return
+        }
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search trips"})
         return
     }
     
-    c.JSON(http.StatusOK, trips)
+    c.JSON(http.StatusOK, page)
+}
+
+// JoinTripRequest books seats for the passenger and guests travelling with
+// them, who may be named. Seats must match the passenger's hold, if any.
+type JoinTripRequest struct {
+    Seats  int      `json:"seats" validate:"omitempty,min=1,max=8"`
+    Guests []string `json:"guests" validate:"omitempty,max=7,dive,required,max=100"`
+}
+
+type HoldSeatsRequest struct {
+    Seats int `json:"seats" validate:"omitempty,min=1,max=8"`
+}
+
+// HoldSeats keeps seats for the passenger while they go through checkout.
+// Joining the trip before the hold expires turns it into the booking.
+func (h *TripHandler) HoldSeats(c *gin.Context) {
+    tripID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+        return
+    }
+    
+    // The body is optional, a bare hold is for a single seat
+    var req HoldSeatsRequest
+    if c.Request.ContentLength != 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+            return
+        }
+    }
+    
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return
+    }
+    
+    seats := req.Seats
+    if seats == 0 {
+        seats = 1
+    }
+    
+    userID, _ := c.Get("userID")
+    
+    hold, err := h.tripService.HoldSeats(c.Request.Context(), tripID, userID.(int), seats, h.seatHoldTTL)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    c.JSON(http.StatusCreated, hold)
+}
+
+func (h *TripHandler) ReleaseHold(c *gin.Context) {
+    tripID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+        return
+    }
+    
+    userID, _ := c.Get("userID")
+    
+    if err := h.tripService.ReleaseHold(c.Request.Context(), tripID, userID.(int)); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    h.promoteWaitlist(c.Request.Context(), tripID)
+    
+    c.JSON(http.StatusOK, gin.H{"message": "Seats released"})
+}
+
+// JoinWaitlistRequest puts the passenger in line for seats on a full trip,
+// for themselves and guests travelling with them.
+type JoinWaitlistRequest struct {
+    Seats  int      `json:"seats" validate:"omitempty,min=1,max=8"`
+    Guests []string `json:"guests" validate:"omitempty,max=7,dive,required,max=100"`
+}
+
+func (h *TripHandler) JoinWaitlist(c *gin.Context) {
+    tripID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+        return
+    }
+    
+    // The body is optional, a bare request waits for a single seat
+    var req JoinWaitlistRequest
+    if c.Request.ContentLength != 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+            return
+        }
+    }
+    
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return
+    }
+    
+    seats := req.Seats
+    if seats == 0 {
+        seats = 1
+    }
+    
+    userID, _ := c.Get("userID")
+    
+    entry, err := h.waitlist.JoinWaitlist(c.Request.Context(), tripID, userID.(int), seats, req.Guests)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    c.JSON(http.StatusCreated, entry)
+}
+
+func (h *TripHandler) LeaveWaitlist(c *gin.Context) {
+    tripID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+        return
+    }
+    
+    userID, _ := c.Get("userID")
+    
+    released, err := h.waitlist.LeaveWaitlist(c.Request.Context(), tripID, userID.(int))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    if released {
+        h.promoteWaitlist(c.Request.Context(), tripID)
+    }
+    
+    c.JSON(http.StatusOK, gin.H{"message": "Left the waitlist"})
 }
 
 func (h *TripHandler) JoinTrip(c *gin.Context) {
@@ -209,12 +789,401 @@ func (h *TripHandler) JoinTrip(c *gin.Context) {
         return
     }
     
+    // The body is optional, a bare join books the held seats or a single one
+    var req JoinTripRequest
+    if c.Request.ContentLength != 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+            return
+        }
+    }
+    
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return
+    }
+    
     userID, _ := c.Get("userID")
     
-    if err := h.tripService.JoinTrip(tripID, userID.(int)); err != nil {
+    status, err := h.tripService.ForRequest(requestInfo(c)).JoinTrip(c.Request.Context(), tripID, userID.(int), req.Seats, req.Guests)
+    if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
     
-    c.JSON(http.StatusOK, gin.H{"message": "Successfully joined trip"})
+    if status == "pending" {
+        if trip, err := h.tripService.GetTripByID(c.Request.Context(), tripID, userID.(int)); err == nil {
+            publishEvent(h.broker, events.UserTopic(trip.DriverID), events.New(events.TypeBookingRequested, tripID, userID.(int), nil))
+        }
+        
+        c.JSON(http.StatusAccepted, gin.H{"message": "Booking request sent to the driver", "status": status})
+        return
+    }
+    
+    if err := h.chargeBooking(c, tripID, userID.(int)); err != nil {
+        c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+        return
+    }
+    
+    c.JSON(http.StatusOK, gin.H{"message": "Successfully joined trip", "status": status})
+}
+
+// notifyTripChange asks each passenger affected by a material change to keep
+// or cancel their booking. It runs after the response is sent, so it doesn't
+// use the request's context.
+func (h *TripHandler) notifyTripChange(trip *models.Trip, change *models.TripChange) {
+    ctx := context.Background()
+    
+    for _, passengerID := range change.Passengers {
+        publishEvent(h.broker, events.UserTopic(passengerID), events.New(events.TypeTripChanged, trip.ID, trip.DriverID, change))
+        
+        passenger, err := h.authService.GetUserByID(ctx, passengerID)
+        if err != nil {
+            log.Printf("Failed to fetch passenger %d for trip change email: %v", passengerID, err)
+            continue
+        }
+        
+        if err := h.emailService.SendTripChangeEmail(passenger.Email, passenger.Name, trip, change); err != nil {
+            log.Printf("Failed to email trip change to passenger %d: %v", passengerID, err)
+        }
+    }
+}
+
+type RespondToChangeRequest struct {
+    Response string `json:"response" validate:"required,oneof=keep cancel"`
+}
+
+// RespondToChange lets a passenger keep their booking after a material
+// change to the trip, or cancel it with a full refund.
+func (h *TripHandler) RespondToChange(c *gin.Context) {
+    tripID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+        return
+    }
+    
+    changeID, err := strconv.Atoi(c.Param("changeId"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid change ID"})
+        return
+    }
+    
+    var req RespondToChangeRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+        return
+    }
+    
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return
+    }
+    
+    userID, _ := c.Get("userID")
+    
+    status, err := h.tripService.ForRequest(requestInfo(c)).RespondToChange(c.Request.Context(), tripID, changeID, userID.(int), req.Response)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    if req.Response == models.ChangeResponseKeep {
+        // Keeping the booking accepts a raised shared fare, which is
+        // charged now
+        h.settleSharedFare(c.Request.Context(), tripID)
+        c.JSON(http.StatusOK, gin.H{"message": "Booking kept"})
+        return
+    }
+    
+    refunds, err := h.paymentService.RefundBooking(c.Request.Context(), tripID, userID.(int), true)
+    if err != nil {
+        log.Printf("Failed to refund passenger %d of trip %d: %v", userID.(int), tripID, err)
+    }
+    
+    if status == "confirmed" {
+        publishEvent(h.broker, events.TripTopic(tripID), events.New(events.TypePassengerLeft, tripID, userID.(int), nil))
+        h.promoteWaitlist(c.Request.Context(), tripID)
+        h.settleSharedFare(c.Request.Context(), tripID)
+    }
+    
+    c.JSON(http.StatusOK, gin.H{"message": "Booking cancelled", "refunds": refunds})
+}
+
+// GetTripChanges lists the changes made to a trip, to its driver and
+// passengers.
+func (h *TripHandler) GetTripChanges(c *gin.Context) {
+    tripID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+        return
+    }
+    
+    userID, _ := c.Get("userID")
+    
+    ok, err := h.tripService.IsParticipant(c.Request.Context(), tripID, userID.(int))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check trip participation"})
+        return
+    }
+    
+    if !ok {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the driver and confirmed passengers can see trip changes"})
+        return
+    }
+    
+    changes, err := h.tripService.GetTripChanges(c.Request.Context(), tripID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trip changes"})
+        return
+    }
+    
+    c.JSON(http.StatusOK, gin.H{"changes": changes})
+}
+
+// promoteWaitlist offers seats freed on a trip to its waitlist.
+func (h *TripHandler) promoteWaitlist(ctx context.Context, tripID int) {
+    if err := h.waitlist.Promote(ctx, tripID); err != nil {
+        log.Printf("Failed to promote waitlist of trip %d: %v", tripID, err)
+    }
+}
+
+// settleSharedFare charges or refunds the passengers of a shared-cost trip
+// the difference after its per-person price changed.
+func (h *TripHandler) settleSharedFare(ctx context.Context, tripID int) {
+    if err := h.paymentService.SettleSharedFare(ctx, tripID); err != nil {
+        log.Printf("Failed to settle shared fare for trip %d: %v", tripID, err)
+    }
+}
+
+// chargeBooking charges a newly confirmed passenger and lets the trip know
+// they joined. If the payment fails the seat is released again.
+func (h *TripHandler) chargeBooking(c *gin.Context, tripID, passengerID int) error {
+    if _, err := h.paymentService.ChargeBooking(c.Request.Context(), tripID, passengerID); err != nil {
+        // Release the seat again, the passenger couldn't pay for it. This
+        // must happen even if the request timed out.
+        if leaveErr := h.tripService.ForRequest(requestInfo(c)).LeaveTrip(context.WithoutCancel(c.Request.Context()), tripID, passengerID); leaveErr != nil {
+            log.Printf("Failed to release seat on trip %d after payment error: %v", tripID, leaveErr)
+        }
+        h.promoteWaitlist(c.Request.Context(), tripID)
+        return err
+    }
+    
+    // A new passenger lowers everyone's share of a shared-cost trip
+    h.settleSharedFare(c.Request.Context(), tripID)
+    
+    publishEvent(h.broker, events.TripTopic(tripID), events.New(events.TypePassengerJoined, tripID, passengerID, nil))
+    
+    return nil
+}
+
+// bookingParams reads the trip and passenger IDs of a booking request route.
+func bookingParams(c *gin.Context) (int, int, bool) {
+    tripID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+        return 0, 0, false
+    }
+    
+    passengerID, err := strconv.Atoi(c.Param("passengerId"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid passenger ID"})
+        return 0, 0, false
+    }
+    
+    return tripID, passengerID, true
+}
+
+func (h *TripHandler) ApproveBooking(c *gin.Context) {
+    tripID, passengerID, ok := bookingParams(c)
+    if !ok {
+        return
+    }
+    
+    userID, _ := c.Get("userID")
+    
+    if err := h.tripService.ForRequest(requestInfo(c)).ApproveBooking(c.Request.Context(), tripID, userID.(int), passengerID); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    if err := h.chargeBooking(c, tripID, passengerID); err != nil {
+        c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+        return
+    }
+    
+    c.JSON(http.StatusOK, gin.H{"message": "Booking approved"})
+}
+
+func (h *TripHandler) DeclineBooking(c *gin.Context) {
+    tripID, passengerID, ok := bookingParams(c)
+    if !ok {
+        return
+    }
+    
+    userID, _ := c.Get("userID")
+    
+    if err := h.tripService.ForRequest(requestInfo(c)).DeclineBooking(c.Request.Context(), tripID, userID.(int), passengerID); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    publishEvent(h.broker, events.UserTopic(passengerID), events.New(events.TypeBookingDeclined, tripID, userID.(int), nil))
+    
+    c.JSON(http.StatusOK, gin.H{"message": "Booking declined"})
+}
+
+type RemovePassengerRequest struct {
+    Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// RemovePassenger lets the driver take a passenger off their trip. A
+// confirmed passenger is refunded in full, and the passenger is told why.
+func (h *TripHandler) RemovePassenger(c *gin.Context) {
+    tripID, passengerID, ok := bookingParams(c)
+    if !ok {
+        return
+    }
+    
+    var req RemovePassengerRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+        return
+    }
+    
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return
+    }
+    
+    userID, _ := c.Get("userID")
+    
+    status, err := h.tripService.ForRequest(requestInfo(c)).RemovePassenger(c.Request.Context(), tripID, userID.(int), passengerID, req.Reason)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    var refunds []models.PaymentTransaction
+    if status == "confirmed" {
+        refunds, err = h.paymentService.RefundBooking(c.Request.Context(), tripID, passengerID, true)
+        if err != nil {
+            log.Printf("Failed to refund passenger %d of trip %d: %v", passengerID, tripID, err)
+        }
+        
+        publishEvent(h.broker, events.TripTopic(tripID), events.New(events.TypePassengerLeft, tripID, passengerID, nil))
+        h.settleSharedFare(c.Request.Context(), tripID)
+    }
+    
+    publishEvent(h.broker, events.UserTopic(passengerID), events.New(events.TypePassengerRemoved, tripID, userID.(int), map[string]interface{}{
+        "reason": req.Reason,
+    }))
+    
+    h.promoteWaitlist(c.Request.Context(), tripID)
+    
+    c.JSON(http.StatusOK, gin.H{"message": "Passenger removed", "refunds": refunds})
+}
+
+// LeaveTripRequest cancels some seats of a booking instead of all of them.
+// Guests names the guests keeping their seats.
+type LeaveTripRequest struct {
+    Seats  int      `json:"seats" validate:"omitempty,min=1"`
+    Guests []string `json:"guests" validate:"omitempty,dive,required,max=100"`
+}
+
+func (h *TripHandler) LeaveTrip(c *gin.Context) {
+    tripID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+        return
+    }
+    
+    // The body is optional, a bare leave cancels every seat
+    var req LeaveTripRequest
+    if c.Request.ContentLength != 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+            return
+        }
+    }
+    
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return
+    }
+    
+    userID, _ := c.Get("userID")
+    
+    status, remaining, err := h.tripService.ForRequest(requestInfo(c)).CancelSeats(c.Request.Context(), tripID, userID.(int), req.Seats, req.Guests)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    h.promoteWaitlist(c.Request.Context(), tripID)
+    
+    if remaining > 0 {
+        var refunds []models.PaymentTransaction
+        if status == "confirmed" {
+            refunds, err = h.paymentService.RefundSeats(c.Request.Context(), tripID, userID.(int))
+            if err != nil {
+                log.Printf("Failed to refund seats of passenger %d on trip %d: %v", userID.(int), tripID, err)
+            }
+            
+            publishEvent(h.broker, events.TripTopic(tripID), events.New(events.TypeSeatsCancelled, tripID, userID.(int), map[string]interface{}{
+                "seats": req.Seats,
+            }))
+            h.settleSharedFare(c.Request.Context(), tripID)
+        }
+        
+        c.JSON(http.StatusOK, gin.H{"message": "Seats cancelled", "seats": remaining, "refunds": refunds})
+        return
+    }
+    
+    refunds, err := h.paymentService.RefundBooking(c.Request.Context(), tripID, userID.(int), false)
+    if err != nil {
+        log.Printf("Failed to refund passenger %d of trip %d: %v", userID.(int), tripID, err)
+    }
+    
+    publishEvent(h.broker, events.TripTopic(tripID), events.New(events.TypePassengerLeft, tripID, userID.(int), nil))
+    h.settleSharedFare(c.Request.Context(), tripID)
+    
+    c.JSON(http.StatusOK, gin.H{"message": "Successfully left trip", "refunds": refunds})
+}
+
+func (h *TripHandler) StartTrip(c *gin.Context) {
+    tripID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+        return
+    }
+    
+    userID, _ := c.Get("userID")
+    
+    if err := h.tripService.ForRequest(requestInfo(c)).StartTrip(c.Request.Context(), tripID, userID.(int)); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    publishEvent(h.broker, events.TripTopic(tripID), events.New(events.TypeTripStarted, tripID, userID.(int), nil))
+    
+    c.JSON(http.StatusOK, gin.H{"message": "Trip started"})
+}
+
+func (h *TripHandler) CompleteTrip(c *gin.Context) {
+    tripID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+        return
+    }
+    
+    userID, _ := c.Get("userID")
+    
+    if err := h.tripService.ForRequest(requestInfo(c)).CompleteTrip(c.Request.Context(), tripID, userID.(int)); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    
+    publishEvent(h.broker, events.TripTopic(tripID), events.New(events.TypeTripCompleted, tripID, userID.(int), nil))
+    
+    c.JSON(http.StatusOK, gin.H{"message": "Trip completed"})
 }
\ No newline at end of file
@@ -4,24 +4,26 @@ import (
     "net/http"
     "rideshare-backend/internal/services"
     "rideshare-backend/internal/models"
+    "rideshare-backend/internal/utils"
     
     "github.com/gin-gonic/gin"
     "github.com/go-playground/validator/v10"
-    "database/sql"
 )
 
 type UserHandler struct {
-    db          *sql.DB
-    authService *services.AuthService
-    validator   *validator.Validate
+    authService       *services.AuthService
+    preferenceService *services.PreferenceService
+    tripService       *services.TripService
+    validator         *validator.Validate
     
 }
 
-func NewUserHandler(db *sql.DB) *UserHandler {
+func NewUserHandler(authService *services.AuthService, preferenceService *services.PreferenceService, tripService *services.TripService) *UserHandler {
     return &UserHandler{
-        db:          db,
-        authService: services.NewAuthService(db, nil),
-        validator:   validator.New(),
+        authService:       authService,
+        preferenceService: preferenceService,
+        tripService:       tripService,
+        validator:         validator.New(),
     }
 }
 
@@ -29,6 +31,19 @@ type UpdateProfileRequest struct {
     Name         string  `json:"name" validate:"required,min=2,max=100"`
     Phone        string  `json:"phone" validate:"required"`
     ProfileImage *string `json:"profileImage"`
+    Gender       *string `json:"gender" validate:"omitempty,oneof=female male other"`
+    Timezone     string  `json:"timezone"`
+}
+
+// PreferencesRequest holds a passenger's default search preferences. Fields
+// left out mean no preference.
+type PreferencesRequest struct {
+    PetsAllowed    *bool  `json:"petsAllowed"`
+    SmokingAllowed *bool  `json:"smokingAllowed"`
+    LuggageSize    string `json:"luggageSize" validate:"omitempty,oneof=none small medium large"`
+    Music          *bool  `json:"music"`
+    WomenOnly      *bool  `json:"womenOnly"`
+    InstantBooking *bool  `json:"instantBooking"`
 }
 
 func (h *UserHandler) GetProfile(c *gin.Context) {
@@ -38,7 +53,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
         return
     }
     
-    user, err := h.authService.GetUserByID(userID.(int))
+    user, err := h.authService.GetUserByID(c.Request.Context(), userID.(int))
     if err != nil {
         c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
         return
@@ -68,20 +83,38 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
         return
     }
     
+    current, err := h.authService.GetUserByID(c.Request.Context(), userID.(int))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+        return
+    }
+    
+    // Keep the current timezone unless a new one is given
+    timezone := current.Timezone
+    if req.Timezone != "" {
+        if !utils.ValidTimezone(req.Timezone) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone"})
+            return
+        }
+        timezone = req.Timezone
+    }
+    
     user := &models.User{
         ID:           userID.(int),
         Name:         req.Name,
         Phone:        req.Phone,
         ProfileImage: req.ProfileImage,
+        Gender:       req.Gender,
+        Timezone:     timezone,
     }
     
-    if err := h.authService.UpdateUser(user); err != nil {
+    if err := h.authService.ForRequest(requestInfo(c)).UpdateUser(c.Request.Context(), user); err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
         return
     }
     
     // Get updated user data
-    updatedUser, err := h.authService.GetUserByID(userID.(int))
+    updatedUser, err := h.authService.GetUserByID(c.Request.Context(), userID.(int))
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated profile"})
         return
@@ -93,34 +126,65 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
     c.JSON(http.StatusOK, updatedUser)
 }
 
-func (h *UserHandler) GetUserStats(c *gin.Context) {
+func (h *UserHandler) GetPreferences(c *gin.Context) {
     userID, exists := c.Get("userID")
     if !exists {
         c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
         return
     }
     
-    // Get user statistics
-    var stats models.UserStats
-    query := `
-        SELECT 
-            COUNT(*) as total_trips,
-            COUNT(CASE WHEN status = 'completed' THEN 1 END) as completed_trips,
-            COUNT(CASE WHEN status = 'cancelled' THEN 1 END) as cancelled_trips,
-            COALESCE(AVG(CASE WHEN status = 'completed' THEN 5.0 END), 0) as rating,
-            COUNT(CASE WHEN status = 'completed' THEN 1 END) as review_count
-        FROM trips 
-        WHERE driver_id = $1
-    `
-    
-    err := h.db.QueryRow(query, userID.(int)).Scan(
-        &stats.TotalTrips,
-        &stats.CompletedTrips,
-        &stats.CancelledTrips,
-        &stats.Rating,
-        &stats.ReviewCount,
-    )
+    prefs, err := h.preferenceService.GetUserPreferences(c.Request.Context(), userID.(int))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch preferences"})
+        return
+    }
+    
+    c.JSON(http.StatusOK, prefs)
+}
+
+func (h *UserHandler) UpdatePreferences(c *gin.Context) {
+    userID, exists := c.Get("userID")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+        return
+    }
+    
+    var req PreferencesRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+        return
+    }
+    
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed"})
+        return
+    }
+    
+    prefs := models.PreferenceFilter{
+        PetsAllowed:    req.PetsAllowed,
+        SmokingAllowed: req.SmokingAllowed,
+        LuggageSize:    req.LuggageSize,
+        Music:          req.Music,
+        WomenOnly:      req.WomenOnly,
+        InstantBooking: req.InstantBooking,
+    }
+    
+    if err := h.preferenceService.SaveUserPreferences(c.Request.Context(), userID.(int), prefs); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preferences"})
+        return
+    }
+    
+    c.JSON(http.StatusOK, prefs)
+}
+
+func (h *UserHandler) GetUserStats(c *gin.Context) {
+    userID, exists := c.Get("userID")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+        return
+    }
     
+    stats, err := h.tripService.GetDriverStats(c.Request.Context(), userID.(int))
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user stats"})
         return
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+    "net/http"
+    "strconv"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/services"
+    "rideshare-backend/internal/utils"
+
+    "github.com/gin-gonic/gin"
+    "github.com/go-playground/validator/v10"
+)
+
+type VehicleHandler struct {
+    vehicleService *services.VehicleService
+    validator      *validator.Validate
+}
+
+func NewVehicleHandler(vehicleService *services.VehicleService) *VehicleHandler {
+    return &VehicleHandler{
+        vehicleService: vehicleService,
+        validator:      validator.New(),
+    }
+}
+
+// VehicleRequest describes a car. Seats counts the driver's seat too.
+type VehicleRequest struct {
+    Make           string   `json:"make" validate:"required,max=50"`
+    Model          string   `json:"model" validate:"required,max=50"`
+    Colour         string   `json:"colour" validate:"required,max=30"`
+    Plate          string   `json:"plate" validate:"required,max=20"`
+    Seats          int      `json:"seats" validate:"required,min=2,max=9"`
+    Amenities      []string `json:"amenities" validate:"max=20,dive,required,max=50"`
+    LitresPer100Km *float64 `json:"litresPer100Km" validate:"omitempty,gt=0,max=50"`
+}
+
+func (r *VehicleRequest) vehicle(ownerID int) *models.Vehicle {
+    amenities := r.Amenities
+    if amenities == nil {
+        amenities = []string{}
+    }
+
+    return &models.Vehicle{
+        OwnerID:        ownerID,
+        Make:           r.Make,
+        Model:          r.Model,
+        Colour:         r.Colour,
+        Plate:          r.Plate,
+        Seats:          r.Seats,
+        Amenities:      amenities,
+        LitresPer100Km: r.LitresPer100Km,
+    }
+}
+
+func (h *VehicleHandler) bindVehicle(c *gin.Context) (*VehicleRequest, bool) {
+    var req VehicleRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+        return nil, false
+    }
+
+    if err := h.validator.Struct(req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": utils.FormatValidationErrors(err)})
+        return nil, false
+    }
+
+    return &req, true
+}
+
+func (h *VehicleHandler) GetVehicles(c *gin.Context) {
+    userID, _ := c.Get("userID")
+
+    vehicles, err := h.vehicleService.GetUserVehicles(c.Request.Context(), userID.(int))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vehicles"})
+        return
+    }
+
+    c.JSON(http.StatusOK, vehicles)
+}
+
+func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
+    req, ok := h.bindVehicle(c)
+    if !ok {
+        return
+    }
+
+    userID, _ := c.Get("userID")
+
+    vehicle := req.vehicle(userID.(int))
+    if err := h.vehicleService.CreateVehicle(c.Request.Context(), vehicle); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create vehicle"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, vehicle)
+}
+
+func (h *VehicleHandler) GetVehicle(c *gin.Context) {
+    vehicleID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID"})
+        return
+    }
+
+    userID, _ := c.Get("userID")
+
+    vehicle, err := h.vehicleService.GetVehicle(c.Request.Context(), vehicleID, userID.(int))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, vehicle)
+}
+
+func (h *VehicleHandler) UpdateVehicle(c *gin.Context) {
+    vehicleID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID"})
+        return
+    }
+
+    req, ok := h.bindVehicle(c)
+    if !ok {
+        return
+    }
+
+    userID, _ := c.Get("userID")
+
+    vehicle := req.vehicle(userID.(int))
+    vehicle.ID = vehicleID
+
+    if err := h.vehicleService.UpdateVehicle(c.Request.Context(), vehicle); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    c.JSON(http.StatusOK, vehicle)
+}
+
+func (h *VehicleHandler) DeleteVehicle(c *gin.Context) {
+    vehicleID, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID"})
+        return
+    }
+
+    userID, _ := c.Get("userID")
+
+    if err := h.vehicleService.DeleteVehicle(c.Request.Context(), vehicleID, userID.(int)); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Vehicle deleted successfully"})
+}
@@ -0,0 +1,423 @@
+// Package migrate applies the numbered SQL migrations of the database schema
+// and keeps track of which ones have run.
+package migrate
+
+import (
+    "context"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/hex"
+    "fmt"
+    "io/fs"
+    "log"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// lockKey is the Postgres advisory lock held while migrating, so two servers
+// starting at once don't both apply the same migration.
+const lockKey = 7283140516
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one schema change, read from <version>_<name>.up.sql and the
+// matching .down.sql that reverts it.
+type Migration struct {
+    Version  int
+    Name     string
+    Up       string
+    Down     string
+    Checksum string
+}
+
+// Status is the state of a migration in the database. A migration is
+// Modified if its file changed after it was applied, and Missing if it was
+// applied but has no file any more.
+type Status struct {
+    Version   int
+    Name      string
+    AppliedAt *time.Time
+    Modified  bool
+    Missing   bool
+}
+
+type applied struct {
+    name      string
+    checksum  string
+    appliedAt time.Time
+}
+
+// Load reads the migrations in fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+    entries, err := fs.ReadDir(fsys, ".")
+    if err != nil {
+        return nil, fmt.Errorf("failed to read migration directory: %w", err)
+    }
+    
+    byVersion := map[int]*Migration{}
+    for _, entry := range entries {
+        match := fileName.FindStringSubmatch(entry.Name())
+        if entry.IsDir() || match == nil {
+            continue
+        }
+        
+        version, _ := strconv.Atoi(match[1])
+        migration, ok := byVersion[version]
+        if !ok {
+            migration = &Migration{Version: version, Name: match[2]}
+            byVersion[version] = migration
+        } else if migration.Name != match[2] {
+            return nil, fmt.Errorf("migrations %d_%s and %d_%s share a version", version, migration.Name, version, match[2])
+        }
+        
+        content, err := fs.ReadFile(fsys, entry.Name())
+        if err != nil {
+            return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+        }
+        
+        if match[3] == "up" {
+            migration.Up = string(content)
+            sum := sha256.Sum256(content)
+            migration.Checksum = hex.EncodeToString(sum[:])
+        } else {
+            migration.Down = string(content)
+        }
+    }
+    
+    migrations := make([]Migration, 0, len(byVersion))
+    for _, migration := range byVersion {
+        if migration.Checksum == "" {
+            return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+        }
+        migrations = append(migrations, *migration)
+    }
+    
+    sort.Slice(migrations, func(i, j int) bool {
+        return migrations[i].Version < migrations[j].Version
+    })
+    
+    return migrations, nil
+}
+
+// Migrator runs migrations against a database, recording each one applied
+// in the schema_migrations table.
+type Migrator struct {
+    db         *sql.DB
+    migrations []Migration
+}
+
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+    migrations, err := Load(fsys)
+    if err != nil {
+        return nil, err
+    }
+    
+    return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies every migration not applied yet, in order, each in its own
+// transaction. It refuses to run if an applied migration has since been
+// edited. It returns how many migrations were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+    count := 0
+    err := m.withLock(ctx, func(conn *sql.Conn) error {
+        done, err := loadApplied(ctx, conn)
+        if err != nil {
+            return err
+        }
+        
+        for _, migration := range m.migrations {
+            if a, ok := done[migration.Version]; ok && a.checksum != migration.Checksum {
+                return fmt.Errorf("migration %d_%s was changed after it was applied", migration.Version, migration.Name)
+            }
+        }
+        
+        for _, migration := range m.migrations {
+            if _, ok := done[migration.Version]; ok {
+                continue
+            }
+            
+            err := inTx(ctx, conn, func(tx *sql.Tx) error {
+                if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+                    return err
+                }
+                _, err := tx.ExecContext(ctx,
+                    "INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, NOW())",
+                    migration.Version, migration.Name, migration.Checksum,
+                )
+                return err
+            })
+            if err != nil {
+                return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+            }
+            
+            log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+            count++
+        }
+        
+        return nil
+    })
+    
+    return count, err
+}
+
+// Down reverts the last steps migrations applied, most recent first, using
+// their down files. It returns how many were reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+    files := map[int]Migration{}
+    for _, migration := range m.migrations {
+        files[migration.Version] = migration
+    }
+    
+    count := 0
+    err := m.withLock(ctx, func(conn *sql.Conn) error {
+        done, err := loadApplied(ctx, conn)
+        if err != nil {
+            return err
+        }
+        
+        versions := make([]int, 0, len(done))
+        for version := range done {
+            versions = append(versions, version)
+        }
+        sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+        
+        for _, version := range versions {
+            if count == steps {
+                break
+            }
+            
+            migration, ok := files[version]
+            if !ok || strings.TrimSpace(migration.Down) == "" {
+                return fmt.Errorf("migration %d_%s has no down file to revert it", version, done[version].name)
+            }
+            
+            err := inTx(ctx, conn, func(tx *sql.Tx) error {
+                if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+                    return err
+                }
+                _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", version)
+                return err
+            })
+            if err != nil {
+                return fmt.Errorf("failed to revert migration %d_%s: %w", version, migration.Name, err)
+            }
+            
+            log.Printf("Reverted migration %d_%s", version, migration.Name)
+            count++
+        }
+        
+        return nil
+    })
+    
+    return count, err
+}
+
+// Force records every migration up to version as applied without running
+// it, and any later one as not applied. It baselines a database whose schema
+// was migrated by hand.
+func (m *Migrator) Force(ctx context.Context, version int) error {
+    return m.withLock(ctx, func(conn *sql.Conn) error {
+        return inTx(ctx, conn, func(tx *sql.Tx) error {
+            if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version > $1", version); err != nil {
+                return fmt.Errorf("failed to clear migrations: %w", err)
+            }
+            
+            for _, migration := range m.migrations {
+                if migration.Version > version {
+                    break
+                }
+                
+                _, err := tx.ExecContext(ctx, `
+                    INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, NOW())
+                    ON CONFLICT (version) DO UPDATE SET name = EXCLUDED.name, checksum = EXCLUDED.checksum
+                `, migration.Version, migration.Name, migration.Checksum)
+                if err != nil {
+                    return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
+                }
+            }
+            
+            return nil
+        })
+    })
+}
+
+// Status lists every migration, known from its files or applied to the
+// database, in version order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+    var exists bool
+    if err := m.db.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+        return nil, fmt.Errorf("failed to check migrations table: %w", err)
+    }
+    
+    done := map[int]applied{}
+    if exists {
+        var err error
+        if done, err = loadApplied(ctx, m.db); err != nil {
+            return nil, err
+        }
+    }
+    
+    var statuses []Status
+    for _, migration := range m.migrations {
+        status := Status{Version: migration.Version, Name: migration.Name}
+        if a, ok := done[migration.Version]; ok {
+            appliedAt := a.appliedAt
+            status.AppliedAt = &appliedAt
+            status.Modified = a.checksum != migration.Checksum
+            delete(done, migration.Version)
+        }
+        statuses = append(statuses, status)
+    }
+    
+    for version, a := range done {
+        appliedAt := a.appliedAt
+        statuses = append(statuses, Status{Version: version, Name: a.name, AppliedAt: &appliedAt, Missing: true})
+    }
+    
+    sort.Slice(statuses, func(i, j int) bool {
+        return statuses[i].Version < statuses[j].Version
+    })
+    
+    return statuses, nil
+}
+
+// Check fails unless every migration is applied, unchanged, so the schema
+// is what this build expects.
+func (m *Migrator) Check(ctx context.Context) error {
+    statuses, err := m.Status(ctx)
+    if err != nil {
+        return err
+    }
+    
+    current, expected, pending := 0, 0, 0
+    for _, status := range statuses {
+        if status.Modified {
+            return fmt.Errorf("migration %d_%s was changed after it was applied", status.Version, status.Name)
+        }
+        if status.AppliedAt != nil && status.Version > current {
+            current = status.Version
+        }
+        if !status.Missing {
+            expected = status.Version
+            if status.AppliedAt == nil {
+                pending++
+            }
+        }
+    }
+    
+    if pending > 0 {
+        return fmt.Errorf("database schema is at version %d but this build expects %d, with %d migrations pending", current, expected, pending)
+    }
+    
+    return nil
+}
+
+// Create writes empty up and down files for a new migration to dir,
+// numbered after the latest one, and returns their paths.
+func Create(dir, name string) (string, string, error) {
+    if !regexp.MustCompile(`^\w+$`).MatchString(name) {
+        return "", "", fmt.Errorf("migration name may only use letters, digits and underscores")
+    }
+    
+    migrations, err := Load(os.DirFS(dir))
+    if err != nil {
+        return "", "", err
+    }
+    
+    version := 1
+    if len(migrations) > 0 {
+        version = migrations[len(migrations)-1].Version + 1
+    }
+    
+    title := strings.ReplaceAll(name, "_", " ")
+    base := filepath.Join(dir, fmt.Sprintf("%d_%s", version, name))
+    files := []struct {
+        path    string
+        content string
+    }{
+        {base + ".up.sql", "-- Migration: " + title + "\n"},
+        {base + ".down.sql", "-- Drop " + title + "\n"},
+    }
+    
+    for _, file := range files {
+        if err := os.WriteFile(file.path, []byte(file.content), 0644); err != nil {
+            return "", "", fmt.Errorf("failed to write migration: %w", err)
+        }
+    }
+    
+    return files[0].path, files[1].path, nil
+}
+
+// withLock runs fn on a single connection holding the migration lock, with
+// the migrations table created.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+    conn, err := m.db.Conn(ctx)
+    if err != nil {
+        return fmt.Errorf("failed to get connection: %w", err)
+    }
+    defer conn.Close()
+    
+    // Session-level lock, so it has to be taken and released on this connection
+    if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+        return fmt.Errorf("failed to take migration lock: %w", err)
+    }
+    defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+    
+    _, err = conn.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version INTEGER PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            checksum CHAR(64) NOT NULL,
+            applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )
+    `)
+    if err != nil {
+        return fmt.Errorf("failed to create migrations table: %w", err)
+    }
+    
+    return fn(conn)
+}
+
+type querier interface {
+    QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func loadApplied(ctx context.Context, db querier) (map[int]applied, error) {
+    rows, err := db.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+    if err != nil {
+        return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+    }
+    defer rows.Close()
+    
+    done := map[int]applied{}
+    for rows.Next() {
+        var version int
+        var a applied
+        if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+            return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+        }
+        done[version] = a
+    }
+    
+    return done, rows.Err()
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+    tx, err := conn.BeginTx(ctx, nil)
+    if err != nil {
+        return fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback()
+    
+    if err := fn(tx); err != nil {
+        return err
+    }
+    
+    return tx.Commit()
+}
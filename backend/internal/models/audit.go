@@ -0,0 +1,61 @@
+package models
+
+import (
+    "time"
+)
+
+// Entities recorded in the audit log
+const (
+    AuditEntityTrip = "trip"
+    AuditEntityUser = "user"
+)
+
+// Audited actions. Booking actions are recorded on the trip.
+const (
+    AuditCreate   = "create"
+    AuditUpdate   = "update"
+    AuditCancel   = "cancel"
+    AuditStart    = "start"
+    AuditComplete = "complete"
+    AuditJoin     = "join"
+    AuditApprove  = "approve"
+    AuditDecline  = "decline"
+    AuditLeave    = "leave"
+    AuditRemove   = "remove"
+)
+
+// RequestInfo identifies the API request behind a change, for the audit log.
+type RequestInfo struct {
+    RequestID string
+    ClientIP  string
+}
+
+// AuditEvent is an entry of the append-only audit log: who did what to which
+// trip or user, with the fields it changed.
+type AuditEvent struct {
+    ID         int64                  `json:"id" db:"id"`
+    EntityType string                 `json:"entityType" db:"entity_type"`
+    EntityID   int                    `json:"entityId" db:"entity_id"`
+    Action     string                 `json:"action" db:"action"`
+    ActorID    *int                   `json:"actorId,omitempty" db:"actor_id"`
+    Changes    map[string]FieldChange `json:"changes" db:"changes"`
+    RequestID  *string                `json:"requestId,omitempty" db:"request_id"`
+    ClientIP   *string                `json:"clientIp,omitempty" db:"client_ip"`
+    CreatedAt  time.Time              `json:"createdAt" db:"created_at"`
+}
+
+// AuditFilter narrows the audit log to one entity, or to the events of one
+// user: those they made and those made to their account.
+type AuditFilter struct {
+    EntityType string
+    EntityID   int
+    UserID     int
+    Cursor     string
+    Limit      int
+}
+
+type AuditPage struct {
+    Events     []AuditEvent `json:"events"`
+    NextCursor *string      `json:"nextCursor,omitempty"`
+    HasMore    bool         `json:"hasMore"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+    "rideshare-backend/internal/money"
+)
+
+type FareSuggestion struct {
+    DistanceKm     float64     `json:"distanceKm"`
+    FuelLitres     float64     `json:"fuelLitres"`
+    LitresPer100Km float64     `json:"litresPer100Km"`
+    FuelPrice      money.Rate  `json:"fuelPrice"`
+    TotalCost      money.Money `json:"totalCost"`
+    Seats          int         `json:"seats"`
+    PerSeat        money.Money `json:"perSeat"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+    "time"
+)
+
+type TripLocation struct {
+    ID         int64     `json:"id" db:"id"`
+    TripID     int       `json:"tripId" db:"trip_id"`
+    Latitude   float64   `json:"latitude" db:"latitude"`
+    Longitude  float64   `json:"longitude" db:"longitude"`
+    Heading    *float64  `json:"heading,omitempty" db:"heading"`
+    SpeedKmh   *float64  `json:"speedKmh,omitempty" db:"speed_kmh"`
+    RecordedAt time.Time `json:"recordedAt" db:"recorded_at"`
+}
+
+type PickupPoint struct {
+    PassengerID int     `json:"passengerId" db:"passenger_id"`
+    Latitude    float64 `json:"latitude" db:"pickup_latitude"`
+    Longitude   float64 `json:"longitude" db:"pickup_longitude"`
+}
+
+type PickupETA struct {
+    PassengerID int       `json:"passengerId"`
+    DistanceKm  float64   `json:"distanceKm"`
+    ETAMinutes  float64   `json:"etaMinutes"`
+    ArrivesAt   time.Time `json:"arrivesAt"`
+}
+
+// LiveLocation is what trip participants see while following the driver.
+type LiveLocation struct {
+    Position *TripLocation `json:"position"`
+    ETAs     []PickupETA   `json:"etas"`
+}
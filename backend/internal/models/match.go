@@ -1,15 +1,26 @@
 package models
 
 import (
+    "rideshare-backend/internal/money"
     "time"
 )
 
+// TripPassenger is a booking on a trip. In a trip's manifest only the
+// driver is shown the booking itself; others just see the passenger.
 type TripPassenger struct {
-    ID          int       `json:"id" db:"id"`
-    TripID      int       `json:"tripId" db:"trip_id"`
-    PassengerID int       `json:"passengerId" db:"passenger_id"`
-    Status      string    `json:"status" db:"status"`
-    JoinedAt    time.Time `json:"joinedAt" db:"joined_at"`
+    ID          int        `json:"id,omitempty" db:"id"`
+    TripID      int        `json:"tripId,omitempty" db:"trip_id"`
+    PassengerID int        `json:"passengerId" db:"passenger_id"`
+    Status      string     `json:"status,omitempty" db:"status"`
+    Seats       int        `json:"seats,omitempty" db:"seats"`
+    Guests      []string   `json:"guests,omitempty" db:"guest_names"`
+    JoinedAt    *time.Time `json:"joinedAt,omitempty" db:"joined_at"`
+    RemovedAt   *time.Time `json:"-" db:"removed_at"`
+    
+    // Fare is what the passenger has paid for FareSeats seats, net of
+    // refunds. Fees kept when seats were given up don't count.
+    Fare      money.Amount `json:"-" db:"fare_minor"`
+    FareSeats int          `json:"-" db:"fare_seats"`
     
     // Relationships
     Trip      *Trip `json:"trip,omitempty"`
@@ -17,8 +28,10 @@ type TripPassenger struct {
 }
 
 type TripMatch struct {
-    TripID      int     `json:"tripId"`
-    PassengerID int     `json:"passengerId"`
-    Similarity  float64 `json:"similarity"`
-    Distance    float64 `json:"distance"`
+    TripID          int     `json:"tripId"`
+    PassengerID     int     `json:"passengerId"`
+    Similarity      float64 `json:"similarity"`
+    Distance        float64 `json:"distance"`
+    PreferenceScore float64 `json:"preferenceScore"`
+    Score           float64 `json:"score"`
 }
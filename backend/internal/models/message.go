@@ -0,0 +1,43 @@
+package models
+
+import (
+    "time"
+)
+
+type TripMessage struct {
+    ID        int       `json:"id" db:"id"`
+    TripID    int       `json:"tripId" db:"trip_id"`
+    SenderID  int       `json:"senderId" db:"sender_id"`
+    Body      string    `json:"body" db:"body"`
+    CreatedAt time.Time `json:"createdAt" db:"created_at"`
+
+    // Relationships
+    Sender *User `json:"sender,omitempty"`
+    ReadBy []int `json:"readBy"`
+}
+
+type MessageReceipt struct {
+    TripID            int        `json:"tripId" db:"trip_id"`
+    UserID            int        `json:"userId" db:"user_id"`
+    LastReadMessageID int        `json:"lastReadMessageId" db:"last_read_message_id"`
+    ReadAt            *time.Time `json:"readAt,omitempty" db:"read_at"`
+}
+
+type MessagePage struct {
+    Messages   []TripMessage `json:"messages"`
+    NextBefore *int          `json:"nextBefore,omitempty"`
+}
+
+// UnreadDigest summarises the messages a participant has neither read nor
+// been emailed about yet.
+type UnreadDigest struct {
+    TripID          int
+    UserID          int
+    Email           string
+    Name            string
+    FromLocation    string
+    ToLocation      string
+    DepartureTime   time.Time // in the trip's timezone
+    UnreadCount     int
+    LatestMessageID int
+}
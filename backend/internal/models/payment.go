@@ -0,0 +1,77 @@
+package models
+
+import (
+    "rideshare-backend/internal/money"
+    "time"
+)
+
+type PaymentTransaction struct {
+    ID                int          `json:"id" db:"id"`
+    BookingID         *int         `json:"bookingId,omitempty" db:"booking_id"`
+    TripID            *int         `json:"tripId,omitempty" db:"trip_id"`
+    ParentID          *int         `json:"parentId,omitempty" db:"parent_id"`
+    Kind              string       `json:"kind" db:"kind"`
+    Amount            money.Amount `json:"amount" db:"amount"`
+    Currency          string       `json:"currency" db:"currency"`
+    ProviderReference *string      `json:"providerReference,omitempty" db:"provider_reference"`
+    Description       *string      `json:"description,omitempty" db:"description"`
+    CreatedAt         time.Time    `json:"createdAt" db:"created_at"`
+    
+    // Relationships
+    Entries []LedgerEntry `json:"entries,omitempty"`
+}
+
+type LedgerEntry struct {
+    ID            int          `json:"id" db:"id"`
+    TransactionID int          `json:"transactionId" db:"transaction_id"`
+    Account       string       `json:"account" db:"account"`
+    UserID        *int         `json:"userId,omitempty" db:"user_id"`
+    EntryType     string       `json:"entryType" db:"entry_type"`
+    Amount        money.Amount `json:"amount" db:"amount"`
+    Currency      string       `json:"currency" db:"currency"`
+    CreatedAt     time.Time    `json:"createdAt" db:"created_at"`
+    
+    // Relationships
+    TripID *int `json:"tripId,omitempty"`
+}
+
+type AccountBalance struct {
+    Account   string       `json:"account"`
+    Currency  string       `json:"currency"`
+    Balance   money.Amount `json:"balance"`
+    Formatted string       `json:"formatted"`
+}
+
+// BookingCharge is a charge for a booking, with its ledger entries, and
+// what has been refunded or requested to be refunded against it so far.
+type BookingCharge struct {
+    PaymentTransaction
+    Fee      money.Amount
+    Refunded money.Amount
+}
+
+// Remaining is what is left of the charge to refund.
+func (c *BookingCharge) Remaining() money.Amount {
+    return c.Amount - c.Refunded
+}
+
+// Refund request statuses
+const (
+    RefundPending   = "pending"
+    RefundCompleted = "completed"
+)
+
+// RefundRequest is a refund owed against a charge. It is pending until the
+// payment provider has paid it and it is recorded in the ledger.
+type RefundRequest struct {
+    ID            int          `json:"id" db:"id"`
+    ChargeID      int          `json:"chargeId" db:"charge_id"`
+    Amount        money.Amount `json:"amount" db:"amount"`
+    Description   *string      `json:"description,omitempty" db:"description"`
+    Status        string       `json:"status" db:"status"`
+    Attempts      int          `json:"attempts" db:"attempts"`
+    LastError     *string      `json:"lastError,omitempty" db:"last_error"`
+    TransactionID *int         `json:"transactionId,omitempty" db:"transaction_id"`
+    CreatedAt     time.Time    `json:"createdAt" db:"created_at"`
+    UpdatedAt     time.Time    `json:"updatedAt" db:"updated_at"`
+}
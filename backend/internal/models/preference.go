@@ -0,0 +1,66 @@
+package models
+
+// Luggage sizes, smallest first
+const (
+    LuggageNone   = "none"
+    LuggageSmall  = "small"
+    LuggageMedium = "medium"
+    LuggageLarge  = "large"
+)
+
+// LuggageSizes lists the luggage sizes in increasing order.
+var LuggageSizes = []string{LuggageNone, LuggageSmall, LuggageMedium, LuggageLarge}
+
+// LuggageRank orders luggage sizes, -1 for an unknown one.
+func LuggageRank(size string) int {
+    for i, s := range LuggageSizes {
+        if s == size {
+            return i
+        }
+    }
+    return -1
+}
+
+// TripPreferences are the rules of a trip as set by its driver.
+type TripPreferences struct {
+    PetsAllowed    bool   `json:"petsAllowed" db:"pets_allowed"`
+    SmokingAllowed bool   `json:"smokingAllowed" db:"smoking_allowed"`
+    LuggageSize    string `json:"luggageSize" db:"luggage_size"`
+    Music          bool   `json:"music" db:"music"`
+    WomenOnly      bool   `json:"womenOnly" db:"women_only"`
+    InstantBooking bool   `json:"instantBooking" db:"instant_booking"`
+}
+
+// PreferenceFilter is what a passenger wants from a trip. Nil fields and an
+// empty LuggageSize mean no preference; LuggageSize is the minimum needed.
+type PreferenceFilter struct {
+    PetsAllowed    *bool  `json:"petsAllowed,omitempty" db:"pets_allowed"`
+    SmokingAllowed *bool  `json:"smokingAllowed,omitempty" db:"smoking_allowed"`
+    LuggageSize    string `json:"luggageSize,omitempty" db:"luggage_size"`
+    Music          *bool  `json:"music,omitempty" db:"music"`
+    WomenOnly      *bool  `json:"womenOnly,omitempty" db:"women_only"`
+    InstantBooking *bool  `json:"instantBooking,omitempty" db:"instant_booking"`
+}
+
+// Or fills the preferences f leaves open from defaults.
+func (f PreferenceFilter) Or(defaults PreferenceFilter) PreferenceFilter {
+    if f.PetsAllowed == nil {
+        f.PetsAllowed = defaults.PetsAllowed
+    }
+    if f.SmokingAllowed == nil {
+        f.SmokingAllowed = defaults.SmokingAllowed
+    }
+    if f.LuggageSize == "" {
+        f.LuggageSize = defaults.LuggageSize
+    }
+    if f.Music == nil {
+        f.Music = defaults.Music
+    }
+    if f.WomenOnly == nil {
+        f.WomenOnly = defaults.WomenOnly
+    }
+    if f.InstantBooking == nil {
+        f.InstantBooking = defaults.InstantBooking
+    }
+    return f
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+    "time"
+)
+
+// SeatHold keeps seats on a trip for a passenger while they book, until
+// ExpiresAt.
+type SeatHold struct {
+    ID          int       `json:"id" db:"id"`
+    TripID      int       `json:"tripId" db:"trip_id"`
+    PassengerID int       `json:"passengerId" db:"passenger_id"`
+    Seats       int       `json:"seats" db:"seats"`
+    ExpiresAt   time.Time `json:"expiresAt" db:"expires_at"`
+    CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+}
@@ -1,34 +1,97 @@
 package models
 
 import (
+    "rideshare-backend/internal/money"
     "time"
 )
 
 type Trip struct {
-    ID                int       `json:"id" db:"id"`
-    DriverID          int       `json:"driverId" db:"driver_id"`
-    FromLocation      string    `json:"from" db:"from_location"`
-    ToLocation        string    `json:"to" db:"to_location"`
-    DepartureTime     time.Time `json:"departureTime" db:"departure_time"`
-    MaxPassengers     int       `json:"maxPassengers" db:"max_passengers"`
-    CurrentPassengers int       `json:"currentPassengers" db:"current_passengers"`
-    PricePerPerson    float64   `json:"pricePerPerson" db:"price_per_person"`
-    Description       *string   `json:"description,omitempty" db:"description"`
-    Status            string    `json:"status" db:"status"`
-    CreatedAt         time.Time `json:"createdAt" db:"created_at"`
-    UpdatedAt         time.Time `json:"updatedAt" db:"updated_at"`
+    ID                int             `json:"id" db:"id"`
+    DriverID          int             `json:"driverId" db:"driver_id"`
+    FromLocation      string          `json:"from" db:"from_location"`
+    ToLocation        string          `json:"to" db:"to_location"`
+    DepartureTime     time.Time       `json:"departureTime" db:"departure_time"`
+    Timezone          string          `json:"timezone" db:"timezone"`
+    MaxPassengers     int             `json:"maxPassengers" db:"max_passengers"`
+    CurrentPassengers int             `json:"currentPassengers" db:"current_passengers"`
+    HeldSeats         int             `json:"heldSeats" db:"held_seats"`
+    PricePerPerson    money.Money     `json:"pricePerPerson" db:"price_per_person_minor"`
+    FareMode          string          `json:"fareMode" db:"fare_mode"`
+    TotalCost         *money.Money    `json:"totalCost,omitempty" db:"total_cost_minor"`
+    VehicleID         *int            `json:"vehicleId,omitempty" db:"vehicle_id"`
+    Preferences       TripPreferences `json:"preferences"`
+    Description       *string         `json:"description,omitempty" db:"description"`
+    Status            string          `json:"status" db:"status"`
+    CreatedAt         time.Time       `json:"createdAt" db:"created_at"`
+    UpdatedAt         time.Time       `json:"updatedAt" db:"updated_at"`
     
     // Relationships
-    Driver     *User  `json:"driver,omitempty"`
-    Passengers []User `json:"passengers,omitempty"`
-    UserRole  string `json:"userRole,omitempty"`
+    Driver        *User           `json:"driver,omitempty"`
+    Vehicle       *Vehicle        `json:"vehicle,omitempty"`
+    Passengers    []TripPassenger `json:"passengers,omitempty"`
+    UserRole      string          `json:"userRole,omitempty"`
+    BookingStatus *string         `json:"bookingStatus,omitempty"`
 }
 
+const (
+    FareModeFixed  = "fixed"
+    FareModeShared = "shared"
+)
+
+// Roles a user can have on a trip
+const (
+    RoleDriver    = "driver"
+    RolePassenger = "passenger"
+)
+
+// Timeline sections
+const (
+    TimelineUpcoming = "upcoming"
+    TimelinePast     = "past"
+)
+
+// TimelineFilter selects trips from a user's timeline. Empty fields don't
+// filter; BookingStatus only matches trips the user booked.
+type TimelineFilter struct {
+    When          string `json:"when"`
+    Role          string `json:"role"`
+    Status        string `json:"status"`
+    BookingStatus string `json:"bookingStatus"`
+    Cursor        string `json:"cursor"`
+    Limit         int    `json:"limit"`
+}
+
+// Trip search sort orders
+const (
+    SortDeparture  = "departure_time"
+    SortPrice      = "price"
+    SortSeatsLeft  = "seats_left"
+    SortMatchScore = "match_score"
+)
+
 type TripSearchCriteria struct {
-    From          string  `json:"from"`
-    To            string  `json:"to"`
-    DepartureDate string  `json:"departureDate"`
-    MaxPrice      float64 `json:"maxPrice"`
-    Limit         int     `json:"limit"`
-    Offset        int     `json:"offset"`
+    From          string           `json:"from"`
+    To            string           `json:"to"`
+    Departures    []TimeRange      `json:"departures,omitempty"`
+    MaxPrice      *money.Money     `json:"maxPrice,omitempty"`
+    Preferences   PreferenceFilter `json:"preferences"`
+    Sort          string           `json:"sort"`
+    Cursor        string           `json:"cursor"`
+    Limit         int              `json:"limit"`
+}
+
+// TimeRange is a half-open span of time, [Start, End). A zero bound leaves
+// that side open.
+type TimeRange struct {
+    Start time.Time `json:"start"`
+    End   time.Time `json:"end"`
+}
+
+// TripPage is one page of a trip listing. Pass NextCursor back to get the
+// page after it; Total counts every trip matching the listing.
+type TripPage struct {
+    Trips      []Trip  `json:"trips"`
+    NextCursor *string `json:"nextCursor,omitempty"`
+    HasMore    bool    `json:"hasMore"`
+    Total      int     `json:"total"`
 }
@@ -0,0 +1,30 @@
+package models
+
+import (
+    "time"
+)
+
+// Responses to a material trip change
+const (
+    ChangeResponseKeep   = "keep"
+    ChangeResponseCancel = "cancel"
+)
+
+// FieldChange is the value of a trip field before and after an update.
+type FieldChange struct {
+    From interface{} `json:"from"`
+    To   interface{} `json:"to"`
+}
+
+// TripChange records an update to a trip, keyed by field. Material changes
+// let booked passengers cancel with a full refund; Passengers lists who was
+// asked.
+type TripChange struct {
+    ID         int                    `json:"id" db:"id"`
+    TripID     int                    `json:"tripId" db:"trip_id"`
+    ChangedBy  *int                   `json:"changedBy,omitempty" db:"changed_by"`
+    Changes    map[string]FieldChange `json:"changes" db:"changes"`
+    Material   bool                   `json:"material" db:"material"`
+    Passengers []int                  `json:"passengers,omitempty"`
+    CreatedAt  time.Time              `json:"createdAt" db:"created_at"`
+}
@@ -11,6 +11,8 @@ type User struct {
     Password     string    `json:"password,omitempty" db:"password"`
     Phone        string    `json:"phone" db:"phone"`
     ProfileImage *string   `json:"profileImage,omitempty" db:"profile_image"`
+    Gender       *string   `json:"gender,omitempty" db:"gender"`
+    Timezone     string    `json:"timezone" db:"timezone"`
     IsVerified   bool      `json:"isVerified" db:"is_verified"`
     CreatedAt    time.Time `json:"createdAt" db:"created_at"`
     UpdatedAt    time.Time `json:"updatedAt" db:"updated_at"`
@@ -0,0 +1,24 @@
+package models
+
+import (
+    "time"
+)
+
+type Vehicle struct {
+    ID             int       `json:"id" db:"id"`
+    OwnerID        int       `json:"ownerId" db:"owner_id"`
+    Make           string    `json:"make" db:"make"`
+    Model          string    `json:"model" db:"model"`
+    Colour         string    `json:"colour" db:"colour"`
+    Plate          string    `json:"plate" db:"plate"`
+    Seats          int       `json:"seats" db:"seats"`
+    Amenities      []string  `json:"amenities" db:"amenities"`
+    LitresPer100Km *float64  `json:"litresPer100Km,omitempty" db:"litres_per_100km"`
+    CreatedAt      time.Time `json:"createdAt" db:"created_at"`
+    UpdatedAt      time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// PassengerSeats is how many passengers fit next to the driver.
+func (v *Vehicle) PassengerSeats() int {
+    return v.Seats - 1
+}
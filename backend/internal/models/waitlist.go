@@ -0,0 +1,28 @@
+package models
+
+import (
+    "time"
+)
+
+// Waitlist entry statuses
+const (
+    WaitlistWaiting   = "waiting"
+    WaitlistOffered   = "offered"
+    WaitlistBooked    = "booked"
+    WaitlistExpired   = "expired"
+    WaitlistCancelled = "cancelled"
+)
+
+// WaitlistEntry is a passenger waiting for seats on a full trip. Once
+// offered, the seats are held for them until OfferExpiresAt.
+type WaitlistEntry struct {
+    ID             int        `json:"id" db:"id"`
+    TripID         int        `json:"tripId" db:"trip_id"`
+    PassengerID    int        `json:"passengerId" db:"passenger_id"`
+    Seats          int        `json:"seats" db:"seats"`
+    Guests         []string   `json:"guests,omitempty" db:"guest_names"`
+    Status         string     `json:"status" db:"status"`
+    Position       int        `json:"position,omitempty"`
+    OfferExpiresAt *time.Time `json:"offerExpiresAt,omitempty" db:"offer_expires_at"`
+    CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+}
@@ -0,0 +1,263 @@
+package money
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+// Amount is a monetary value in the minor unit of its currency (e.g. cents).
+type Amount int64
+
+type Currency struct {
+    Code     string
+    Exponent int
+    Symbol   string
+}
+
+// DefaultCurrency is assumed when a client doesn't specify one.
+const DefaultCurrency = "USD"
+
+var currencies = map[string]Currency{
+    "USD": {Code: "USD", Exponent: 2, Symbol: "$"},
+    "EUR": {Code: "EUR", Exponent: 2, Symbol: "€"},
+    "GBP": {Code: "GBP", Exponent: 2, Symbol: "£"},
+    "INR": {Code: "INR", Exponent: 2, Symbol: "₹"},
+    "CAD": {Code: "CAD", Exponent: 2, Symbol: "CA$"},
+    "AUD": {Code: "AUD", Exponent: 2, Symbol: "A$"},
+    "CHF": {Code: "CHF", Exponent: 2, Symbol: "CHF "},
+    "JPY": {Code: "JPY", Exponent: 0, Symbol: "¥"},
+}
+
+// Lookup returns the supported currency for an ISO 4217 code.
+func Lookup(code string) (Currency, bool) {
+    currency, ok := currencies[strings.ToUpper(code)]
+    return currency, ok
+}
+
+// Parse converts a non-negative decimal string such as "12.50" into minor
+// units without going through floating point. It rejects more decimal places
+// than the currency allows.
+func Parse(value, code string) (Amount, error) {
+    return parse(value, code, 0)
+}
+
+// parse converts a decimal string into minor units scaled by 10^extra,
+// allowing extra more decimal places than the currency does.
+func parse(value, code string, extra int) (Amount, error) {
+    currency, ok := Lookup(code)
+    if !ok {
+        return 0, fmt.Errorf("unsupported currency %q", code)
+    }
+    currency.Exponent += extra
+
+    value = strings.TrimSpace(value)
+    whole, fraction, hasFraction := strings.Cut(value, ".")
+    if whole == "" || !isDigits(whole) || (hasFraction && !isDigits(fraction)) {
+        return 0, fmt.Errorf("invalid amount %q", value)
+    }
+
+    if len(fraction) > currency.Exponent {
+        return 0, fmt.Errorf("%s amounts allow at most %d decimal places", currency.Code, currency.Exponent)
+    }
+
+    // Keep well clear of int64 overflow once scaled to minor units
+    if len(whole) > 15 {
+        return 0, fmt.Errorf("amount %q is too large", value)
+    }
+
+    fraction += strings.Repeat("0", currency.Exponent-len(fraction))
+    minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+    if err != nil {
+        return 0, fmt.Errorf("invalid amount %q", value)
+    }
+
+    return Amount(minor), nil
+}
+
+func isDigits(s string) bool {
+    if s == "" {
+        return false
+    }
+    for _, r := range s {
+        if r < '0' || r > '9' {
+            return false
+        }
+    }
+    return true
+}
+
+// Scale returns a * num / den rounded half away from zero.
+func (a Amount) Scale(num, den int64) Amount {
+    if den == 0 {
+        return 0
+    }
+
+    product := int64(a) * num
+    quotient, remainder := product/den, product%den
+    if remainder < 0 {
+        remainder = -remainder
+    }
+    if remainder*2 >= abs(den) {
+        if (product < 0) != (den < 0) {
+            quotient--
+        } else {
+            quotient++
+        }
+    }
+
+    return Amount(quotient)
+}
+
+// Percent returns pct percent of a, rounded half away from zero.
+func (a Amount) Percent(pct int) Amount {
+    return a.Scale(int64(pct), 100)
+}
+
+// Split divides a into n shares that differ by at most one minor unit and
+// always add back up to a. The larger shares come first.
+func (a Amount) Split(n int) []Amount {
+    if n <= 0 {
+        return nil
+    }
+
+    shares := make([]Amount, n)
+    base, remainder := int64(a)/int64(n), int64(a)%int64(n)
+    for i := range shares {
+        shares[i] = Amount(base)
+        if int64(i) < remainder {
+            shares[i]++
+        }
+    }
+
+    return shares
+}
+
+// Format renders an amount for display, e.g. "$1,234.50" or "¥1,200".
+func Format(a Amount, code string) string {
+    currency, ok := Lookup(code)
+    if !ok {
+        currency = Currency{Code: code, Exponent: 2, Symbol: code + " "}
+    }
+
+    sign := ""
+    value := int64(a)
+    if value < 0 {
+        sign = "-"
+        value = -value
+    }
+
+    digits := strconv.FormatInt(value, 10)
+    if len(digits) <= currency.Exponent {
+        digits = strings.Repeat("0", currency.Exponent-len(digits)+1) + digits
+    }
+
+    whole := digits[:len(digits)-currency.Exponent]
+    fraction := digits[len(digits)-currency.Exponent:]
+
+    var grouped strings.Builder
+    for i, r := range whole {
+        if i > 0 && (len(whole)-i)%3 == 0 {
+            grouped.WriteByte(',')
+        }
+        grouped.WriteRune(r)
+    }
+
+    if fraction == "" {
+        return sign + currency.Symbol + grouped.String()
+    }
+    return sign + currency.Symbol + grouped.String() + "." + fraction
+}
+
+func abs(v int64) int64 {
+    if v < 0 {
+        return -v
+    }
+    return v
+}
+
+// Money pairs an amount with its ISO 4217 currency code.
+type Money struct {
+    Amount   Amount `json:"amount"`
+    Currency string `json:"currency"`
+}
+
+func New(amount Amount, currency string) Money {
+    return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+func (m Money) String() string {
+    return Format(m.Amount, m.Currency)
+}
+
+// MarshalJSON adds a display string next to the minor-unit amount so
+// clients don't need their own currency tables.
+func (m Money) MarshalJSON() ([]byte, error) {
+    return json.Marshal(struct {
+        Amount    Amount `json:"amount"`
+        Currency  string `json:"currency"`
+        Formatted string `json:"formatted"`
+    }{
+        Amount:    m.Amount,
+        Currency:  m.Currency,
+        Formatted: m.String(),
+    })
+}
+
+// RateDigits is how many more decimal places than its currency a Rate keeps.
+const RateDigits = 3
+
+// Rate is a price per unit that is quoted more precisely than the currency's
+// minor unit, e.g. fuel at "1.759" a litre. Amount is in thousandths of the
+// minor unit.
+type Rate struct {
+    Amount   Amount `json:"amount"`
+    Currency string `json:"currency"`
+}
+
+func NewRate(amount Amount, currency string) Rate {
+    return Rate{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// ParseRate converts a non-negative decimal string such as "1.759" into
+// thousandths of the minor unit, allowing up to RateDigits more decimal
+// places than the currency does.
+func ParseRate(value, code string) (Amount, error) {
+    return parse(value, code, RateDigits)
+}
+
+// Times returns the cost of quantity units, rounded to whole minor units.
+func (r Rate) Times(quantity float64) Amount {
+    return Amount(math.Round(quantity * float64(r.Amount) / rateScale))
+}
+
+const rateScale = 1000 // 10^RateDigits
+
+// String renders the rate like Format, with the extra decimal places it
+// needs, e.g. "$1.759".
+func (r Rate) String() string {
+    formatted := Format(r.Amount/rateScale, r.Currency)
+    extra := strings.TrimRight(fmt.Sprintf("%03d", abs(int64(r.Amount%rateScale))), "0")
+    if extra == "" {
+        return formatted
+    }
+    if currency, ok := Lookup(r.Currency); ok && currency.Exponent == 0 {
+        formatted += "."
+    }
+    return formatted + extra
+}
+
+// MarshalJSON adds a display string like Money does.
+func (r Rate) MarshalJSON() ([]byte, error) {
+    return json.Marshal(struct {
+        Amount    Amount `json:"amount"`
+        Currency  string `json:"currency"`
+        Formatted string `json:"formatted"`
+    }{
+        Amount:    r.Amount,
+        Currency:  r.Currency,
+        Formatted: r.String(),
+    })
+}
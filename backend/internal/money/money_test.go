@@ -0,0 +1,59 @@
+package money
+
+import "testing"
+
+func TestParseRate(t *testing.T) {
+    tests := []struct {
+        value   string
+        code    string
+        want    Amount
+        wantErr bool
+    }{
+        {"1.759", "EUR", 175900, false},
+        {"1.8", "USD", 180000, false},
+        {"2", "USD", 200000, false},
+        {"1.75901", "EUR", 175901, false},
+        {"1.759012", "EUR", 0, true},
+        {"165.5", "JPY", 165500, false},
+        {"-1.759", "EUR", 0, true},
+        {"1.759", "XXX", 0, true},
+    }
+
+    for _, tt := range tests {
+        got, err := ParseRate(tt.value, tt.code)
+        if (err != nil) != tt.wantErr {
+            t.Errorf("ParseRate(%q, %s) error = %v, want error %v", tt.value, tt.code, err, tt.wantErr)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("ParseRate(%q, %s) = %d, want %d", tt.value, tt.code, got, tt.want)
+        }
+    }
+
+    // Plain amounts still stop at the currency's minor unit
+    if _, err := Parse("1.759", "EUR"); err == nil {
+        t.Error("Parse accepted three decimal places for EUR")
+    }
+}
+
+func TestRate(t *testing.T) {
+    tests := []struct {
+        rate   Rate
+        litres float64
+        total  Amount
+        text   string
+    }{
+        {NewRate(175900, "eur"), 10, 1759, "€1.759"},
+        {NewRate(180000, "USD"), 7.5, 1350, "$1.80"},
+        {NewRate(165500, "JPY"), 2, 331, "¥165.5"},
+    }
+
+    for _, tt := range tests {
+        if got := tt.rate.Times(tt.litres); got != tt.total {
+            t.Errorf("%v for %v litres = %d, want %d", tt.rate, tt.litres, got, tt.total)
+        }
+        if got := tt.rate.String(); got != tt.text {
+            t.Errorf("String() = %q, want %q", got, tt.text)
+        }
+    }
+}
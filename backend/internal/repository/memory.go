@@ -0,0 +1,406 @@
+package repository
+
+import (
+    "context"
+    "fmt"
+    "rideshare-backend/internal/models"
+    "sort"
+    "sync"
+    "time"
+)
+
+// MemoryStore keeps everything in memory, for running services without a
+// database. Each call is atomic; InTx holds the store for the whole
+// transaction and puts everything back if it fails.
+type MemoryStore struct {
+    mu   *sync.Mutex
+    data *memoryData
+    inTx bool
+}
+
+func NewMemoryStore() *MemoryStore {
+    return &MemoryStore{mu: &sync.Mutex{}, data: newMemoryData()}
+}
+
+type bookingKey struct {
+    tripID, passengerID int
+}
+
+type memoryUser struct {
+    models.User
+    isAdmin bool
+}
+
+type memoryTrip struct {
+    models.Trip
+    startedAt      *time.Time
+    reminderSentAt *time.Time
+}
+
+type memoryBooking struct {
+    models.TripPassenger
+    removalReason string
+    pickup        *models.PickupPoint
+}
+
+type memoryWaitlistEntry struct {
+    models.WaitlistEntry
+    offeredAt *time.Time
+}
+
+type memoryData struct {
+    lastID       map[string]int
+    users        map[int]memoryUser
+    preferences  map[int]models.PreferenceFilter
+    trips        map[int]memoryTrip
+    bookings     map[bookingKey]memoryBooking
+    holds        map[int]models.SeatHold
+    waitlist     map[bookingKey]memoryWaitlistEntry
+    changes      map[int]models.TripChange
+    responses    map[bookingKey]*string // keyed by change and passenger
+    vehicles     map[int]models.Vehicle
+    messages     map[int]models.TripMessage
+    reads        map[bookingKey]memoryRead // keyed by trip and user
+    locations    map[int64]models.TripLocation
+    transactions map[int]models.PaymentTransaction
+    refunds      map[int]models.RefundRequest
+    audit        []models.AuditEvent
+}
+
+func newMemoryData() *memoryData {
+    return &memoryData{
+        lastID:       map[string]int{},
+        users:        map[int]memoryUser{},
+        preferences:  map[int]models.PreferenceFilter{},
+        trips:        map[int]memoryTrip{},
+        bookings:     map[bookingKey]memoryBooking{},
+        holds:        map[int]models.SeatHold{},
+        waitlist:     map[bookingKey]memoryWaitlistEntry{},
+        changes:      map[int]models.TripChange{},
+        responses:    map[bookingKey]*string{},
+        vehicles:     map[int]models.Vehicle{},
+        messages:     map[int]models.TripMessage{},
+        reads:        map[bookingKey]memoryRead{},
+        locations:    map[int64]models.TripLocation{},
+        transactions: map[int]models.PaymentTransaction{},
+        refunds:      map[int]models.RefundRequest{},
+    }
+}
+
+// nextID hands out IDs like a sequence, per table.
+func (d *memoryData) nextID(table string) int {
+    d.lastID[table]++
+    return d.lastID[table]
+}
+
+// clone copies the data so a failed transaction can be undone. Records are
+// only ever replaced, never changed in place, so copying the maps is enough.
+func (d *memoryData) clone() *memoryData {
+    c := newMemoryData()
+    for k, v := range d.lastID {
+        c.lastID[k] = v
+    }
+    for k, v := range d.users {
+        c.users[k] = v
+    }
+    for k, v := range d.preferences {
+        c.preferences[k] = v
+    }
+    for k, v := range d.trips {
+        c.trips[k] = v
+    }
+    for k, v := range d.bookings {
+        c.bookings[k] = v
+    }
+    for k, v := range d.holds {
+        c.holds[k] = v
+    }
+    for k, v := range d.waitlist {
+        c.waitlist[k] = v
+    }
+    for k, v := range d.changes {
+        c.changes[k] = v
+    }
+    for k, v := range d.responses {
+        c.responses[k] = v
+    }
+    for k, v := range d.vehicles {
+        c.vehicles[k] = v
+    }
+    for k, v := range d.messages {
+        c.messages[k] = v
+    }
+    for k, v := range d.reads {
+        c.reads[k] = v
+    }
+    for k, v := range d.locations {
+        c.locations[k] = v
+    }
+    for k, v := range d.transactions {
+        c.transactions[k] = v
+    }
+    for k, v := range d.refunds {
+        c.refunds[k] = v
+    }
+    c.audit = append([]models.AuditEvent{}, d.audit...)
+    return c
+}
+
+// lock holds the store for one call, unless the store is already held by
+// the transaction the call is part of.
+func (s *MemoryStore) lock() func() {
+    if s.inTx {
+        return func() {}
+    }
+    s.mu.Lock()
+    return s.mu.Unlock
+}
+
+func (s *MemoryStore) Users() UserRepository { return memoryUsers{s} }
+func (s *MemoryStore) Trips() TripRepository { return memoryTrips{s} }
+func (s *MemoryStore) Bookings() BookingRepository { return memoryBookings{s} }
+func (s *MemoryStore) Audit() AuditRepository { return memoryAudit{s} }
+func (s *MemoryStore) Vehicles() VehicleRepository { return memoryVehicles{s} }
+func (s *MemoryStore) Messages() MessageRepository { return memoryMessages{s} }
+func (s *MemoryStore) Locations() LocationRepository { return memoryLocations{s} }
+func (s *MemoryStore) Payments() PaymentRepository { return memoryPayments{s} }
+
+func (s *MemoryStore) InTx(ctx context.Context, fn func(tx Store) error) error {
+    if s.inTx {
+        return fn(s)
+    }
+    
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    
+    snapshot := s.data.clone()
+    if err := fn(&MemoryStore{mu: s.mu, data: s.data, inTx: true}); err != nil {
+        *s.data = *snapshot
+        return err
+    }
+    
+    return nil
+}
+
+// SetAdmin grants or revokes the user's admin rights, which the API has no
+// way to do.
+func (s *MemoryStore) SetAdmin(userID int, isAdmin bool) error {
+    defer s.lock()()
+    
+    user, ok := s.data.users[userID]
+    if !ok {
+        return ErrNotFound
+    }
+    user.isAdmin = isAdmin
+    s.data.users[userID] = user
+    return nil
+}
+
+// copyStrings copies a slice so the store and its callers never share one.
+func copyStrings(values []string) []string {
+    if values == nil {
+        return nil
+    }
+    return append([]string{}, values...)
+}
+
+type memoryUsers struct {
+    s *MemoryStore
+}
+
+func (r memoryUsers) Create(ctx context.Context, user *models.User) error {
+    defer r.s.lock()()
+    
+    for _, existing := range r.s.data.users {
+        if existing.Email == user.Email {
+            return fmt.Errorf("failed to create user: %w", ErrDuplicate)
+        }
+    }
+    
+    now := time.Now()
+    user.ID = r.s.data.nextID("users")
+    user.CreatedAt = now
+    user.UpdatedAt = now
+    r.s.data.users[user.ID] = memoryUser{User: *user}
+    return nil
+}
+
+func (r memoryUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+    defer r.s.lock()()
+    
+    for _, user := range r.s.data.users {
+        if user.Email == email {
+            found := user.User
+            return &found, nil
+        }
+    }
+    return nil, ErrNotFound
+}
+
+func (r memoryUsers) GetByID(ctx context.Context, id int) (*models.User, error) {
+    defer r.s.lock()()
+    return r.get(ctx, id)
+}
+
+func (r memoryUsers) GetForUpdate(ctx context.Context, id int) (*models.User, error) {
+    defer r.s.lock()()
+    return r.get(ctx, id)
+}
+
+func (r memoryUsers) get(ctx context.Context, id int) (*models.User, error) {
+    user, ok := r.s.data.users[id]
+    if !ok {
+        return nil, ErrNotFound
+    }
+    
+    found := user.User
+    found.Password = ""
+    return &found, nil
+}
+
+func (r memoryUsers) Update(ctx context.Context, user *models.User) error {
+    defer r.s.lock()()
+    
+    stored, ok := r.s.data.users[user.ID]
+    if !ok {
+        return ErrNotFound
+    }
+    
+    stored.Name = user.Name
+    stored.Phone = user.Phone
+    stored.ProfileImage = user.ProfileImage
+    stored.Gender = user.Gender
+    stored.Timezone = user.Timezone
+    stored.UpdatedAt = time.Now()
+    r.s.data.users[user.ID] = stored
+    
+    user.UpdatedAt = stored.UpdatedAt
+    return nil
+}
+
+func (r memoryUsers) IsAdmin(ctx context.Context, id int) (bool, error) {
+    defer r.s.lock()()
+    return r.s.data.users[id].isAdmin, nil
+}
+
+// copyBool copies an optional flag so the store and its callers never share it.
+func copyBool(value *bool) *bool {
+    if value == nil {
+        return nil
+    }
+    copied := *value
+    return &copied
+}
+
+func copyPreferences(prefs models.PreferenceFilter) models.PreferenceFilter {
+    return models.PreferenceFilter{
+        PetsAllowed:    copyBool(prefs.PetsAllowed),
+        SmokingAllowed: copyBool(prefs.SmokingAllowed),
+        LuggageSize:    prefs.LuggageSize,
+        Music:          copyBool(prefs.Music),
+        WomenOnly:      copyBool(prefs.WomenOnly),
+        InstantBooking: copyBool(prefs.InstantBooking),
+    }
+}
+
+func (r memoryUsers) Preferences(ctx context.Context, id int) (models.PreferenceFilter, error) {
+    defer r.s.lock()()
+    return copyPreferences(r.s.data.preferences[id]), nil
+}
+
+func (r memoryUsers) SavePreferences(ctx context.Context, id int, prefs models.PreferenceFilter) error {
+    defer r.s.lock()()
+    
+    if _, ok := r.s.data.users[id]; !ok {
+        return fmt.Errorf("failed to save preferences: %w", ErrNotFound)
+    }
+    r.s.data.preferences[id] = copyPreferences(prefs)
+    return nil
+}
+
+// isWoman reports whether the user may book women-only trips.
+func (d *memoryData) isWoman(userID int) bool {
+    gender := d.users[userID].Gender
+    return gender != nil && *gender == "female"
+}
+
+type memoryAudit struct {
+    s *MemoryStore
+}
+
+func (r memoryAudit) Record(ctx context.Context, event models.AuditEvent, req models.RequestInfo) error {
+    defer r.s.lock()()
+    
+    if event.Changes == nil {
+        event.Changes = map[string]models.FieldChange{}
+    }
+    
+    event.ID = int64(r.s.data.nextID("audit_events"))
+    event.CreatedAt = time.Now()
+    event.RequestID = nil
+    event.ClientIP = nil
+    if req.RequestID != "" {
+        requestID := req.RequestID
+        event.RequestID = &requestID
+    }
+    if req.ClientIP != "" {
+        clientIP := req.ClientIP
+        event.ClientIP = &clientIP
+    }
+    
+    r.s.data.audit = append(r.s.data.audit, event)
+    return nil
+}
+
+func (r memoryAudit) List(ctx context.Context, filter models.AuditFilter) (*models.AuditPage, error) {
+    defer r.s.lock()()
+    
+    var before int64
+    if filter.Cursor != "" {
+        cursor, err := decodeCursor(filter.Cursor)
+        if err != nil {
+            return nil, err
+        }
+        before = int64(cursor.ID)
+    }
+    
+    var events []models.AuditEvent
+    for _, event := range r.s.data.audit {
+        if filter.EntityType != "" && (event.EntityType != filter.EntityType || event.EntityID != filter.EntityID) {
+            continue
+        }
+        if filter.UserID != 0 {
+            actor := event.ActorID != nil && *event.ActorID == filter.UserID
+            account := event.EntityType == models.AuditEntityUser && event.EntityID == filter.UserID
+            if !actor && !account {
+                continue
+            }
+        }
+        if before != 0 && event.ID >= before {
+            continue
+        }
+        events = append(events, event)
+    }
+    
+    sort.Slice(events, func(i, j int) bool { return events[i].ID > events[j].ID })
+    
+    page := &models.AuditPage{Events: []models.AuditEvent{}}
+    limit := pageSize(filter.Limit, defaultAuditPageSize, maxAuditPageSize)
+    if len(events) > limit {
+        events = events[:limit]
+        page.HasMore = true
+    }
+    page.Events = append(page.Events, events...)
+    
+    if page.HasMore {
+        last := page.Events[len(page.Events)-1]
+        next := encodeCursor(pageCursor{ID: int(last.ID)})
+        page.NextCursor = &next
+    }
+    
+    return page, nil
+}
@@ -0,0 +1,414 @@
+package repository
+
+import (
+    "context"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/money"
+    "sort"
+    "time"
+)
+
+type memoryBookings struct {
+    s *MemoryStore
+}
+
+// copyBooking returns the booking as stored, without relationships.
+func copyBooking(booking models.TripPassenger) models.TripPassenger {
+    return models.TripPassenger{
+        ID:          booking.ID,
+        TripID:      booking.TripID,
+        PassengerID: booking.PassengerID,
+        Status:      booking.Status,
+        Seats:       booking.Seats,
+        Guests:      copyStrings(booking.Guests),
+        JoinedAt:    booking.JoinedAt,
+        RemovedAt:   booking.RemovedAt,
+        Fare:        booking.Fare,
+        FareSeats:   booking.FareSeats,
+    }
+}
+
+func (r memoryBookings) Get(ctx context.Context, tripID, passengerID int) (*models.TripPassenger, error) {
+    defer r.s.lock()()
+    
+    booking, ok := r.s.data.bookings[bookingKey{tripID, passengerID}]
+    if !ok {
+        return nil, ErrNotFound
+    }
+    
+    found := copyBooking(booking.TripPassenger)
+    return &found, nil
+}
+
+func (r memoryBookings) Create(ctx context.Context, booking *models.TripPassenger) error {
+    defer r.s.lock()()
+    
+    key := bookingKey{booking.TripID, booking.PassengerID}
+    stored, ok := r.s.data.bookings[key]
+    if !ok {
+        stored.ID = r.s.data.nextID("trip_passengers")
+        stored.TripID = booking.TripID
+        stored.PassengerID = booking.PassengerID
+    }
+    
+    now := time.Now()
+    stored.Status = booking.Status
+    stored.Seats = booking.Seats
+    stored.Guests = copyStrings(booking.Guests)
+    if stored.Guests == nil {
+        stored.Guests = []string{}
+    }
+    stored.JoinedAt = &now
+    stored.Fare = 0
+    stored.FareSeats = 0
+    r.s.data.bookings[key] = stored
+    
+    booking.ID = stored.ID
+    booking.JoinedAt = &now
+    return nil
+}
+
+// update applies fn to a stored booking.
+func (r memoryBookings) update(ctx context.Context, tripID, passengerID int, fn func(booking *memoryBooking)) error {
+    defer r.s.lock()()
+    
+    key := bookingKey{tripID, passengerID}
+    booking, ok := r.s.data.bookings[key]
+    if !ok {
+        return ErrNotFound
+    }
+    
+    fn(&booking)
+    r.s.data.bookings[key] = booking
+    return nil
+}
+
+func (r memoryBookings) SetStatus(ctx context.Context, tripID, passengerID int, status string) error {
+    return r.update(ctx, tripID, passengerID, func(booking *memoryBooking) {
+        booking.Status = status
+    })
+}
+
+func (r memoryBookings) SetSeats(ctx context.Context, tripID, passengerID, seats int, guests []string) error {
+    return r.update(ctx, tripID, passengerID, func(booking *memoryBooking) {
+        booking.Seats = seats
+        booking.Guests = copyStrings(guests)
+        if booking.Guests == nil {
+            booking.Guests = []string{}
+        }
+    })
+}
+
+func (r memoryBookings) SetFare(ctx context.Context, tripID, passengerID int, fare money.Amount, seats int) error {
+    return r.update(ctx, tripID, passengerID, func(booking *memoryBooking) {
+        booking.Fare = fare
+        booking.FareSeats = seats
+    })
+}
+
+func (r memoryBookings) Remove(ctx context.Context, tripID, passengerID int, reason string) error {
+    return r.update(ctx, tripID, passengerID, func(booking *memoryBooking) {
+        now := time.Now()
+        booking.Status = "cancelled"
+        booking.RemovedAt = &now
+        booking.removalReason = reason
+    })
+}
+
+// sortByJoined orders bookings by when they were made.
+func sortByJoined(bookings []models.TripPassenger) {
+    sort.Slice(bookings, func(i, j int) bool {
+        a, b := bookings[i].JoinedAt, bookings[j].JoinedAt
+        if a != nil && b != nil && !a.Equal(*b) {
+            return a.Before(*b)
+        }
+        return bookings[i].ID < bookings[j].ID
+    })
+}
+
+func (r memoryBookings) Manifest(ctx context.Context, tripID int) ([]models.TripPassenger, error) {
+    defer r.s.lock()()
+    
+    manifest := []models.TripPassenger{}
+    for key, booking := range r.s.data.bookings {
+        if key.tripID != tripID || booking.Status == "cancelled" {
+            continue
+        }
+        
+        passenger := copyBooking(booking.TripPassenger)
+        passenger.RemovedAt = nil
+        user := r.s.data.users[key.passengerID]
+        passenger.Passenger = &models.User{
+            ID:           user.ID,
+            Name:         user.Name,
+            Email:        user.Email,
+            Phone:        user.Phone,
+            ProfileImage: user.ProfileImage,
+        }
+        manifest = append(manifest, passenger)
+    }
+    
+    sortByJoined(manifest)
+    return manifest, nil
+}
+
+func (r memoryBookings) CoPassengers(ctx context.Context, tripIDs []int, userID int) ([]models.TripPassenger, error) {
+    defer r.s.lock()()
+    
+    wanted := make(map[int]bool, len(tripIDs))
+    for _, id := range tripIDs {
+        wanted[id] = true
+    }
+    
+    var passengers []models.TripPassenger
+    for key, booking := range r.s.data.bookings {
+        if !wanted[key.tripID] || booking.Status != "confirmed" || key.passengerID == userID {
+            continue
+        }
+        
+        user := r.s.data.users[key.passengerID]
+        passengers = append(passengers, models.TripPassenger{
+            ID:          booking.ID,
+            TripID:      key.tripID,
+            PassengerID: key.passengerID,
+            JoinedAt:    booking.JoinedAt,
+            Passenger:   &models.User{ID: user.ID, Name: user.Name, ProfileImage: user.ProfileImage},
+        })
+    }
+    
+    sortByJoined(passengers)
+    for i := range passengers {
+        passengers[i].ID = 0
+        passengers[i].JoinedAt = nil
+    }
+    
+    return passengers, nil
+}
+
+func (r memoryBookings) GetHold(ctx context.Context, tripID, passengerID int) (*models.SeatHold, error) {
+    defer r.s.lock()()
+    
+    for _, hold := range r.s.data.holds {
+        if hold.TripID == tripID && hold.PassengerID == passengerID {
+            return &hold, nil
+        }
+    }
+    
+    return nil, ErrNotFound
+}
+
+func (r memoryBookings) CreateHold(ctx context.Context, hold *models.SeatHold, ttl time.Duration) error {
+    defer r.s.lock()()
+    
+    trip, ok := r.s.data.trips[hold.TripID]
+    if !ok {
+        return ErrNotFound
+    }
+    for _, other := range r.s.data.holds {
+        if other.TripID == hold.TripID && other.PassengerID == hold.PassengerID {
+            return ErrDuplicate
+        }
+    }
+    trip.HeldSeats += hold.Seats
+    r.s.data.trips[hold.TripID] = trip
+    
+    now := time.Now()
+    hold.ID = r.s.data.nextID("seat_holds")
+    hold.CreatedAt = now
+    hold.ExpiresAt = now.Add(ttl)
+    r.s.data.holds[hold.ID] = *hold
+    return nil
+}
+
+func (r memoryBookings) ReleaseHolds(ctx context.Context, tripID, passengerID int) (int, error) {
+    defer r.s.lock()()
+    
+    now := time.Now()
+    return heldSeats(r.s.data.releaseHolds(tripID, func(hold models.SeatHold) bool {
+        return hold.PassengerID == passengerID && hold.ExpiresAt.After(now)
+    })), nil
+}
+
+func (r memoryBookings) ReleaseExpiredHolds(ctx context.Context, tripID int) ([]models.SeatHold, error) {
+    defer r.s.lock()()
+    
+    now := time.Now()
+    return r.s.data.releaseHolds(tripID, func(hold models.SeatHold) bool {
+        return !hold.ExpiresAt.After(now)
+    }), nil
+}
+
+// releaseHolds deletes the holds on a trip matching match and gives their
+// seats back, returning the holds.
+func (d *memoryData) releaseHolds(tripID int, match func(hold models.SeatHold) bool) []models.SeatHold {
+    var holds []models.SeatHold
+    for id, hold := range d.holds {
+        if hold.TripID == tripID && match(hold) {
+            holds = append(holds, hold)
+            delete(d.holds, id)
+        }
+    }
+    
+    if trip, ok := d.trips[tripID]; ok && len(holds) > 0 {
+        trip.HeldSeats -= heldSeats(holds)
+        d.trips[tripID] = trip
+    }
+    
+    sort.Slice(holds, func(i, j int) bool { return holds[i].ID < holds[j].ID })
+    return holds
+}
+
+func (r memoryBookings) TripsWithExpiredHolds(ctx context.Context) ([]int, error) {
+    defer r.s.lock()()
+    
+    now := time.Now()
+    seen := map[int]bool{}
+    var tripIDs []int
+    for _, hold := range r.s.data.holds {
+        if !hold.ExpiresAt.After(now) && !seen[hold.TripID] {
+            seen[hold.TripID] = true
+            tripIDs = append(tripIDs, hold.TripID)
+        }
+    }
+    
+    sort.Ints(tripIDs)
+    return tripIDs, nil
+}
+
+// open reports whether a waitlist entry is still in line or holding an offer.
+func open(entry memoryWaitlistEntry) bool {
+    return entry.Status == models.WaitlistWaiting || entry.Status == models.WaitlistOffered
+}
+
+func (r memoryBookings) AddToWaitlist(ctx context.Context, entry *models.WaitlistEntry) error {
+    defer r.s.lock()()
+    
+    key := bookingKey{entry.TripID, entry.PassengerID}
+    stored, ok := r.s.data.waitlist[key]
+    if ok && open(stored) {
+        return ErrDuplicate
+    }
+    if !ok {
+        stored.ID = r.s.data.nextID("trip_waitlist")
+    }
+    
+    stored.TripID = entry.TripID
+    stored.PassengerID = entry.PassengerID
+    stored.Seats = entry.Seats
+    stored.Guests = copyStrings(entry.Guests)
+    if stored.Guests == nil {
+        stored.Guests = []string{}
+    }
+    stored.Status = models.WaitlistWaiting
+    stored.OfferExpiresAt = nil
+    stored.offeredAt = nil
+    stored.CreatedAt = time.Now()
+    r.s.data.waitlist[key] = stored
+    
+    entry.ID = stored.ID
+    entry.Status = stored.Status
+    entry.CreatedAt = stored.CreatedAt
+    entry.Position = 0
+    for _, other := range r.s.data.waitlist {
+        if other.TripID != entry.TripID || other.Status != models.WaitlistWaiting {
+            continue
+        }
+        if other.CreatedAt.Before(stored.CreatedAt) || (other.CreatedAt.Equal(stored.CreatedAt) && other.ID <= stored.ID) {
+            entry.Position++
+        }
+    }
+    
+    return nil
+}
+
+// copyWaitlistEntry returns the entry as stored.
+func copyWaitlistEntry(entry memoryWaitlistEntry) models.WaitlistEntry {
+    found := entry.WaitlistEntry
+    found.Guests = copyStrings(entry.Guests)
+    found.Position = 0
+    if entry.OfferExpiresAt != nil {
+        expiresAt := *entry.OfferExpiresAt
+        found.OfferExpiresAt = &expiresAt
+    }
+    return found
+}
+
+func (r memoryBookings) GetWaitlistEntry(ctx context.Context, tripID, passengerID int) (*models.WaitlistEntry, error) {
+    defer r.s.lock()()
+    
+    entry, ok := r.s.data.waitlist[bookingKey{tripID, passengerID}]
+    if !ok || !open(entry) {
+        return nil, ErrNotFound
+    }
+    
+    found := copyWaitlistEntry(entry)
+    return &found, nil
+}
+
+func (r memoryBookings) Waiting(ctx context.Context, tripID int) ([]models.WaitlistEntry, error) {
+    defer r.s.lock()()
+    
+    var entries []models.WaitlistEntry
+    for _, entry := range r.s.data.waitlist {
+        if entry.TripID == tripID && entry.Status == models.WaitlistWaiting {
+            entries = append(entries, copyWaitlistEntry(entry))
+        }
+    }
+    
+    sort.Slice(entries, func(i, j int) bool {
+        if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+            return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+        }
+        return entries[i].ID < entries[j].ID
+    })
+    
+    return entries, nil
+}
+
+func (r memoryBookings) SetWaitlistStatus(ctx context.Context, tripID, passengerID int, status string) error {
+    defer r.s.lock()()
+    
+    key := bookingKey{tripID, passengerID}
+    entry, ok := r.s.data.waitlist[key]
+    if !ok || !open(entry) {
+        return ErrNotFound
+    }
+    
+    entry.Status = status
+    r.s.data.waitlist[key] = entry
+    return nil
+}
+
+func (r memoryBookings) OfferWaitlist(ctx context.Context, tripID, passengerID int, expiresAt time.Time) error {
+    defer r.s.lock()()
+    
+    key := bookingKey{tripID, passengerID}
+    entry, ok := r.s.data.waitlist[key]
+    if !ok || entry.Status != models.WaitlistWaiting {
+        return ErrNotFound
+    }
+    
+    now := time.Now()
+    entry.Status = models.WaitlistOffered
+    entry.offeredAt = &now
+    entry.OfferExpiresAt = &expiresAt
+    r.s.data.waitlist[key] = entry
+    return nil
+}
+
+func (r memoryBookings) ExpireWaitlistOffers(ctx context.Context, tripID int) error {
+    defer r.s.lock()()
+    
+    now := time.Now()
+    for key, entry := range r.s.data.waitlist {
+        if key.tripID != tripID || entry.Status != models.WaitlistOffered {
+            continue
+        }
+        if entry.OfferExpiresAt != nil && !entry.OfferExpiresAt.After(now) {
+            entry.Status = models.WaitlistExpired
+            r.s.data.waitlist[key] = entry
+        }
+    }
+    
+    return nil
+}
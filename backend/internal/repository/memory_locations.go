@@ -0,0 +1,105 @@
+package repository
+
+import (
+    "context"
+    "rideshare-backend/internal/models"
+    "time"
+)
+
+type memoryLocations struct {
+    s *MemoryStore
+}
+
+// copyLocation returns a copy of the position sharing nothing with it.
+func copyLocation(location models.TripLocation) models.TripLocation {
+    if location.Heading != nil {
+        heading := *location.Heading
+        location.Heading = &heading
+    }
+    if location.SpeedKmh != nil {
+        speed := *location.SpeedKmh
+        location.SpeedKmh = &speed
+    }
+    return location
+}
+
+func (r memoryLocations) Record(ctx context.Context, location *models.TripLocation, driverID int) error {
+    defer r.s.lock()()
+    
+    trip, ok := r.s.data.trips[location.TripID]
+    if !ok || trip.DriverID != driverID || trip.Status != "in_progress" {
+        return ErrNotFound
+    }
+    
+    location.ID = int64(r.s.data.nextID("trip_locations"))
+    location.RecordedAt = time.Now()
+    r.s.data.locations[location.ID] = copyLocation(*location)
+    return nil
+}
+
+func (r memoryLocations) Latest(ctx context.Context, tripID int, since time.Time) (*models.TripLocation, error) {
+    defer r.s.lock()()
+    
+    var latest *models.TripLocation
+    for _, location := range r.s.data.locations {
+        if location.TripID != tripID || !location.RecordedAt.After(since) {
+            continue
+        }
+        if latest == nil || location.RecordedAt.After(latest.RecordedAt) ||
+            (location.RecordedAt.Equal(latest.RecordedAt) && location.ID > latest.ID) {
+            found := copyLocation(location)
+            latest = &found
+        }
+    }
+    
+    if latest == nil {
+        return nil, ErrNotFound
+    }
+    return latest, nil
+}
+
+func (r memoryLocations) SetPickup(ctx context.Context, tripID, passengerID int, lat, lon float64) error {
+    defer r.s.lock()()
+    
+    key := bookingKey{tripID, passengerID}
+    booking, ok := r.s.data.bookings[key]
+    if !ok || booking.Status != "confirmed" {
+        return ErrNotFound
+    }
+    
+    booking.pickup = &models.PickupPoint{PassengerID: passengerID, Latitude: lat, Longitude: lon}
+    r.s.data.bookings[key] = booking
+    return nil
+}
+
+func (r memoryLocations) Pickups(ctx context.Context, tripID int) ([]models.PickupPoint, error) {
+    defer r.s.lock()()
+    
+    var bookings []models.TripPassenger
+    for key, booking := range r.s.data.bookings {
+        if key.tripID == tripID && booking.Status == "confirmed" && booking.pickup != nil {
+            bookings = append(bookings, booking.TripPassenger)
+        }
+    }
+    sortByJoined(bookings)
+    
+    var pickups []models.PickupPoint
+    for _, booking := range bookings {
+        pickups = append(pickups, *r.s.data.bookings[bookingKey{tripID, booking.PassengerID}].pickup)
+    }
+    
+    return pickups, nil
+}
+
+func (r memoryLocations) PurgeBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+    defer r.s.lock()()
+    
+    var purged int64
+    for id, location := range r.s.data.locations {
+        if location.RecordedAt.Before(cutoff) {
+            delete(r.s.data.locations, id)
+            purged++
+        }
+    }
+    return purged, nil
+}
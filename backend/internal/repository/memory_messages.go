@@ -0,0 +1,171 @@
+package repository
+
+import (
+    "context"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/utils"
+    "sort"
+    "time"
+)
+
+type memoryMessages struct {
+    s *MemoryStore
+}
+
+// memoryRead is how far a participant has read a trip's conversation, and
+// how far they were emailed about it.
+type memoryRead struct {
+    lastRead     int
+    readAt       *time.Time
+    lastNotified int
+}
+
+func (r memoryMessages) Create(ctx context.Context, message *models.TripMessage) error {
+    defer r.s.lock()()
+    
+    message.ID = r.s.data.nextID("trip_messages")
+    message.CreatedAt = time.Now()
+    r.s.data.messages[message.ID] = models.TripMessage{
+        ID:        message.ID,
+        TripID:    message.TripID,
+        SenderID:  message.SenderID,
+        Body:      message.Body,
+        CreatedAt: message.CreatedAt,
+    }
+    return nil
+}
+
+func (r memoryMessages) List(ctx context.Context, tripID, beforeID, limit int) ([]models.TripMessage, error) {
+    defer r.s.lock()()
+    
+    messages := []models.TripMessage{}
+    for _, message := range r.s.data.messages {
+        if message.TripID != tripID || (beforeID != 0 && message.ID >= beforeID) {
+            continue
+        }
+        
+        sender := r.s.data.users[message.SenderID]
+        message.Sender = &models.User{ID: sender.ID, Name: sender.Name, ProfileImage: sender.ProfileImage}
+        messages = append(messages, message)
+    }
+    
+    sort.Slice(messages, func(i, j int) bool { return messages[i].ID > messages[j].ID })
+    if len(messages) > limit {
+        messages = messages[:limit]
+    }
+    
+    return messages, nil
+}
+
+func (r memoryMessages) Receipts(ctx context.Context, tripID int) ([]models.MessageReceipt, error) {
+    defer r.s.lock()()
+    
+    var receipts []models.MessageReceipt
+    for key, read := range r.s.data.reads {
+        if key.tripID != tripID || read.lastRead <= 0 {
+            continue
+        }
+        receipts = append(receipts, models.MessageReceipt{
+            TripID:            key.tripID,
+            UserID:            key.passengerID,
+            LastReadMessageID: read.lastRead,
+            ReadAt:            read.readAt,
+        })
+    }
+    
+    sort.Slice(receipts, func(i, j int) bool { return receipts[i].UserID < receipts[j].UserID })
+    return receipts, nil
+}
+
+func (r memoryMessages) MarkRead(ctx context.Context, tripID, userID, messageID int) error {
+    defer r.s.lock()()
+    
+    if message, ok := r.s.data.messages[messageID]; !ok || message.TripID != tripID {
+        return ErrNotFound
+    }
+    
+    key := bookingKey{tripID, userID}
+    read := r.s.data.reads[key]
+    if messageID > read.lastRead {
+        read.lastRead = messageID
+    }
+    now := time.Now()
+    read.readAt = &now
+    r.s.data.reads[key] = read
+    return nil
+}
+
+func (r memoryMessages) MarkNotified(ctx context.Context, tripID, userID, messageID int) error {
+    defer r.s.lock()()
+    
+    key := bookingKey{tripID, userID}
+    read := r.s.data.reads[key]
+    if messageID > read.lastNotified {
+        read.lastNotified = messageID
+    }
+    r.s.data.reads[key] = read
+    return nil
+}
+
+func (r memoryMessages) UnreadDigests(ctx context.Context, postedBefore time.Time) ([]models.UnreadDigest, error) {
+    defer r.s.lock()()
+    
+    digests := map[bookingKey]*models.UnreadDigest{}
+    for _, message := range r.s.data.messages {
+        trip, ok := r.s.data.trips[message.TripID]
+        if !ok || (trip.Status != "active" && trip.Status != "in_progress") || message.CreatedAt.After(postedBefore) {
+            continue
+        }
+        
+        for _, userID := range r.s.data.participants(trip) {
+            key := bookingKey{trip.ID, userID}
+            read := r.s.data.reads[key]
+            if userID == message.SenderID || message.ID <= read.lastRead || message.ID <= read.lastNotified {
+                continue
+            }
+            
+            digest, ok := digests[key]
+            if !ok {
+                user := r.s.data.users[userID]
+                digest = &models.UnreadDigest{
+                    TripID:        trip.ID,
+                    UserID:        userID,
+                    Email:         user.Email,
+                    Name:          user.Name,
+                    FromLocation:  trip.FromLocation,
+                    ToLocation:    trip.ToLocation,
+                    DepartureTime: trip.DepartureTime.In(utils.LoadLocation(trip.Timezone)),
+                }
+                digests[key] = digest
+            }
+            digest.UnreadCount++
+            if message.ID > digest.LatestMessageID {
+                digest.LatestMessageID = message.ID
+            }
+        }
+    }
+    
+    var found []models.UnreadDigest
+    for _, digest := range digests {
+        found = append(found, *digest)
+    }
+    sort.Slice(found, func(i, j int) bool {
+        if found[i].TripID != found[j].TripID {
+            return found[i].TripID < found[j].TripID
+        }
+        return found[i].UserID < found[j].UserID
+    })
+    
+    return found, nil
+}
+
+// participants returns the driver and confirmed passengers of a trip.
+func (d *memoryData) participants(trip memoryTrip) []int {
+    userIDs := []int{trip.DriverID}
+    for key, booking := range d.bookings {
+        if key.tripID == trip.ID && booking.Status == "confirmed" {
+            userIDs = append(userIDs, key.passengerID)
+        }
+    }
+    return userIDs
+}
@@ -0,0 +1,306 @@
+package repository
+
+import (
+    "context"
+    "fmt"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/money"
+    "sort"
+    "time"
+)
+
+type memoryPayments struct {
+    s *MemoryStore
+}
+
+// copyTransaction returns a copy of the transaction and its entries sharing
+// nothing with it.
+func copyTransaction(transaction models.PaymentTransaction) models.PaymentTransaction {
+    copyInt := func(value *int) *int {
+        if value == nil {
+            return nil
+        }
+        copied := *value
+        return &copied
+    }
+    copyString := func(value *string) *string {
+        if value == nil {
+            return nil
+        }
+        copied := *value
+        return &copied
+    }
+    
+    transaction.BookingID = copyInt(transaction.BookingID)
+    transaction.TripID = copyInt(transaction.TripID)
+    transaction.ParentID = copyInt(transaction.ParentID)
+    transaction.ProviderReference = copyString(transaction.ProviderReference)
+    transaction.Description = copyString(transaction.Description)
+    
+    entries := make([]models.LedgerEntry, len(transaction.Entries))
+    for i, entry := range transaction.Entries {
+        entry.UserID = copyInt(entry.UserID)
+        entry.TripID = copyInt(entry.TripID)
+        entries[i] = entry
+    }
+    transaction.Entries = entries
+    
+    return transaction
+}
+
+func (r memoryPayments) Record(ctx context.Context, transaction *models.PaymentTransaction) error {
+    defer r.s.lock()()
+    
+    // PostgreSQL refuses unbalanced transactions when they commit
+    var sum money.Amount
+    for _, entry := range transaction.Entries {
+        sum += entry.Amount
+    }
+    if sum != 0 {
+        return fmt.Errorf("failed to record payment transaction: ledger entries sum to %d", sum)
+    }
+    
+    now := time.Now()
+    transaction.ID = r.s.data.nextID("payment_transactions")
+    transaction.CreatedAt = now
+    for i := range transaction.Entries {
+        entry := &transaction.Entries[i]
+        entry.ID = r.s.data.nextID("ledger_entries")
+        entry.TransactionID = transaction.ID
+        entry.Currency = transaction.Currency
+        entry.CreatedAt = now
+    }
+    
+    r.s.data.transactions[transaction.ID] = copyTransaction(*transaction)
+    return nil
+}
+
+// ChargesForUpdate needs no lock of its own, a transaction holds the whole
+// store.
+func (r memoryPayments) ChargesForUpdate(ctx context.Context, bookingID int) ([]models.BookingCharge, error) {
+    defer r.s.lock()()
+    
+    var joinedAt *time.Time
+    for _, booking := range r.s.data.bookings {
+        if booking.ID == bookingID {
+            joinedAt = booking.JoinedAt
+        }
+    }
+    if joinedAt == nil {
+        return nil, nil
+    }
+    
+    var charges []models.BookingCharge
+    for _, transaction := range r.s.data.transactions {
+        if transaction.Kind != "charge" || transaction.BookingID == nil || *transaction.BookingID != bookingID {
+            continue
+        }
+        if transaction.CreatedAt.Before(*joinedAt) {
+            continue
+        }
+        charges = append(charges, *r.s.data.charge(transaction))
+    }
+    
+    sort.Slice(charges, func(i, j int) bool {
+        if !charges[i].CreatedAt.Equal(charges[j].CreatedAt) {
+            return charges[i].CreatedAt.After(charges[j].CreatedAt)
+        }
+        return charges[i].ID > charges[j].ID
+    })
+    
+    return charges, nil
+}
+
+func (r memoryPayments) GetCharge(ctx context.Context, id int) (*models.BookingCharge, error) {
+    defer r.s.lock()()
+    
+    transaction, ok := r.s.data.transactions[id]
+    if !ok || transaction.Kind != "charge" {
+        return nil, ErrNotFound
+    }
+    return r.s.data.charge(transaction), nil
+}
+
+// charge adds up the fee taken by a charge and what has been refunded, or is
+// pending to be, against it.
+func (d *memoryData) charge(transaction models.PaymentTransaction) *models.BookingCharge {
+    charge := &models.BookingCharge{PaymentTransaction: copyTransaction(transaction)}
+    for _, entry := range charge.Entries {
+        if entry.Account == "platform" {
+            charge.Fee += entry.Amount
+        }
+    }
+    for _, refund := range d.transactions {
+        if refund.Kind == "refund" && refund.ParentID != nil && *refund.ParentID == transaction.ID {
+            charge.Refunded += refund.Amount
+        }
+    }
+    for _, request := range d.refunds {
+        if request.ChargeID == transaction.ID && request.Status == models.RefundPending {
+            charge.Refunded += request.Amount
+        }
+    }
+    return charge
+}
+
+func (r memoryPayments) Balances(ctx context.Context, userID int) ([]models.AccountBalance, error) {
+    defer r.s.lock()()
+    
+    type account struct {
+        name, currency string
+    }
+    sums := map[account]money.Amount{}
+    for _, transaction := range r.s.data.transactions {
+        for _, entry := range transaction.Entries {
+            if entry.UserID != nil && *entry.UserID == userID {
+                sums[account{entry.Account, entry.Currency}] += entry.Amount
+            }
+        }
+    }
+    
+    balances := []models.AccountBalance{}
+    for key, sum := range sums {
+        balances = append(balances, models.AccountBalance{Account: key.name, Currency: key.currency, Balance: sum})
+    }
+    sort.Slice(balances, func(i, j int) bool {
+        if balances[i].Account != balances[j].Account {
+            return balances[i].Account < balances[j].Account
+        }
+        return balances[i].Currency < balances[j].Currency
+    })
+    
+    return balances, nil
+}
+
+func (r memoryPayments) Entries(ctx context.Context, userID, limit, offset int) ([]models.LedgerEntry, error) {
+    defer r.s.lock()()
+    
+    entries := []models.LedgerEntry{}
+    for _, transaction := range r.s.data.transactions {
+        copied := copyTransaction(transaction)
+        for _, entry := range copied.Entries {
+            if entry.UserID != nil && *entry.UserID == userID {
+                entry.TripID = copied.TripID
+                entries = append(entries, entry)
+            }
+        }
+    }
+    
+    sort.Slice(entries, func(i, j int) bool {
+        if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+            return entries[i].CreatedAt.After(entries[j].CreatedAt)
+        }
+        return entries[i].ID > entries[j].ID
+    })
+    
+    if offset >= len(entries) {
+        return []models.LedgerEntry{}, nil
+    }
+    entries = entries[offset:]
+    if len(entries) > limit {
+        entries = entries[:limit]
+    }
+    
+    return entries, nil
+}
+
+// copyRefundRequest returns a copy of the request sharing nothing with it.
+func copyRefundRequest(request models.RefundRequest) models.RefundRequest {
+    if request.Description != nil {
+        description := *request.Description
+        request.Description = &description
+    }
+    if request.LastError != nil {
+        lastError := *request.LastError
+        request.LastError = &lastError
+    }
+    if request.TransactionID != nil {
+        transactionID := *request.TransactionID
+        request.TransactionID = &transactionID
+    }
+    return request
+}
+
+func (r memoryPayments) RequestRefund(ctx context.Context, request *models.RefundRequest) error {
+    defer r.s.lock()()
+    
+    if _, ok := r.s.data.transactions[request.ChargeID]; !ok {
+        return fmt.Errorf("failed to request refund: %w", ErrNotFound)
+    }
+    
+    now := time.Now()
+    request.ID = r.s.data.nextID("refund_requests")
+    request.Status = models.RefundPending
+    request.Attempts = 0
+    request.LastError = nil
+    request.TransactionID = nil
+    request.CreatedAt = now
+    request.UpdatedAt = now
+    r.s.data.refunds[request.ID] = copyRefundRequest(*request)
+    return nil
+}
+
+func (r memoryPayments) GetRefundRequestForUpdate(ctx context.Context, id int) (*models.RefundRequest, error) {
+    defer r.s.lock()()
+    
+    request, ok := r.s.data.refunds[id]
+    if !ok {
+        return nil, ErrNotFound
+    }
+    
+    found := copyRefundRequest(request)
+    return &found, nil
+}
+
+func (r memoryPayments) PendingRefunds(ctx context.Context, cutoff time.Time, limit int) ([]models.RefundRequest, error) {
+    defer r.s.lock()()
+    
+    var requests []models.RefundRequest
+    for _, request := range r.s.data.refunds {
+        if request.Status == models.RefundPending && request.UpdatedAt.Before(cutoff) {
+            requests = append(requests, copyRefundRequest(request))
+        }
+    }
+    
+    sort.Slice(requests, func(i, j int) bool {
+        if !requests[i].UpdatedAt.Equal(requests[j].UpdatedAt) {
+            return requests[i].UpdatedAt.Before(requests[j].UpdatedAt)
+        }
+        return requests[i].ID < requests[j].ID
+    })
+    if len(requests) > limit {
+        requests = requests[:limit]
+    }
+    
+    return requests, nil
+}
+
+// updateRefund applies fn to a pending refund request.
+func (r memoryPayments) updateRefund(id int, fn func(request *models.RefundRequest)) error {
+    defer r.s.lock()()
+    
+    request, ok := r.s.data.refunds[id]
+    if !ok || request.Status != models.RefundPending {
+        return ErrNotFound
+    }
+    
+    fn(&request)
+    request.Attempts++
+    request.UpdatedAt = time.Now()
+    r.s.data.refunds[id] = request
+    return nil
+}
+
+func (r memoryPayments) FailRefund(ctx context.Context, id int, reason string) error {
+    return r.updateRefund(id, func(request *models.RefundRequest) {
+        request.LastError = &reason
+    })
+}
+
+func (r memoryPayments) CompleteRefund(ctx context.Context, id, transactionID int) error {
+    return r.updateRefund(id, func(request *models.RefundRequest) {
+        request.Status = models.RefundCompleted
+        request.LastError = nil
+        request.TransactionID = &transactionID
+    })
+}
@@ -0,0 +1,726 @@
+package repository
+
+import (
+    "context"
+    "fmt"
+    "math"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/money"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+    "unicode"
+)
+
+type memoryTrips struct {
+    s *MemoryStore
+}
+
+// copyTrip copies the fields a trip is stored with, so the store and its
+// callers never share a pointer.
+func copyTrip(trip models.Trip) models.Trip {
+    stored := models.Trip{
+        ID:                trip.ID,
+        DriverID:          trip.DriverID,
+        FromLocation:      trip.FromLocation,
+        ToLocation:        trip.ToLocation,
+        DepartureTime:     trip.DepartureTime,
+        Timezone:          trip.Timezone,
+        MaxPassengers:     trip.MaxPassengers,
+        CurrentPassengers: trip.CurrentPassengers,
+        HeldSeats:         trip.HeldSeats,
+        PricePerPerson:    trip.PricePerPerson,
+        FareMode:          trip.FareMode,
+        Preferences:       trip.Preferences,
+        Status:            trip.Status,
+        CreatedAt:         trip.CreatedAt,
+        UpdatedAt:         trip.UpdatedAt,
+    }
+    
+    if trip.TotalCost != nil {
+        total := *trip.TotalCost
+        stored.TotalCost = &total
+    }
+    if trip.VehicleID != nil {
+        vehicleID := *trip.VehicleID
+        stored.VehicleID = &vehicleID
+    }
+    if trip.Description != nil {
+        description := *trip.Description
+        stored.Description = &description
+    }
+    
+    return stored
+}
+
+// withDriver returns a copy of the trip with its driver's contact details,
+// as a LEFT JOIN on users would load them.
+func (d *memoryData) withDriver(trip memoryTrip) models.Trip {
+    found := copyTrip(trip.Trip)
+    if driver, ok := d.users[trip.DriverID]; ok {
+        found.Driver = &models.User{
+            ID:    driver.ID,
+            Name:  driver.Name,
+            Email: driver.Email,
+            Phone: driver.Phone,
+        }
+    }
+    return found
+}
+
+func (r memoryTrips) Create(ctx context.Context, trip *models.Trip) error {
+    defer r.s.lock()()
+    
+    now := time.Now()
+    trip.ID = r.s.data.nextID("trips")
+    trip.CreatedAt = now
+    trip.UpdatedAt = now
+    r.s.data.trips[trip.ID] = memoryTrip{Trip: copyTrip(*trip)}
+    return nil
+}
+
+func (r memoryTrips) Get(ctx context.Context, id int) (*models.Trip, error) {
+    defer r.s.lock()()
+    
+    trip, ok := r.s.data.trips[id]
+    if !ok {
+        return nil, ErrNotFound
+    }
+    
+    found := r.s.data.withDriver(trip)
+    return &found, nil
+}
+
+func (r memoryTrips) GetForUpdate(ctx context.Context, id int) (*models.Trip, error) {
+    defer r.s.lock()()
+    
+    trip, ok := r.s.data.trips[id]
+    if !ok {
+        return nil, ErrNotFound
+    }
+    
+    found := copyTrip(trip.Trip)
+    return &found, nil
+}
+
+func (r memoryTrips) Update(ctx context.Context, trip *models.Trip) error {
+    defer r.s.lock()()
+    
+    stored, ok := r.s.data.trips[trip.ID]
+    if !ok {
+        return ErrNotFound
+    }
+    
+    if stored.CurrentPassengers+stored.HeldSeats > trip.MaxPassengers {
+        return ErrOverCapacity
+    }
+    
+    updated := copyTrip(*trip)
+    updated.DriverID = stored.DriverID
+    updated.CurrentPassengers = stored.CurrentPassengers
+    updated.HeldSeats = stored.HeldSeats
+    updated.Status = stored.Status
+    updated.CreatedAt = stored.CreatedAt
+    updated.UpdatedAt = time.Now()
+    stored.Trip = updated
+    r.s.data.trips[trip.ID] = stored
+    return nil
+}
+
+func (r memoryTrips) SetStatus(ctx context.Context, id int, status string) error {
+    defer r.s.lock()()
+    
+    trip, ok := r.s.data.trips[id]
+    if !ok {
+        return ErrNotFound
+    }
+    
+    now := time.Now()
+    trip.Status = status
+    trip.UpdatedAt = now
+    if status == "in_progress" {
+        trip.startedAt = &now
+    }
+    r.s.data.trips[id] = trip
+    return nil
+}
+
+func (r memoryTrips) TakeSeats(ctx context.Context, id, seats int) error {
+    defer r.s.lock()()
+    
+    trip, ok := r.s.data.trips[id]
+    if !ok || trip.CurrentPassengers+trip.HeldSeats+seats > trip.MaxPassengers {
+        return ErrOverCapacity
+    }
+    
+    trip.CurrentPassengers += seats
+    trip.UpdatedAt = time.Now()
+    r.s.data.trips[id] = trip
+    r.s.data.repriceSharedFare(id)
+    return nil
+}
+
+func (r memoryTrips) ReleaseSeats(ctx context.Context, id, seats int) error {
+    defer r.s.lock()()
+    
+    trip, ok := r.s.data.trips[id]
+    if !ok {
+        return nil
+    }
+    
+    trip.CurrentPassengers -= seats
+    trip.UpdatedAt = time.Now()
+    r.s.data.trips[id] = trip
+    r.s.data.repriceSharedFare(id)
+    return nil
+}
+
+func (r memoryTrips) RepriceSharedFare(ctx context.Context, id int) error {
+    defer r.s.lock()()
+    r.s.data.repriceSharedFare(id)
+    return nil
+}
+
+// repriceSharedFare splits the cost as the PostgreSQL store does, rounding
+// each share up.
+func (d *memoryData) repriceSharedFare(id int) {
+    trip, ok := d.trips[id]
+    if !ok || trip.FareMode != models.FareModeShared || trip.TotalCost == nil {
+        return
+    }
+    
+    passengers := trip.CurrentPassengers
+    if passengers < 1 {
+        passengers = 1
+    }
+    
+    trip.PricePerPerson.Amount = (trip.TotalCost.Amount + money.Amount(passengers)) / money.Amount(passengers+1)
+    d.trips[id] = trip
+}
+
+func (r memoryTrips) DeleteLocations(ctx context.Context, id int) error {
+    defer r.s.lock()()
+    
+    for locationID, location := range r.s.data.locations {
+        if location.TripID == id {
+            delete(r.s.data.locations, locationID)
+        }
+    }
+    return nil
+}
+
+func (r memoryTrips) IsParticipant(ctx context.Context, tripID, userID int) (bool, error) {
+    defer r.s.lock()()
+    return r.s.data.isParticipant(tripID, userID), nil
+}
+
+func (d *memoryData) isParticipant(tripID, userID int) bool {
+    if trip, ok := d.trips[tripID]; ok && trip.DriverID == userID {
+        return true
+    }
+    booking, ok := d.bookings[bookingKey{tripID, userID}]
+    return ok && booking.Status == "confirmed"
+}
+
+func (r memoryTrips) DriverStats(ctx context.Context, driverID int) (*models.UserStats, error) {
+    defer r.s.lock()()
+    
+    stats := &models.UserStats{}
+    for _, trip := range r.s.data.trips {
+        if trip.DriverID != driverID {
+            continue
+        }
+        stats.TotalTrips++
+        switch trip.Status {
+        case "completed":
+            stats.CompletedTrips++
+        case "cancelled":
+            stats.CancelledTrips++
+        }
+    }
+    
+    // Every completed trip counts as a five star review until there are reviews
+    stats.ReviewCount = stats.CompletedTrips
+    if stats.CompletedTrips > 0 {
+        stats.Rating = 5
+    }
+    
+    return stats, nil
+}
+
+func (r memoryTrips) ActiveIDsForUser(ctx context.Context, userID int) ([]int, error) {
+    defer r.s.lock()()
+    
+    var tripIDs []int
+    for id, trip := range r.s.data.trips {
+        if trip.Status != "active" && trip.Status != "in_progress" {
+            continue
+        }
+        if r.s.data.isParticipant(id, userID) {
+            tripIDs = append(tripIDs, id)
+        }
+    }
+    
+    sort.Ints(tripIDs)
+    return tripIDs, nil
+}
+
+func (r memoryTrips) ListByDriver(ctx context.Context, driverID int, cursor string, limit int) (*models.TripPage, error) {
+    defer r.s.lock()()
+    
+    var trips []models.Trip
+    for _, trip := range r.s.data.trips {
+        if trip.DriverID == driverID {
+            trips = append(trips, r.s.data.withDriver(trip))
+        }
+    }
+    
+    return listMemoryTrips(trips, memoryOrders[models.SortDeparture].reversed(), cursor, limit)
+}
+
+func (r memoryTrips) Timeline(ctx context.Context, userID int, filter models.TimelineFilter) (*models.TripPage, error) {
+    defer r.s.lock()()
+    
+    now := time.Now()
+    var trips []models.Trip
+    for id, stored := range r.s.data.trips {
+        driving := stored.DriverID == userID
+        booking, booked := r.s.data.bookings[bookingKey{id, userID}]
+        if !driving && !booked {
+            continue
+        }
+        
+        // A trip is upcoming until it's over, or until its departure has
+        // passed without it being started
+        upcoming := stored.Status == "in_progress" || (stored.Status == "active" && !stored.DepartureTime.Before(now))
+        if upcoming == (filter.When == models.TimelinePast) {
+            continue
+        }
+        
+        if (filter.Role == models.RoleDriver && !driving) || (filter.Role == models.RolePassenger && driving) {
+            continue
+        }
+        if filter.Status != "" && stored.Status != filter.Status {
+            continue
+        }
+        if filter.BookingStatus != "" {
+            if driving || booking.Status != filter.BookingStatus {
+                continue
+            }
+        } else if !driving && booking.Status == "cancelled" {
+            continue
+        }
+        
+        trip := r.s.data.withDriver(stored)
+        if driving {
+            trip.UserRole = models.RoleDriver
+        } else {
+            status := booking.Status
+            trip.UserRole = models.RolePassenger
+            trip.BookingStatus = &status
+        }
+        trips = append(trips, trip)
+    }
+    
+    order := memoryOrders[models.SortDeparture]
+    if filter.When == models.TimelinePast {
+        order = order.reversed()
+    }
+    
+    return listMemoryTrips(trips, order, filter.Cursor, filter.Limit)
+}
+
+func (r memoryTrips) Search(ctx context.Context, userID int, criteria models.TripSearchCriteria) (*models.TripPage, error) {
+    order, ok := memoryOrders[criteria.Sort]
+    switch {
+    case criteria.Sort == "":
+        order = memoryOrders[models.SortDeparture]
+    case criteria.Sort == models.SortMatchScore:
+        // Trigram similarity of the route, weighted like MatchingService
+        order = memoryOrder{
+            key: func(trip *models.Trip) interface{} {
+                return similarity(trip.FromLocation, criteria.From)*0.45 + similarity(trip.ToLocation, criteria.To)*0.55
+            },
+            desc: true,
+        }
+    case !ok:
+        return nil, fmt.Errorf("invalid sort order %q", criteria.Sort)
+    }
+    
+    defer r.s.lock()()
+    
+    woman := r.s.data.isWoman(userID)
+    var trips []models.Trip
+    for _, stored := range r.s.data.trips {
+        trip := stored.Trip
+        if trip.Status != "active" || trip.CurrentPassengers+trip.HeldSeats >= trip.MaxPassengers {
+            continue
+        }
+        if trip.Preferences.WomenOnly && !woman {
+            continue
+        }
+        if !containsFold(trip.FromLocation, criteria.From) || !containsFold(trip.ToLocation, criteria.To) {
+            continue
+        }
+        if len(criteria.Departures) > 0 && !departsWithin(trip.DepartureTime, criteria.Departures) {
+            continue
+        }
+        if criteria.MaxPrice != nil && (trip.PricePerPerson.Currency != criteria.MaxPrice.Currency || trip.PricePerPerson.Amount > criteria.MaxPrice.Amount) {
+            continue
+        }
+        if !matchesPreferences(trip.Preferences, criteria.Preferences) {
+            continue
+        }
+        trips = append(trips, r.s.data.withDriver(stored))
+    }
+    
+    return listMemoryTrips(trips, order, criteria.Cursor, criteria.Limit)
+}
+
+func containsFold(s, substr string) bool {
+    return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func departsWithin(departure time.Time, ranges []models.TimeRange) bool {
+    for _, r := range ranges {
+        if (r.Start.IsZero() || !departure.Before(r.Start)) && (r.End.IsZero() || departure.Before(r.End)) {
+            return true
+        }
+    }
+    return false
+}
+
+func matchesPreferences(trip models.TripPreferences, filter models.PreferenceFilter) bool {
+    flags := []struct {
+        want *bool
+        got  bool
+    }{
+        {filter.PetsAllowed, trip.PetsAllowed},
+        {filter.SmokingAllowed, trip.SmokingAllowed},
+        {filter.Music, trip.Music},
+        {filter.WomenOnly, trip.WomenOnly},
+        {filter.InstantBooking, trip.InstantBooking},
+    }
+    
+    for _, flag := range flags {
+        if flag.want != nil && *flag.want != flag.got {
+            return false
+        }
+    }
+    
+    return filter.LuggageSize == "" || models.LuggageRank(trip.LuggageSize) >= models.LuggageRank(filter.LuggageSize)
+}
+
+// similarity compares two strings by their trigrams, like pg_trgm.
+func similarity(a, b string) float64 {
+    ta, tb := trigrams(a), trigrams(b)
+    if len(ta) == 0 || len(tb) == 0 {
+        return 0
+    }
+    
+    shared := 0
+    for t := range ta {
+        if tb[t] {
+            shared++
+        }
+    }
+    
+    return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+func trigrams(s string) map[string]bool {
+    set := map[string]bool{}
+    words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    })
+    
+    for _, word := range words {
+        padded := []rune("  " + word + " ")
+        for i := 0; i+3 <= len(padded); i++ {
+            set[string(padded[i:i+3])] = true
+        }
+    }
+    
+    return set
+}
+
+func (r memoryTrips) ListBookable(ctx context.Context, userID int) ([]models.Trip, error) {
+    defer r.s.lock()()
+    
+    now := time.Now()
+    woman := r.s.data.isWoman(userID)
+    var trips []models.Trip
+    for _, stored := range r.s.data.trips {
+        trip := stored.Trip
+        if trip.Status != "active" || trip.DriverID == userID || !trip.DepartureTime.After(now) {
+            continue
+        }
+        if trip.CurrentPassengers+trip.HeldSeats >= trip.MaxPassengers {
+            continue
+        }
+        if trip.Preferences.WomenOnly && !woman {
+            continue
+        }
+        trips = append(trips, copyTrip(trip))
+    }
+    
+    sort.Slice(trips, func(i, j int) bool {
+        if !trips[i].DepartureTime.Equal(trips[j].DepartureTime) {
+            return trips[i].DepartureTime.Before(trips[j].DepartureTime)
+        }
+        return trips[i].ID < trips[j].ID
+    })
+    
+    return trips, nil
+}
+
+func (r memoryTrips) ClaimDueReminders(ctx context.Context, lead time.Duration) ([]models.Trip, error) {
+    defer r.s.lock()()
+    
+    now := time.Now()
+    var trips []models.Trip
+    for id, trip := range r.s.data.trips {
+        if trip.Status != "active" || trip.reminderSentAt != nil {
+            continue
+        }
+        if !trip.DepartureTime.After(now) || trip.DepartureTime.After(now.Add(lead)) {
+            continue
+        }
+        
+        trip.reminderSentAt = &now
+        r.s.data.trips[id] = trip
+        trips = append(trips, copyTrip(trip.Trip))
+    }
+    
+    return trips, nil
+}
+
+func (r memoryTrips) RecordChange(ctx context.Context, change *models.TripChange) error {
+    defer r.s.lock()()
+    
+    change.ID = r.s.data.nextID("trip_changes")
+    change.CreatedAt = time.Now()
+    change.Passengers = nil
+    
+    if change.Material {
+        for key, booking := range r.s.data.bookings {
+            if key.tripID == change.TripID && booking.Status != "cancelled" {
+                r.s.data.responses[bookingKey{change.ID, key.passengerID}] = nil
+                change.Passengers = append(change.Passengers, key.passengerID)
+            }
+        }
+        sort.Ints(change.Passengers)
+    }
+    
+    stored := *change
+    stored.Passengers = nil
+    r.s.data.changes[change.ID] = stored
+    return nil
+}
+
+func (r memoryTrips) ListChanges(ctx context.Context, tripID int) ([]models.TripChange, error) {
+    defer r.s.lock()()
+    
+    changes := []models.TripChange{}
+    for _, change := range r.s.data.changes {
+        if change.TripID == tripID {
+            changes = append(changes, change)
+        }
+    }
+    
+    sort.Slice(changes, func(i, j int) bool {
+        if !changes[i].CreatedAt.Equal(changes[j].CreatedAt) {
+            return changes[i].CreatedAt.After(changes[j].CreatedAt)
+        }
+        return changes[i].ID > changes[j].ID
+    })
+    
+    return changes, nil
+}
+
+func (r memoryTrips) GetChangeResponse(ctx context.Context, changeID, tripID, passengerID int) (*string, error) {
+    defer r.s.lock()()
+    
+    if change, ok := r.s.data.changes[changeID]; !ok || change.TripID != tripID {
+        return nil, ErrNotFound
+    }
+    
+    response, ok := r.s.data.responses[bookingKey{changeID, passengerID}]
+    if !ok {
+        return nil, ErrNotFound
+    }
+    if response == nil {
+        return nil, nil
+    }
+    
+    value := *response
+    return &value, nil
+}
+
+func (r memoryTrips) AwaitingResponse(ctx context.Context, tripID, passengerID int) (bool, error) {
+    defer r.s.lock()()
+    
+    for key, response := range r.s.data.responses {
+        if key.passengerID == passengerID && response == nil && r.s.data.changes[key.tripID].TripID == tripID {
+            return true, nil
+        }
+    }
+    
+    return false, nil
+}
+
+func (r memoryTrips) SetChangeResponse(ctx context.Context, changeID, passengerID int, response string) error {
+    defer r.s.lock()()
+    
+    key := bookingKey{changeID, passengerID}
+    if _, ok := r.s.data.responses[key]; !ok {
+        return ErrNotFound
+    }
+    
+    r.s.data.responses[key] = &response
+    return nil
+}
+
+// memoryOrder sorts trips like tripOrder. Keys are time.Time, int64 or
+// float64; ties are broken on the trip ID.
+type memoryOrder struct {
+    key  func(trip *models.Trip) interface{}
+    desc bool
+}
+
+func (o memoryOrder) reversed() memoryOrder {
+    return memoryOrder{key: o.key, desc: !o.desc}
+}
+
+var memoryOrders = map[string]memoryOrder{
+    models.SortDeparture: {key: func(trip *models.Trip) interface{} { return trip.DepartureTime }},
+    models.SortPrice:     {key: func(trip *models.Trip) interface{} { return int64(trip.PricePerPerson.Amount) }},
+    models.SortSeatsLeft: {
+        key:  func(trip *models.Trip) interface{} { return int64(trip.MaxPassengers - trip.CurrentPassengers - trip.HeldSeats) },
+        desc: true,
+    },
+}
+
+func compareKeys(a, b interface{}) int {
+    switch a := a.(type) {
+    case time.Time:
+        b := b.(time.Time)
+        if a.Before(b) {
+            return -1
+        }
+        if a.After(b) {
+            return 1
+        }
+    case int64:
+        b := b.(int64)
+        if a < b {
+            return -1
+        }
+        if a > b {
+            return 1
+        }
+    case float64:
+        b := b.(float64)
+        if a < b {
+            return -1
+        }
+        if a > b {
+            return 1
+        }
+    }
+    return 0
+}
+
+func formatKey(key interface{}) string {
+    switch key := key.(type) {
+    case time.Time:
+        return key.UTC().Format(time.RFC3339Nano)
+    case int64:
+        return strconv.FormatInt(key, 10)
+    case float64:
+        return strconv.FormatFloat(key, 'g', -1, 64)
+    }
+    return ""
+}
+
+// parseKey reads a key printed by formatKey back as the type of like.
+func parseKey(like interface{}, value string) (interface{}, error) {
+    var key interface{}
+    var err error
+    switch like.(type) {
+    case time.Time:
+        key, err = time.Parse(time.RFC3339Nano, value)
+    case int64:
+        key, err = strconv.ParseInt(value, 10, 64)
+    case float64:
+        var f float64
+        f, err = strconv.ParseFloat(value, 64)
+        if math.IsNaN(f) {
+            err = ErrInvalidCursor
+        }
+        key = f
+    }
+    if err != nil {
+        return nil, ErrInvalidCursor
+    }
+    return key, nil
+}
+
+// listMemoryTrips sorts the trips and returns the page after cursor, like
+// the PostgreSQL store's listTrips.
+func listMemoryTrips(trips []models.Trip, order memoryOrder, cursor string, limit int) (*models.TripPage, error) {
+    // before reports whether a sorts before b
+    before := func(aKey interface{}, aID int, bKey interface{}, bID int) bool {
+        c := compareKeys(aKey, bKey)
+        if c == 0 {
+            c = aID - bID
+        }
+        if order.desc {
+            return c > 0
+        }
+        return c < 0
+    }
+    
+    sort.Slice(trips, func(i, j int) bool {
+        return before(order.key(&trips[i]), trips[i].ID, order.key(&trips[j]), trips[j].ID)
+    })
+    
+    result := &models.TripPage{Trips: []models.Trip{}, Total: len(trips)}
+    
+    if cursor != "" {
+        decoded, err := decodeCursor(cursor)
+        if err != nil {
+            return nil, err
+        }
+        
+        key, err := parseKey(order.key(&models.Trip{}), decoded.Value)
+        if err != nil {
+            return nil, err
+        }
+        
+        start := len(trips)
+        for i := range trips {
+            if before(key, decoded.ID, order.key(&trips[i]), trips[i].ID) {
+                start = i
+                break
+            }
+        }
+        trips = trips[start:]
+    }
+    
+    size := pageSize(limit, defaultTripPageSize, maxTripPageSize)
+    if len(trips) > size {
+        trips = trips[:size]
+        result.HasMore = true
+    }
+    result.Trips = append(result.Trips, trips...)
+    
+    if result.HasMore {
+        last := result.Trips[len(result.Trips)-1]
+        next := encodeCursor(pageCursor{Value: formatKey(order.key(&last)), ID: last.ID})
+        result.NextCursor = &next
+    }
+    
+    return result, nil
+}
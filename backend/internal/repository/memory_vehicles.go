@@ -0,0 +1,132 @@
+package repository
+
+import (
+    "context"
+    "rideshare-backend/internal/models"
+    "sort"
+    "time"
+)
+
+type memoryVehicles struct {
+    s *MemoryStore
+}
+
+// copyVehicle returns a copy of the vehicle sharing nothing with it.
+func copyVehicle(vehicle models.Vehicle) models.Vehicle {
+    vehicle.Amenities = copyStrings(vehicle.Amenities)
+    if vehicle.Amenities == nil {
+        vehicle.Amenities = []string{}
+    }
+    if vehicle.LitresPer100Km != nil {
+        litres := *vehicle.LitresPer100Km
+        vehicle.LitresPer100Km = &litres
+    }
+    return vehicle
+}
+
+func (r memoryVehicles) Create(ctx context.Context, vehicle *models.Vehicle) error {
+    defer r.s.lock()()
+    
+    now := time.Now()
+    vehicle.ID = r.s.data.nextID("vehicles")
+    vehicle.CreatedAt = now
+    vehicle.UpdatedAt = now
+    r.s.data.vehicles[vehicle.ID] = copyVehicle(*vehicle)
+    return nil
+}
+
+func (r memoryVehicles) ListByOwner(ctx context.Context, ownerID int) ([]models.Vehicle, error) {
+    defer r.s.lock()()
+    
+    vehicles := []models.Vehicle{}
+    for _, vehicle := range r.s.data.vehicles {
+        if vehicle.OwnerID == ownerID {
+            vehicles = append(vehicles, copyVehicle(vehicle))
+        }
+    }
+    
+    sort.Slice(vehicles, func(i, j int) bool {
+        if !vehicles[i].CreatedAt.Equal(vehicles[j].CreatedAt) {
+            return vehicles[i].CreatedAt.Before(vehicles[j].CreatedAt)
+        }
+        return vehicles[i].ID < vehicles[j].ID
+    })
+    
+    return vehicles, nil
+}
+
+func (r memoryVehicles) Get(ctx context.Context, id, ownerID int) (*models.Vehicle, error) {
+    defer r.s.lock()()
+    
+    vehicle, ok := r.s.data.vehicles[id]
+    if !ok || vehicle.OwnerID != ownerID {
+        return nil, ErrNotFound
+    }
+    
+    found := copyVehicle(vehicle)
+    return &found, nil
+}
+
+func (r memoryVehicles) GetForTrip(ctx context.Context, tripID int) (*models.Vehicle, error) {
+    defer r.s.lock()()
+    
+    trip, ok := r.s.data.trips[tripID]
+    if !ok || trip.VehicleID == nil {
+        return nil, ErrNotFound
+    }
+    
+    vehicle, ok := r.s.data.vehicles[*trip.VehicleID]
+    if !ok {
+        return nil, ErrNotFound
+    }
+    
+    found := copyVehicle(vehicle)
+    return &found, nil
+}
+
+func (r memoryVehicles) Update(ctx context.Context, vehicle *models.Vehicle) error {
+    defer r.s.lock()()
+    
+    stored, ok := r.s.data.vehicles[vehicle.ID]
+    if !ok || stored.OwnerID != vehicle.OwnerID {
+        return ErrNotFound
+    }
+    
+    vehicle.CreatedAt = stored.CreatedAt
+    vehicle.UpdatedAt = time.Now()
+    r.s.data.vehicles[vehicle.ID] = copyVehicle(*vehicle)
+    return nil
+}
+
+func (r memoryVehicles) Delete(ctx context.Context, id, ownerID int) error {
+    defer r.s.lock()()
+    
+    vehicle, ok := r.s.data.vehicles[id]
+    if !ok || vehicle.OwnerID != ownerID || r.s.data.upcomingSeats(id) > 0 {
+        return ErrNotFound
+    }
+    
+    delete(r.s.data.vehicles, id)
+    return nil
+}
+
+func (r memoryVehicles) UpcomingSeats(ctx context.Context, id int) (int, error) {
+    defer r.s.lock()()
+    return r.s.data.upcomingSeats(id), nil
+}
+
+func (d *memoryData) upcomingSeats(vehicleID int) int {
+    seats := 0
+    for _, trip := range d.trips {
+        if trip.VehicleID == nil || *trip.VehicleID != vehicleID {
+            continue
+        }
+        if trip.Status != "active" && trip.Status != "in_progress" {
+            continue
+        }
+        if trip.MaxPassengers > seats {
+            seats = trip.MaxPassengers
+        }
+    }
+    return seats
+}
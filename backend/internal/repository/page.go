@@ -0,0 +1,50 @@
+package repository
+
+import (
+    "encoding/base64"
+    "encoding/json"
+)
+
+const (
+    defaultTripPageSize  = 20
+    maxTripPageSize      = 100
+    defaultAuditPageSize = 50
+    maxAuditPageSize     = 200
+)
+
+// pageCursor marks the last row of a page. Value is the sort key of that row
+// as the store printed it, so it compares back without loss; ties are broken
+// on the ID so a cursor always points at a single row.
+type pageCursor struct {
+    Value string `json:"v"`
+    ID    int    `json:"id"`
+}
+
+func encodeCursor(cursor pageCursor) string {
+    raw, _ := json.Marshal(cursor)
+    return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(encoded string) (*pageCursor, error) {
+    raw, err := base64.RawURLEncoding.DecodeString(encoded)
+    if err != nil {
+        return nil, ErrInvalidCursor
+    }
+
+    cursor := &pageCursor{}
+    if err := json.Unmarshal(raw, cursor); err != nil || cursor.ID <= 0 {
+        return nil, ErrInvalidCursor
+    }
+
+    return cursor, nil
+}
+
+func pageSize(limit, defaultSize, maxSize int) int {
+    if limit <= 0 {
+        return defaultSize
+    }
+    if limit > maxSize {
+        return maxSize
+    }
+    return limit
+}
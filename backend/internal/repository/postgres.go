@@ -0,0 +1,154 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/money"
+    "rideshare-backend/internal/utils"
+)
+
+// querier runs queries on the database or inside a transaction.
+type querier interface {
+    ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+    QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+    QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// PostgresStore keeps everything in PostgreSQL.
+type PostgresStore struct {
+    db *sql.DB
+    q  querier
+    tx *sql.Tx
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+    return &PostgresStore{db: db, q: db}
+}
+
+func (s *PostgresStore) Users() UserRepository { return postgresUsers{s.q} }
+func (s *PostgresStore) Trips() TripRepository { return postgresTrips{s.q} }
+func (s *PostgresStore) Bookings() BookingRepository { return postgresBookings{s.q} }
+func (s *PostgresStore) Audit() AuditRepository { return postgresAudit{s.q} }
+func (s *PostgresStore) Vehicles() VehicleRepository { return postgresVehicles{s.q} }
+func (s *PostgresStore) Messages() MessageRepository { return postgresMessages{s.q} }
+func (s *PostgresStore) Locations() LocationRepository { return postgresLocations{s.q} }
+func (s *PostgresStore) Payments() PaymentRepository { return postgresPayments{s.q} }
+
+func (s *PostgresStore) InTx(ctx context.Context, fn func(tx Store) error) error {
+    if s.tx != nil {
+        return fn(s)
+    }
+    
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback()
+    
+    if err := fn(&PostgresStore{db: s.db, q: tx, tx: tx}); err != nil {
+        return err
+    }
+    
+    return tx.Commit()
+}
+
+// tripColumns lists the trip fields loaded by every query returning trips, in
+// the order scanTrip expects them. Queries select them from trips aliased t.
+const tripColumns = `
+    t.id, t.driver_id, t.from_location, t.to_location, t.departure_time, t.timezone,
+    t.max_passengers, t.current_passengers, t.held_seats, t.price_per_person_minor, t.currency,
+    t.fare_mode, t.total_cost_minor, t.vehicle_id, t.pets_allowed, t.smoking_allowed,
+    t.luggage_size, t.music, t.women_only, t.instant_booking, t.description, t.status,
+    t.created_at, t.updated_at`
+
+type rowScanner interface {
+    Scan(dest ...interface{}) error
+}
+
+// scanTrip scans tripColumns into trip, followed by any extra columns the
+// query selected after them.
+func scanTrip(row rowScanner, trip *models.Trip, extra ...interface{}) error {
+    var totalCost sql.NullInt64
+    
+    dest := []interface{}{
+        &trip.ID,
+        &trip.DriverID,
+        &trip.FromLocation,
+        &trip.ToLocation,
+        &trip.DepartureTime,
+        &trip.Timezone,
+        &trip.MaxPassengers,
+        &trip.CurrentPassengers,
+        &trip.HeldSeats,
+        &trip.PricePerPerson.Amount,
+        &trip.PricePerPerson.Currency,
+        &trip.FareMode,
+        &totalCost,
+        &trip.VehicleID,
+        &trip.Preferences.PetsAllowed,
+        &trip.Preferences.SmokingAllowed,
+        &trip.Preferences.LuggageSize,
+        &trip.Preferences.Music,
+        &trip.Preferences.WomenOnly,
+        &trip.Preferences.InstantBooking,
+        &trip.Description,
+        &trip.Status,
+        &trip.CreatedAt,
+        &trip.UpdatedAt,
+    }
+    
+    if err := row.Scan(append(dest, extra...)...); err != nil {
+        return err
+    }
+    
+    // Show departure in the local time of the trip's origin
+    trip.DepartureTime = trip.DepartureTime.In(utils.LoadLocation(trip.Timezone))
+    
+    trip.TotalCost = nil
+    if totalCost.Valid {
+        total := money.New(money.Amount(totalCost.Int64), trip.PricePerPerson.Currency)
+        trip.TotalCost = &total
+    }
+    
+    return nil
+}
+
+// nullableDriver holds the driver columns of a LEFT JOIN on users.
+type nullableDriver struct {
+    id                 sql.NullInt64
+    name, email, phone sql.NullString
+}
+
+func (d *nullableDriver) user() *models.User {
+    if !d.id.Valid {
+        return nil
+    }
+    return &models.User{
+        ID:    int(d.id.Int64),
+        Name:  d.name.String,
+        Email: d.email.String,
+        Phone: d.phone.String,
+    }
+}
+
+// execOne runs a statement meant to change one row, returning ErrNotFound if
+// it changed none.
+func execOne(ctx context.Context, q querier, query string, args ...interface{}) error {
+    result, err := q.ExecContext(ctx, query, args...)
+    if err != nil {
+        return err
+    }
+    
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return fmt.Errorf("failed to get affected rows: %w", err)
+    }
+    
+    if rowsAffected == 0 {
+        return ErrNotFound
+    }
+    
+    return nil
+}
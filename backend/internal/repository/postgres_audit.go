@@ -0,0 +1,118 @@
+package repository
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "rideshare-backend/internal/models"
+    "strings"
+)
+
+type postgresAudit struct {
+    q querier
+}
+
+func (r postgresAudit) Record(ctx context.Context, event models.AuditEvent, req models.RequestInfo) error {
+    if event.Changes == nil {
+        event.Changes = map[string]models.FieldChange{}
+    }
+    
+    raw, err := json.Marshal(event.Changes)
+    if err != nil {
+        return fmt.Errorf("failed to encode audit changes: %w", err)
+    }
+    
+    _, err = r.q.ExecContext(ctx, `
+        INSERT INTO audit_events (entity_type, entity_id, action, actor_id, changes, request_id, client_ip, created_at)
+        VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NOW())
+    `, event.EntityType, event.EntityID, event.Action, event.ActorID, raw, req.RequestID, req.ClientIP)
+    if err != nil {
+        return fmt.Errorf("failed to record audit event: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresAudit) List(ctx context.Context, filter models.AuditFilter) (*models.AuditPage, error) {
+    var conditions []string
+    var args []interface{}
+    
+    if filter.EntityType != "" {
+        args = append(args, filter.EntityType, filter.EntityID)
+        conditions = append(conditions, fmt.Sprintf("entity_type = $%d AND entity_id = $%d", len(args)-1, len(args)))
+    }
+    if filter.UserID != 0 {
+        args = append(args, filter.UserID)
+        conditions = append(conditions, fmt.Sprintf("(actor_id = $%d OR (entity_type = 'user' AND entity_id = $%d))", len(args), len(args)))
+    }
+    
+    if filter.Cursor != "" {
+        cursor, err := decodeCursor(filter.Cursor)
+        if err != nil {
+            return nil, err
+        }
+        args = append(args, cursor.ID)
+        conditions = append(conditions, fmt.Sprintf("id < $%d", len(args)))
+    }
+    
+    limit := pageSize(filter.Limit, defaultAuditPageSize, maxAuditPageSize)
+    
+    query := `
+        SELECT id, entity_type, entity_id, action, actor_id, changes, request_id, client_ip, created_at
+        FROM audit_events
+    `
+    if len(conditions) > 0 {
+        query += " WHERE " + strings.Join(conditions, " AND ")
+    }
+    query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args)+1)
+    
+    // Fetch one extra row to know whether another page exists
+    rows, err := r.q.QueryContext(ctx, query, append(args, limit+1)...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list audit events: %w", err)
+    }
+    defer rows.Close()
+    
+    page := &models.AuditPage{Events: []models.AuditEvent{}}
+    for rows.Next() {
+        if len(page.Events) == limit {
+            page.HasMore = true
+            break
+        }
+        
+        var event models.AuditEvent
+        var raw []byte
+        err := rows.Scan(
+            &event.ID,
+            &event.EntityType,
+            &event.EntityID,
+            &event.Action,
+            &event.ActorID,
+            &raw,
+            &event.RequestID,
+            &event.ClientIP,
+            &event.CreatedAt,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan audit event: %w", err)
+        }
+        
+        if err := json.Unmarshal(raw, &event.Changes); err != nil {
+            return nil, fmt.Errorf("failed to decode audit changes: %w", err)
+        }
+        
+        page.Events = append(page.Events, event)
+    }
+    
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to list audit events: %w", err)
+    }
+    
+    if page.HasMore {
+        last := page.Events[len(page.Events)-1]
+        next := encodeCursor(pageCursor{ID: int(last.ID)})
+        page.NextCursor = &next
+    }
+    
+    return page, nil
+}
@@ -0,0 +1,410 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/money"
+    "time"
+
+    "github.com/lib/pq"
+)
+
+type postgresBookings struct {
+    q querier
+}
+
+func (r postgresBookings) Get(ctx context.Context, tripID, passengerID int) (*models.TripPassenger, error) {
+    booking := &models.TripPassenger{TripID: tripID, PassengerID: passengerID}
+    err := r.q.QueryRowContext(ctx,
+        "SELECT id, status, seats, guest_names, joined_at, removed_at, fare_minor, fare_seats FROM trip_passengers WHERE trip_id = $1 AND passenger_id = $2",
+        tripID, passengerID,
+    ).Scan(&booking.ID, &booking.Status, &booking.Seats, (*pq.StringArray)(&booking.Guests), &booking.JoinedAt, &booking.RemovedAt, &booking.Fare, &booking.FareSeats)
+    
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, ErrNotFound
+        }
+        return nil, fmt.Errorf("failed to get booking: %w", err)
+    }
+    
+    return booking, nil
+}
+
+func (r postgresBookings) Create(ctx context.Context, booking *models.TripPassenger) error {
+    guests := booking.Guests
+    if guests == nil {
+        guests = []string{}
+    }
+    
+    err := r.q.QueryRowContext(ctx, `
+        INSERT INTO trip_passengers (trip_id, passenger_id, status, seats, guest_names, joined_at) VALUES ($1, $2, $3, $4, $5, NOW())
+        ON CONFLICT (trip_id, passenger_id) DO UPDATE
+        SET status = EXCLUDED.status, seats = EXCLUDED.seats, guest_names = EXCLUDED.guest_names, joined_at = NOW(), fare_minor = 0, fare_seats = 0
+        RETURNING id, joined_at
+    `, booking.TripID, booking.PassengerID, booking.Status, booking.Seats, pq.Array(guests)).Scan(&booking.ID, &booking.JoinedAt)
+    if err != nil {
+        return fmt.Errorf("failed to join trip: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresBookings) SetStatus(ctx context.Context, tripID, passengerID int, status string) error {
+    err := execOne(ctx, r.q,
+        "UPDATE trip_passengers SET status = $3 WHERE trip_id = $1 AND passenger_id = $2",
+        tripID, passengerID, status,
+    )
+    if err != nil && err != ErrNotFound {
+        return fmt.Errorf("failed to update booking: %w", err)
+    }
+    
+    return err
+}
+
+func (r postgresBookings) SetSeats(ctx context.Context, tripID, passengerID, seats int, guests []string) error {
+    if guests == nil {
+        guests = []string{}
+    }
+    
+    err := execOne(ctx, r.q,
+        "UPDATE trip_passengers SET seats = $3, guest_names = $4 WHERE trip_id = $1 AND passenger_id = $2",
+        tripID, passengerID, seats, pq.Array(guests),
+    )
+    if err != nil && err != ErrNotFound {
+        return fmt.Errorf("failed to update booking: %w", err)
+    }
+    
+    return err
+}
+
+func (r postgresBookings) SetFare(ctx context.Context, tripID, passengerID int, fare money.Amount, seats int) error {
+    err := execOne(ctx, r.q,
+        "UPDATE trip_passengers SET fare_minor = $3, fare_seats = $4 WHERE trip_id = $1 AND passenger_id = $2",
+        tripID, passengerID, fare, seats,
+    )
+    if err != nil && err != ErrNotFound {
+        return fmt.Errorf("failed to update booking fare: %w", err)
+    }
+    
+    return err
+}
+
+func (r postgresBookings) Remove(ctx context.Context, tripID, passengerID int, reason string) error {
+    err := execOne(ctx, r.q,
+        "UPDATE trip_passengers SET status = 'cancelled', removed_at = NOW(), removal_reason = $3 WHERE trip_id = $1 AND passenger_id = $2",
+        tripID, passengerID, reason,
+    )
+    if err != nil && err != ErrNotFound {
+        return fmt.Errorf("failed to remove passenger: %w", err)
+    }
+    
+    return err
+}
+
+func (r postgresBookings) Manifest(ctx context.Context, tripID int) ([]models.TripPassenger, error) {
+    rows, err := r.q.QueryContext(ctx, `
+        SELECT tp.id, tp.status, tp.seats, tp.guest_names, tp.joined_at, u.id, u.name, u.email, u.phone, u.profile_image
+        FROM trip_passengers tp
+        JOIN users u ON u.id = tp.passenger_id
+        WHERE tp.trip_id = $1 AND tp.status <> 'cancelled'
+        ORDER BY tp.joined_at, tp.id
+    `, tripID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get manifest: %w", err)
+    }
+    defer rows.Close()
+    
+    manifest := []models.TripPassenger{}
+    for rows.Next() {
+        passenger := models.TripPassenger{TripID: tripID, Passenger: &models.User{}}
+        err := rows.Scan(
+            &passenger.ID,
+            &passenger.Status,
+            &passenger.Seats,
+            (*pq.StringArray)(&passenger.Guests),
+            &passenger.JoinedAt,
+            &passenger.Passenger.ID,
+            &passenger.Passenger.Name,
+            &passenger.Passenger.Email,
+            &passenger.Passenger.Phone,
+            &passenger.Passenger.ProfileImage,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan passenger: %w", err)
+        }
+        
+        passenger.PassengerID = passenger.Passenger.ID
+        manifest = append(manifest, passenger)
+    }
+    
+    return manifest, rows.Err()
+}
+
+func (r postgresBookings) CoPassengers(ctx context.Context, tripIDs []int, userID int) ([]models.TripPassenger, error) {
+    ids := make([]int64, len(tripIDs))
+    for i, id := range tripIDs {
+        ids[i] = int64(id)
+    }
+    
+    rows, err := r.q.QueryContext(ctx, `
+        SELECT tp.trip_id, u.id, u.name, u.profile_image
+        FROM trip_passengers tp
+        JOIN users u ON u.id = tp.passenger_id
+        WHERE tp.trip_id = ANY($1) AND tp.status = 'confirmed' AND tp.passenger_id <> $2
+        ORDER BY tp.joined_at
+    `, pq.Array(ids), userID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get co-passengers: %w", err)
+    }
+    defer rows.Close()
+    
+    var passengers []models.TripPassenger
+    for rows.Next() {
+        var tripID int
+        user := &models.User{}
+        if err := rows.Scan(&tripID, &user.ID, &user.Name, &user.ProfileImage); err != nil {
+            return nil, fmt.Errorf("failed to scan co-passenger: %w", err)
+        }
+        
+        passengers = append(passengers, models.TripPassenger{TripID: tripID, PassengerID: user.ID, Passenger: user})
+    }
+    
+    return passengers, rows.Err()
+}
+
+func (r postgresBookings) GetHold(ctx context.Context, tripID, passengerID int) (*models.SeatHold, error) {
+    hold := &models.SeatHold{}
+    err := r.q.QueryRowContext(ctx,
+        "SELECT id, trip_id, passenger_id, seats, expires_at, created_at FROM seat_holds WHERE trip_id = $1 AND passenger_id = $2",
+        tripID, passengerID,
+    ).Scan(&hold.ID, &hold.TripID, &hold.PassengerID, &hold.Seats, &hold.ExpiresAt, &hold.CreatedAt)
+    
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, ErrNotFound
+        }
+        return nil, fmt.Errorf("failed to get seat hold: %w", err)
+    }
+    
+    return hold, nil
+}
+
+func (r postgresBookings) CreateHold(ctx context.Context, hold *models.SeatHold, ttl time.Duration) error {
+    _, err := r.q.ExecContext(ctx,
+        "UPDATE trips SET held_seats = held_seats + $2 WHERE id = $1",
+        hold.TripID, hold.Seats,
+    )
+    if err != nil {
+        return fmt.Errorf("failed to hold seats: %w", err)
+    }
+    
+    err = r.q.QueryRowContext(ctx, `
+        INSERT INTO seat_holds (trip_id, passenger_id, seats, expires_at, created_at)
+        VALUES ($1, $2, $3, NOW() + make_interval(secs => $4), NOW())
+        RETURNING id, expires_at, created_at
+    `, hold.TripID, hold.PassengerID, hold.Seats, ttl.Seconds()).Scan(&hold.ID, &hold.ExpiresAt, &hold.CreatedAt)
+    if err != nil {
+        return fmt.Errorf("failed to hold seats: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresBookings) ReleaseHolds(ctx context.Context, tripID, passengerID int) (int, error) {
+    holds, err := r.releaseHolds(ctx, tripID, "passenger_id = $2 AND expires_at > NOW()", passengerID)
+    return heldSeats(holds), err
+}
+
+func (r postgresBookings) ReleaseExpiredHolds(ctx context.Context, tripID int) ([]models.SeatHold, error) {
+    return r.releaseHolds(ctx, tripID, "expires_at <= NOW()")
+}
+
+// releaseHolds deletes the holds on a trip matching condition, whose
+// placeholders are numbered from $2, and gives their seats back.
+func (r postgresBookings) releaseHolds(ctx context.Context, tripID int, condition string, args ...interface{}) ([]models.SeatHold, error) {
+    rows, err := r.q.QueryContext(ctx, `
+        DELETE FROM seat_holds WHERE trip_id = $1 AND `+condition+`
+        RETURNING id, trip_id, passenger_id, seats, expires_at, created_at
+    `, append([]interface{}{tripID}, args...)...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to release held seats: %w", err)
+    }
+    defer rows.Close()
+    
+    var holds []models.SeatHold
+    for rows.Next() {
+        var hold models.SeatHold
+        if err := rows.Scan(&hold.ID, &hold.TripID, &hold.PassengerID, &hold.Seats, &hold.ExpiresAt, &hold.CreatedAt); err != nil {
+            return nil, fmt.Errorf("failed to scan seat hold: %w", err)
+        }
+        holds = append(holds, hold)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to release held seats: %w", err)
+    }
+    rows.Close()
+    
+    seats := heldSeats(holds)
+    if seats == 0 {
+        return holds, nil
+    }
+    
+    _, err = r.q.ExecContext(ctx,
+        "UPDATE trips SET held_seats = held_seats - $2 WHERE id = $1",
+        tripID, seats,
+    )
+    if err != nil {
+        return nil, fmt.Errorf("failed to release held seats: %w", err)
+    }
+    
+    return holds, nil
+}
+
+func (r postgresBookings) TripsWithExpiredHolds(ctx context.Context) ([]int, error) {
+    rows, err := r.q.QueryContext(ctx, "SELECT DISTINCT trip_id FROM seat_holds WHERE expires_at <= NOW()")
+    if err != nil {
+        return nil, fmt.Errorf("failed to find expired holds: %w", err)
+    }
+    defer rows.Close()
+    
+    var tripIDs []int
+    for rows.Next() {
+        var id int
+        if err := rows.Scan(&id); err != nil {
+            return nil, fmt.Errorf("failed to scan trip: %w", err)
+        }
+        tripIDs = append(tripIDs, id)
+    }
+    
+    return tripIDs, rows.Err()
+}
+
+func (r postgresBookings) AddToWaitlist(ctx context.Context, entry *models.WaitlistEntry) error {
+    guests := entry.Guests
+    if guests == nil {
+        guests = []string{}
+    }
+    
+    err := r.q.QueryRowContext(ctx, `
+        INSERT INTO trip_waitlist (trip_id, passenger_id, seats, guest_names, status, created_at)
+        VALUES ($1, $2, $3, $4, 'waiting', NOW())
+        ON CONFLICT (trip_id, passenger_id) DO UPDATE
+        SET seats = EXCLUDED.seats, guest_names = EXCLUDED.guest_names, status = 'waiting',
+            offered_at = NULL, offer_expires_at = NULL, created_at = NOW()
+        WHERE trip_waitlist.status NOT IN ('waiting', 'offered')
+        RETURNING id, created_at
+    `, entry.TripID, entry.PassengerID, entry.Seats, pq.Array(guests)).Scan(&entry.ID, &entry.CreatedAt)
+    
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return ErrDuplicate
+        }
+        return fmt.Errorf("failed to join waitlist: %w", err)
+    }
+    entry.Status = models.WaitlistWaiting
+    
+    err = r.q.QueryRowContext(ctx, `
+        SELECT COUNT(*) FROM trip_waitlist
+        WHERE trip_id = $1 AND status = 'waiting' AND (created_at, id) <= ($2, $3)
+    `, entry.TripID, entry.CreatedAt, entry.ID).Scan(&entry.Position)
+    if err != nil {
+        return fmt.Errorf("failed to get waitlist position: %w", err)
+    }
+    
+    return nil
+}
+
+// waitlistColumns lists the waitlist fields scanned by scanWaitlistEntry.
+const waitlistColumns = "id, trip_id, passenger_id, seats, guest_names, status, offer_expires_at, created_at"
+
+func scanWaitlistEntry(row rowScanner, entry *models.WaitlistEntry) error {
+    return row.Scan(
+        &entry.ID,
+        &entry.TripID,
+        &entry.PassengerID,
+        &entry.Seats,
+        (*pq.StringArray)(&entry.Guests),
+        &entry.Status,
+        &entry.OfferExpiresAt,
+        &entry.CreatedAt,
+    )
+}
+
+func (r postgresBookings) GetWaitlistEntry(ctx context.Context, tripID, passengerID int) (*models.WaitlistEntry, error) {
+    entry := &models.WaitlistEntry{}
+    err := scanWaitlistEntry(r.q.QueryRowContext(ctx,
+        "SELECT "+waitlistColumns+" FROM trip_waitlist WHERE trip_id = $1 AND passenger_id = $2 AND status IN ('waiting', 'offered')",
+        tripID, passengerID,
+    ), entry)
+    
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, ErrNotFound
+        }
+        return nil, fmt.Errorf("failed to get waitlist entry: %w", err)
+    }
+    
+    return entry, nil
+}
+
+func (r postgresBookings) Waiting(ctx context.Context, tripID int) ([]models.WaitlistEntry, error) {
+    rows, err := r.q.QueryContext(ctx,
+        "SELECT "+waitlistColumns+" FROM trip_waitlist WHERE trip_id = $1 AND status = 'waiting' ORDER BY created_at, id",
+        tripID,
+    )
+    if err != nil {
+        return nil, fmt.Errorf("failed to get waitlist: %w", err)
+    }
+    defer rows.Close()
+    
+    var entries []models.WaitlistEntry
+    for rows.Next() {
+        var entry models.WaitlistEntry
+        if err := scanWaitlistEntry(rows, &entry); err != nil {
+            return nil, fmt.Errorf("failed to scan waitlist entry: %w", err)
+        }
+        entries = append(entries, entry)
+    }
+    
+    return entries, rows.Err()
+}
+
+// SetWaitlistStatus moves the passenger's open entry, waiting or offered
+// seats, to status.
+func (r postgresBookings) SetWaitlistStatus(ctx context.Context, tripID, passengerID int, status string) error {
+    err := execOne(ctx, r.q,
+        "UPDATE trip_waitlist SET status = $3 WHERE trip_id = $1 AND passenger_id = $2 AND status IN ('waiting', 'offered')",
+        tripID, passengerID, status,
+    )
+    if err != nil && err != ErrNotFound {
+        return fmt.Errorf("failed to update waitlist: %w", err)
+    }
+    
+    return err
+}
+
+func (r postgresBookings) OfferWaitlist(ctx context.Context, tripID, passengerID int, expiresAt time.Time) error {
+    err := execOne(ctx, r.q,
+        "UPDATE trip_waitlist SET status = 'offered', offered_at = NOW(), offer_expires_at = $3 WHERE trip_id = $1 AND passenger_id = $2 AND status = 'waiting'",
+        tripID, passengerID, expiresAt,
+    )
+    if err != nil && err != ErrNotFound {
+        return fmt.Errorf("failed to offer seats: %w", err)
+    }
+    
+    return err
+}
+
+func (r postgresBookings) ExpireWaitlistOffers(ctx context.Context, tripID int) error {
+    _, err := r.q.ExecContext(ctx,
+        "UPDATE trip_waitlist SET status = 'expired' WHERE trip_id = $1 AND status = 'offered' AND offer_expires_at <= NOW()",
+        tripID,
+    )
+    if err != nil {
+        return fmt.Errorf("failed to expire waitlist offers: %w", err)
+    }
+    
+    return nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "rideshare-backend/internal/models"
+    "time"
+)
+
+type postgresLocations struct {
+    q querier
+}
+
+func (r postgresLocations) Record(ctx context.Context, location *models.TripLocation, driverID int) error {
+    query := `
+        INSERT INTO trip_locations (trip_id, latitude, longitude, heading, speed_kmh, recorded_at)
+        SELECT id, $3, $4, $5, $6, NOW()
+        FROM trips
+        WHERE id = $1 AND driver_id = $2 AND status = 'in_progress'
+        RETURNING id, recorded_at
+    `
+    
+    err := r.q.QueryRowContext(ctx,
+        query,
+        location.TripID,
+        driverID,
+        location.Latitude,
+        location.Longitude,
+        location.Heading,
+        location.SpeedKmh,
+    ).Scan(&location.ID, &location.RecordedAt)
+    
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return ErrNotFound
+        }
+        return fmt.Errorf("failed to record location: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresLocations) Latest(ctx context.Context, tripID int, since time.Time) (*models.TripLocation, error) {
+    location := &models.TripLocation{}
+    query := `
+        SELECT id, trip_id, latitude, longitude, heading, speed_kmh, recorded_at
+        FROM trip_locations
+        WHERE trip_id = $1 AND recorded_at > $2
+        ORDER BY recorded_at DESC
+        LIMIT 1
+    `
+    
+    err := r.q.QueryRowContext(ctx, query, tripID, since).Scan(
+        &location.ID,
+        &location.TripID,
+        &location.Latitude,
+        &location.Longitude,
+        &location.Heading,
+        &location.SpeedKmh,
+        &location.RecordedAt,
+    )
+    
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, ErrNotFound
+        }
+        return nil, fmt.Errorf("failed to get latest location: %w", err)
+    }
+    
+    return location, nil
+}
+
+func (r postgresLocations) SetPickup(ctx context.Context, tripID, passengerID int, lat, lon float64) error {
+    err := execOne(ctx, r.q,
+        "UPDATE trip_passengers SET pickup_latitude = $1, pickup_longitude = $2 WHERE trip_id = $3 AND passenger_id = $4 AND status = 'confirmed'",
+        lat, lon, tripID, passengerID,
+    )
+    if err != nil && err != ErrNotFound {
+        return fmt.Errorf("failed to set pickup point: %w", err)
+    }
+    
+    return err
+}
+
+func (r postgresLocations) Pickups(ctx context.Context, tripID int) ([]models.PickupPoint, error) {
+    rows, err := r.q.QueryContext(ctx, `
+        SELECT passenger_id, pickup_latitude, pickup_longitude
+        FROM trip_passengers
+        WHERE trip_id = $1 AND status = 'confirmed'
+        AND pickup_latitude IS NOT NULL AND pickup_longitude IS NOT NULL
+    `, tripID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get pickup points: %w", err)
+    }
+    defer rows.Close()
+    
+    var pickups []models.PickupPoint
+    for rows.Next() {
+        var pickup models.PickupPoint
+        if err := rows.Scan(&pickup.PassengerID, &pickup.Latitude, &pickup.Longitude); err != nil {
+            return nil, fmt.Errorf("failed to scan pickup point: %w", err)
+        }
+        pickups = append(pickups, pickup)
+    }
+    
+    return pickups, rows.Err()
+}
+
+func (r postgresLocations) PurgeBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+    result, err := r.q.ExecContext(ctx, "DELETE FROM trip_locations WHERE recorded_at < $1", cutoff)
+    if err != nil {
+        return 0, fmt.Errorf("failed to purge locations: %w", err)
+    }
+    
+    return result.RowsAffected()
+}
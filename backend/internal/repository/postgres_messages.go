@@ -0,0 +1,186 @@
+package repository
+
+import (
+    "context"
+    "fmt"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/utils"
+    "time"
+)
+
+type postgresMessages struct {
+    q querier
+}
+
+func (r postgresMessages) Create(ctx context.Context, message *models.TripMessage) error {
+    err := r.q.QueryRowContext(ctx,
+        "INSERT INTO trip_messages (trip_id, sender_id, body, created_at) VALUES ($1, $2, $3, NOW()) RETURNING id, created_at",
+        message.TripID, message.SenderID, message.Body,
+    ).Scan(&message.ID, &message.CreatedAt)
+    if err != nil {
+        return fmt.Errorf("failed to post message: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresMessages) List(ctx context.Context, tripID, beforeID, limit int) ([]models.TripMessage, error) {
+    query := `
+        SELECT m.id, m.trip_id, m.sender_id, m.body, m.created_at,
+               u.id, u.name, u.profile_image
+        FROM trip_messages m
+        JOIN users u ON m.sender_id = u.id
+        WHERE m.trip_id = $1 AND ($2 = 0 OR m.id < $2)
+        ORDER BY m.id DESC
+        LIMIT $3
+    `
+    
+    rows, err := r.q.QueryContext(ctx, query, tripID, beforeID, limit)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get messages: %w", err)
+    }
+    defer rows.Close()
+    
+    messages := []models.TripMessage{}
+    for rows.Next() {
+        var message models.TripMessage
+        var sender models.User
+        
+        err := rows.Scan(
+            &message.ID,
+            &message.TripID,
+            &message.SenderID,
+            &message.Body,
+            &message.CreatedAt,
+            &sender.ID,
+            &sender.Name,
+            &sender.ProfileImage,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan message: %w", err)
+        }
+        
+        message.Sender = &sender
+        messages = append(messages, message)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to read messages: %w", err)
+    }
+    
+    return messages, nil
+}
+
+func (r postgresMessages) Receipts(ctx context.Context, tripID int) ([]models.MessageReceipt, error) {
+    rows, err := r.q.QueryContext(ctx,
+        "SELECT trip_id, user_id, last_read_message_id, read_at FROM trip_message_reads WHERE trip_id = $1 AND last_read_message_id > 0",
+        tripID,
+    )
+    if err != nil {
+        return nil, fmt.Errorf("failed to get read receipts: %w", err)
+    }
+    defer rows.Close()
+    
+    var receipts []models.MessageReceipt
+    for rows.Next() {
+        var receipt models.MessageReceipt
+        if err := rows.Scan(&receipt.TripID, &receipt.UserID, &receipt.LastReadMessageID, &receipt.ReadAt); err != nil {
+            return nil, fmt.Errorf("failed to scan read receipt: %w", err)
+        }
+        receipts = append(receipts, receipt)
+    }
+    
+    return receipts, rows.Err()
+}
+
+func (r postgresMessages) MarkRead(ctx context.Context, tripID, userID, messageID int) error {
+    // Only a message posted on the trip can be read, which also keeps the
+    // receipt from running ahead of the conversation
+    result, err := r.q.ExecContext(ctx, `
+        INSERT INTO trip_message_reads (trip_id, user_id, last_read_message_id, read_at)
+        SELECT trip_id, $2, id, NOW() FROM trip_messages WHERE id = $3 AND trip_id = $1
+        ON CONFLICT (trip_id, user_id) DO UPDATE
+        SET last_read_message_id = GREATEST(trip_message_reads.last_read_message_id, EXCLUDED.last_read_message_id),
+            read_at = NOW()
+    `, tripID, userID, messageID)
+    if err != nil {
+        return fmt.Errorf("failed to mark messages as read: %w", err)
+    }
+    
+    rows, err := result.RowsAffected()
+    if err != nil {
+        return fmt.Errorf("failed to mark messages as read: %w", err)
+    }
+    if rows == 0 {
+        return ErrNotFound
+    }
+    
+    return nil
+}
+
+func (r postgresMessages) MarkNotified(ctx context.Context, tripID, userID, messageID int) error {
+    _, err := r.q.ExecContext(ctx, `
+        INSERT INTO trip_message_reads (trip_id, user_id, last_notified_message_id)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (trip_id, user_id) DO UPDATE
+        SET last_notified_message_id = GREATEST(trip_message_reads.last_notified_message_id, EXCLUDED.last_notified_message_id)
+    `, tripID, userID, messageID)
+    if err != nil {
+        return fmt.Errorf("failed to mark messages as notified: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresMessages) UnreadDigests(ctx context.Context, postedBefore time.Time) ([]models.UnreadDigest, error) {
+    query := `
+        WITH participants AS (
+            SELECT id AS trip_id, driver_id AS user_id FROM trips WHERE status IN ('active', 'in_progress')
+            UNION
+            SELECT tp.trip_id, tp.passenger_id
+            FROM trip_passengers tp
+            JOIN trips t ON t.id = tp.trip_id
+            WHERE tp.status = 'confirmed' AND t.status IN ('active', 'in_progress')
+        )
+        SELECT p.trip_id, p.user_id, u.email, u.name, t.from_location, t.to_location,
+               t.departure_time, t.timezone, COUNT(m.id), MAX(m.id)
+        FROM participants p
+        JOIN users u ON u.id = p.user_id
+        JOIN trips t ON t.id = p.trip_id
+        JOIN trip_messages m ON m.trip_id = p.trip_id AND m.sender_id <> p.user_id
+        LEFT JOIN trip_message_reads r ON r.trip_id = p.trip_id AND r.user_id = p.user_id
+        WHERE m.id > GREATEST(COALESCE(r.last_read_message_id, 0), COALESCE(r.last_notified_message_id, 0))
+        AND m.created_at <= $1
+        GROUP BY p.trip_id, p.user_id, u.email, u.name, t.from_location, t.to_location, t.departure_time, t.timezone
+    `
+    
+    rows, err := r.q.QueryContext(ctx, query, postedBefore)
+    if err != nil {
+        return nil, fmt.Errorf("failed to find unread messages: %w", err)
+    }
+    defer rows.Close()
+    
+    var digests []models.UnreadDigest
+    for rows.Next() {
+        var digest models.UnreadDigest
+        var timezone string
+        err := rows.Scan(
+            &digest.TripID,
+            &digest.UserID,
+            &digest.Email,
+            &digest.Name,
+            &digest.FromLocation,
+            &digest.ToLocation,
+            &digest.DepartureTime,
+            &timezone,
+            &digest.UnreadCount,
+            &digest.LatestMessageID,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan unread digest: %w", err)
+        }
+        digest.DepartureTime = digest.DepartureTime.In(utils.LoadLocation(timezone))
+        digests = append(digests, digest)
+    }
+    
+    return digests, rows.Err()
+}
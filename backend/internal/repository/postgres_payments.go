@@ -0,0 +1,322 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "rideshare-backend/internal/models"
+    "time"
+)
+
+type postgresPayments struct {
+    q querier
+}
+
+func (r postgresPayments) Record(ctx context.Context, transaction *models.PaymentTransaction) error {
+    err := r.q.QueryRowContext(ctx, `
+        INSERT INTO payment_transactions (booking_id, trip_id, parent_id, kind, amount, currency, provider_reference, description, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+        RETURNING id, created_at
+    `,
+        transaction.BookingID,
+        transaction.TripID,
+        transaction.ParentID,
+        transaction.Kind,
+        transaction.Amount,
+        transaction.Currency,
+        transaction.ProviderReference,
+        transaction.Description,
+    ).Scan(&transaction.ID, &transaction.CreatedAt)
+    if err != nil {
+        return fmt.Errorf("failed to record payment transaction: %w", err)
+    }
+    
+    for i := range transaction.Entries {
+        entry := &transaction.Entries[i]
+        entry.TransactionID = transaction.ID
+        entry.Currency = transaction.Currency
+        
+        err := r.q.QueryRowContext(ctx, `
+            INSERT INTO ledger_entries (transaction_id, account, user_id, entry_type, amount, currency, created_at)
+            VALUES ($1, $2, $3, $4, $5, $6, NOW())
+            RETURNING id, created_at
+        `,
+            entry.TransactionID,
+            entry.Account,
+            entry.UserID,
+            entry.EntryType,
+            entry.Amount,
+            entry.Currency,
+        ).Scan(&entry.ID, &entry.CreatedAt)
+        if err != nil {
+            return fmt.Errorf("failed to record ledger entry: %w", err)
+        }
+    }
+    
+    return nil
+}
+
+func (r postgresPayments) ChargesForUpdate(ctx context.Context, bookingID int) ([]models.BookingCharge, error) {
+    rows, err := r.q.QueryContext(ctx, `
+        SELECT pt.id
+        FROM payment_transactions pt
+        JOIN trip_passengers tp ON tp.id = pt.booking_id
+        WHERE pt.booking_id = $1 AND pt.kind = 'charge' AND pt.created_at >= tp.joined_at
+        ORDER BY pt.created_at DESC, pt.id DESC
+        FOR UPDATE OF pt
+    `, bookingID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get charges: %w", err)
+    }
+    
+    var ids []int
+    for rows.Next() {
+        var id int
+        if err := rows.Scan(&id); err != nil {
+            rows.Close()
+            return nil, fmt.Errorf("failed to scan charge: %w", err)
+        }
+        ids = append(ids, id)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to get charges: %w", err)
+    }
+    
+    charges := make([]models.BookingCharge, 0, len(ids))
+    for _, id := range ids {
+        charge, err := r.GetCharge(ctx, id)
+        if err != nil {
+            return nil, err
+        }
+        charges = append(charges, *charge)
+    }
+    
+    return charges, nil
+}
+
+// GetCharge loads a charge with its ledger entries and what has been
+// refunded against it.
+func (r postgresPayments) GetCharge(ctx context.Context, id int) (*models.BookingCharge, error) {
+    charge := &models.BookingCharge{}
+    err := r.q.QueryRowContext(ctx, `
+        SELECT id, booking_id, trip_id, kind, amount, currency, provider_reference, description, created_at
+        FROM payment_transactions
+        WHERE id = $1 AND kind = 'charge'
+    `, id).Scan(
+        &charge.ID,
+        &charge.BookingID,
+        &charge.TripID,
+        &charge.Kind,
+        &charge.Amount,
+        &charge.Currency,
+        &charge.ProviderReference,
+        &charge.Description,
+        &charge.CreatedAt,
+    )
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, ErrNotFound
+        }
+        return nil, fmt.Errorf("failed to get charge: %w", err)
+    }
+    
+    // Refunds still being paid count as refunded, so they aren't paid twice
+    err = r.q.QueryRowContext(ctx, `
+        SELECT COALESCE((SELECT SUM(amount) FROM payment_transactions WHERE parent_id = $1 AND kind = 'refund'), 0)
+             + COALESCE((SELECT SUM(amount) FROM refund_requests WHERE charge_id = $1 AND status = 'pending'), 0)
+    `, charge.ID).Scan(&charge.Refunded)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get refunded amount: %w", err)
+    }
+    
+    rows, err := r.q.QueryContext(ctx, `
+        SELECT id, transaction_id, account, user_id, entry_type, amount, currency, created_at
+        FROM ledger_entries
+        WHERE transaction_id = $1
+        ORDER BY id
+    `, charge.ID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get charge entries: %w", err)
+    }
+    defer rows.Close()
+    
+    for rows.Next() {
+        var entry models.LedgerEntry
+        err := rows.Scan(
+            &entry.ID,
+            &entry.TransactionID,
+            &entry.Account,
+            &entry.UserID,
+            &entry.EntryType,
+            &entry.Amount,
+            &entry.Currency,
+            &entry.CreatedAt,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+        }
+        if entry.Account == "platform" {
+            charge.Fee += entry.Amount
+        }
+        charge.Entries = append(charge.Entries, entry)
+    }
+    
+    return charge, rows.Err()
+}
+
+func (r postgresPayments) Balances(ctx context.Context, userID int) ([]models.AccountBalance, error) {
+    rows, err := r.q.QueryContext(ctx, `
+        SELECT account, currency, SUM(amount)
+        FROM ledger_entries
+        WHERE user_id = $1
+        GROUP BY account, currency
+        ORDER BY account, currency
+    `, userID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get balances: %w", err)
+    }
+    defer rows.Close()
+    
+    balances := []models.AccountBalance{}
+    for rows.Next() {
+        var balance models.AccountBalance
+        if err := rows.Scan(&balance.Account, &balance.Currency, &balance.Balance); err != nil {
+            return nil, fmt.Errorf("failed to scan balance: %w", err)
+        }
+        balances = append(balances, balance)
+    }
+    
+    return balances, rows.Err()
+}
+
+func (r postgresPayments) Entries(ctx context.Context, userID, limit, offset int) ([]models.LedgerEntry, error) {
+    rows, err := r.q.QueryContext(ctx, `
+        SELECT e.id, e.transaction_id, e.account, e.user_id, e.entry_type, e.amount, e.currency, e.created_at, pt.trip_id
+        FROM ledger_entries e
+        JOIN payment_transactions pt ON pt.id = e.transaction_id
+        WHERE e.user_id = $1
+        ORDER BY e.created_at DESC, e.id DESC
+        LIMIT $2 OFFSET $3
+    `, userID, limit, offset)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get transactions: %w", err)
+    }
+    defer rows.Close()
+    
+    entries := []models.LedgerEntry{}
+    for rows.Next() {
+        var entry models.LedgerEntry
+        err := rows.Scan(
+            &entry.ID,
+            &entry.TransactionID,
+            &entry.Account,
+            &entry.UserID,
+            &entry.EntryType,
+            &entry.Amount,
+            &entry.Currency,
+            &entry.CreatedAt,
+            &entry.TripID,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+        }
+        entries = append(entries, entry)
+    }
+    
+    return entries, rows.Err()
+}
+
+func (r postgresPayments) RequestRefund(ctx context.Context, request *models.RefundRequest) error {
+    err := r.q.QueryRowContext(ctx, `
+        INSERT INTO refund_requests (charge_id, amount, description, status, created_at, updated_at)
+        VALUES ($1, $2, $3, 'pending', NOW(), NOW())
+        RETURNING id, status, created_at, updated_at
+    `, request.ChargeID, request.Amount, request.Description).Scan(&request.ID, &request.Status, &request.CreatedAt, &request.UpdatedAt)
+    if err != nil {
+        return fmt.Errorf("failed to request refund: %w", err)
+    }
+    
+    return nil
+}
+
+const refundRequestColumns = "id, charge_id, amount, description, status, attempts, last_error, transaction_id, created_at, updated_at"
+
+func scanRefundRequest(row rowScanner, request *models.RefundRequest) error {
+    return row.Scan(
+        &request.ID,
+        &request.ChargeID,
+        &request.Amount,
+        &request.Description,
+        &request.Status,
+        &request.Attempts,
+        &request.LastError,
+        &request.TransactionID,
+        &request.CreatedAt,
+        &request.UpdatedAt,
+    )
+}
+
+func (r postgresPayments) GetRefundRequestForUpdate(ctx context.Context, id int) (*models.RefundRequest, error) {
+    request := &models.RefundRequest{}
+    row := r.q.QueryRowContext(ctx, "SELECT "+refundRequestColumns+" FROM refund_requests WHERE id = $1 FOR UPDATE", id)
+    if err := scanRefundRequest(row, request); err != nil {
+        if err == sql.ErrNoRows {
+            return nil, ErrNotFound
+        }
+        return nil, fmt.Errorf("failed to get refund request: %w", err)
+    }
+    
+    return request, nil
+}
+
+func (r postgresPayments) PendingRefunds(ctx context.Context, cutoff time.Time, limit int) ([]models.RefundRequest, error) {
+    rows, err := r.q.QueryContext(ctx, `
+        SELECT `+refundRequestColumns+`
+        FROM refund_requests
+        WHERE status = 'pending' AND updated_at < $1
+        ORDER BY updated_at, id
+        LIMIT $2
+    `, cutoff, limit)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get pending refunds: %w", err)
+    }
+    defer rows.Close()
+    
+    var requests []models.RefundRequest
+    for rows.Next() {
+        var request models.RefundRequest
+        if err := scanRefundRequest(rows, &request); err != nil {
+            return nil, fmt.Errorf("failed to scan refund request: %w", err)
+        }
+        requests = append(requests, request)
+    }
+    
+    return requests, rows.Err()
+}
+
+func (r postgresPayments) FailRefund(ctx context.Context, id int, reason string) error {
+    err := execOne(ctx, r.q, `
+        UPDATE refund_requests
+        SET attempts = attempts + 1, last_error = $2, updated_at = NOW()
+        WHERE id = $1 AND status = 'pending'
+    `, id, reason)
+    if err != nil && err != ErrNotFound {
+        return fmt.Errorf("failed to record refund attempt: %w", err)
+    }
+    
+    return err
+}
+
+func (r postgresPayments) CompleteRefund(ctx context.Context, id, transactionID int) error {
+    err := execOne(ctx, r.q, `
+        UPDATE refund_requests
+        SET status = 'completed', attempts = attempts + 1, last_error = NULL, transaction_id = $2, updated_at = NOW()
+        WHERE id = $1 AND status = 'pending'
+    `, id, transactionID)
+    if err != nil && err != ErrNotFound {
+        return fmt.Errorf("failed to complete refund request: %w", err)
+    }
+    
+    return err
+}
@@ -0,0 +1,795 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/money"
+    "rideshare-backend/internal/utils"
+    "strings"
+    "time"
+
+    "github.com/lib/pq"
+)
+
+type postgresTrips struct {
+    q querier
+}
+
+func totalCostMinor(trip *models.Trip) *money.Amount {
+    if trip.TotalCost == nil {
+        return nil
+    }
+    return &trip.TotalCost.Amount
+}
+
+func (r postgresTrips) Create(ctx context.Context, trip *models.Trip) error {
+    query := `
+        INSERT INTO trips (driver_id, from_location, to_location, departure_time, timezone, max_passengers, price_per_person_minor, currency, fare_mode, total_cost_minor, vehicle_id,
+            pets_allowed, smoking_allowed, luggage_size, music, women_only, instant_booking, description, status, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, NOW(), NOW())
+        RETURNING id, created_at, updated_at
+    `
+    
+    err := r.q.QueryRowContext(ctx,
+        query,
+        trip.DriverID,
+        trip.FromLocation,
+        trip.ToLocation,
+        trip.DepartureTime,
+        trip.Timezone,
+        trip.MaxPassengers,
+        trip.PricePerPerson.Amount,
+        trip.PricePerPerson.Currency,
+        trip.FareMode,
+        totalCostMinor(trip),
+        trip.VehicleID,
+        trip.Preferences.PetsAllowed,
+        trip.Preferences.SmokingAllowed,
+        trip.Preferences.LuggageSize,
+        trip.Preferences.Music,
+        trip.Preferences.WomenOnly,
+        trip.Preferences.InstantBooking,
+        trip.Description,
+        trip.Status,
+    ).Scan(&trip.ID, &trip.CreatedAt, &trip.UpdatedAt)
+    
+    if err != nil {
+        return fmt.Errorf("failed to create trip: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresTrips) Get(ctx context.Context, id int) (*models.Trip, error) {
+    trip := &models.Trip{}
+    driver := &models.User{}
+    
+    query := `
+        SELECT ` + tripColumns + `,
+               u.id, u.name, u.email, u.phone
+        FROM trips t
+        LEFT JOIN users u ON t.driver_id = u.id
+        WHERE t.id = $1
+    `
+    
+    err := scanTrip(r.q.QueryRowContext(ctx, query, id), trip, &driver.ID, &driver.Name, &driver.Email, &driver.Phone)
+    
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, ErrNotFound
+        }
+        return nil, fmt.Errorf("failed to get trip: %w", err)
+    }
+    
+    trip.Driver = driver
+    return trip, nil
+}
+
+func (r postgresTrips) GetForUpdate(ctx context.Context, id int) (*models.Trip, error) {
+    trip := &models.Trip{}
+    err := scanTrip(r.q.QueryRowContext(ctx, "SELECT "+tripColumns+" FROM trips t WHERE t.id = $1 FOR UPDATE", id), trip)
+    
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, ErrNotFound
+        }
+        return nil, fmt.Errorf("failed to lock trip: %w", err)
+    }
+    
+    return trip, nil
+}
+
+func (r postgresTrips) Update(ctx context.Context, trip *models.Trip) error {
+    query := `
+        UPDATE trips
+        SET from_location = $1, to_location = $2, departure_time = $3, timezone = $4,
+            max_passengers = $5, price_per_person_minor = $6, currency = $7,
+            fare_mode = $8, total_cost_minor = $9, vehicle_id = $10, pets_allowed = $11, smoking_allowed = $12,
+            luggage_size = $13, music = $14, women_only = $15, instant_booking = $16, description = $17, updated_at = NOW()
+        WHERE id = $18
+    `
+    
+    err := execOne(
+        ctx,
+        r.q,
+        query,
+        trip.FromLocation,
+        trip.ToLocation,
+        trip.DepartureTime,
+        trip.Timezone,
+        trip.MaxPassengers,
+        trip.PricePerPerson.Amount,
+        trip.PricePerPerson.Currency,
+        trip.FareMode,
+        totalCostMinor(trip),
+        trip.VehicleID,
+        trip.Preferences.PetsAllowed,
+        trip.Preferences.SmokingAllowed,
+        trip.Preferences.LuggageSize,
+        trip.Preferences.Music,
+        trip.Preferences.WomenOnly,
+        trip.Preferences.InstantBooking,
+        trip.Description,
+        trip.ID,
+    )
+    
+    if err != nil {
+        // Seats held by passengers checking out count as taken too
+        if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == "trips_seats_within_capacity" {
+            return ErrOverCapacity
+        }
+        return fmt.Errorf("failed to update trip: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresTrips) SetStatus(ctx context.Context, id int, status string) error {
+    err := execOne(ctx, r.q, `
+        UPDATE trips
+        SET status = $2, updated_at = NOW(),
+            started_at = CASE WHEN $2 = 'in_progress' THEN NOW() ELSE started_at END
+        WHERE id = $1
+    `, id, status)
+    if err != nil {
+        return fmt.Errorf("failed to update trip status: %w", err)
+    }
+    
+    return nil
+}
+
+// TakeSeats only goes through if the seats are free at that moment, so
+// concurrent bookings can't overbook the trip even without its row lock.
+func (r postgresTrips) TakeSeats(ctx context.Context, id, seats int) error {
+    err := execOne(ctx, r.q, `
+        UPDATE trips SET current_passengers = current_passengers + $2, updated_at = NOW()
+        WHERE id = $1 AND current_passengers + held_seats + $2 <= max_passengers
+    `, id, seats)
+    if err == ErrNotFound {
+        return ErrOverCapacity
+    }
+    if err != nil {
+        return fmt.Errorf("failed to update passenger count: %w", err)
+    }
+    
+    return r.RepriceSharedFare(ctx, id)
+}
+
+func (r postgresTrips) ReleaseSeats(ctx context.Context, id, seats int) error {
+    _, err := r.q.ExecContext(ctx,
+        "UPDATE trips SET current_passengers = current_passengers - $2, updated_at = NOW() WHERE id = $1",
+        id, seats,
+    )
+    if err != nil {
+        return fmt.Errorf("failed to update passenger count: %w", err)
+    }
+    
+    return r.RepriceSharedFare(ctx, id)
+}
+
+// RepriceSharedFare prices a seat as in services.SharedFare.
+func (r postgresTrips) RepriceSharedFare(ctx context.Context, id int) error {
+    _, err := r.q.ExecContext(ctx, `
+        UPDATE trips
+        SET price_per_person_minor = (total_cost_minor + GREATEST(current_passengers, 1)) / (GREATEST(current_passengers, 1) + 1)
+        WHERE id = $1 AND fare_mode = 'shared'
+    `, id)
+    if err != nil {
+        return fmt.Errorf("failed to update shared fare: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresTrips) DeleteLocations(ctx context.Context, id int) error {
+    if _, err := r.q.ExecContext(ctx, "DELETE FROM trip_locations WHERE trip_id = $1", id); err != nil {
+        return fmt.Errorf("failed to clear trip locations: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresTrips) IsParticipant(ctx context.Context, tripID, userID int) (bool, error) {
+    query := `
+        SELECT EXISTS (
+            SELECT 1 FROM trips WHERE id = $1 AND driver_id = $2
+            UNION ALL
+            SELECT 1 FROM trip_passengers
+            WHERE trip_id = $1 AND passenger_id = $2 AND status = 'confirmed'
+        )
+    `
+    
+    var ok bool
+    if err := r.q.QueryRowContext(ctx, query, tripID, userID).Scan(&ok); err != nil {
+        return false, fmt.Errorf("failed to check trip participation: %w", err)
+    }
+    
+    return ok, nil
+}
+
+func (r postgresTrips) DriverStats(ctx context.Context, driverID int) (*models.UserStats, error) {
+    query := `
+        SELECT 
+            COUNT(*) as total_trips,
+            COUNT(CASE WHEN status = 'completed' THEN 1 END) as completed_trips,
+            COUNT(CASE WHEN status = 'cancelled' THEN 1 END) as cancelled_trips,
+            COALESCE(AVG(CASE WHEN status = 'completed' THEN 5.0 END), 0) as rating,
+            COUNT(CASE WHEN status = 'completed' THEN 1 END) as review_count
+        FROM trips 
+        WHERE driver_id = $1
+    `
+    
+    stats := &models.UserStats{}
+    err := r.q.QueryRowContext(ctx, query, driverID).Scan(
+        &stats.TotalTrips,
+        &stats.CompletedTrips,
+        &stats.CancelledTrips,
+        &stats.Rating,
+        &stats.ReviewCount,
+    )
+    if err != nil {
+        return nil, fmt.Errorf("failed to get driver stats: %w", err)
+    }
+    
+    return stats, nil
+}
+
+func (r postgresTrips) ActiveIDsForUser(ctx context.Context, userID int) ([]int, error) {
+    query := `
+        SELECT id FROM trips WHERE driver_id = $1 AND status IN ('active', 'in_progress')
+        UNION
+        SELECT t.id
+        FROM trips t
+        JOIN trip_passengers tp ON tp.trip_id = t.id
+        WHERE tp.passenger_id = $1 AND tp.status = 'confirmed' AND t.status IN ('active', 'in_progress')
+    `
+    
+    rows, err := r.q.QueryContext(ctx, query, userID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get user trip IDs: %w", err)
+    }
+    defer rows.Close()
+    
+    var tripIDs []int
+    for rows.Next() {
+        var id int
+        if err := rows.Scan(&id); err != nil {
+            return nil, fmt.Errorf("failed to scan trip ID: %w", err)
+        }
+        tripIDs = append(tripIDs, id)
+    }
+    
+    return tripIDs, rows.Err()
+}
+
+func (r postgresTrips) ListByDriver(ctx context.Context, driverID int, cursor string, limit int) (*models.TripPage, error) {
+    return r.listTrips(ctx, tripPage{
+        from:       "FROM trips t LEFT JOIN users u ON t.driver_id = u.id",
+        conditions: []string{"t.driver_id = $1"},
+        args:       []interface{}{driverID},
+        order:      tripOrder{expr: "t.departure_time", sqlType: "timestamptz", desc: true},
+        cursor:     cursor,
+        limit:      limit,
+    })
+}
+
+func (r postgresTrips) Timeline(ctx context.Context, userID int, filter models.TimelineFilter) (*models.TripPage, error) {
+    // A trip is upcoming until it's over, or until its departure has passed
+    // without it being started
+    upcoming := "(t.status = 'in_progress' OR (t.status = 'active' AND t.departure_time >= NOW()))"
+    
+    conditions := []string{"(t.driver_id = $1 OR tp.passenger_id IS NOT NULL)"}
+    args := []interface{}{userID}
+    order := tripOrder{expr: "t.departure_time", sqlType: "timestamptz"}
+    
+    if filter.When == models.TimelinePast {
+        conditions = append(conditions, "NOT "+upcoming)
+        order.desc = true
+    } else {
+        conditions = append(conditions, upcoming)
+    }
+    
+    switch filter.Role {
+    case models.RoleDriver:
+        conditions = append(conditions, "t.driver_id = $1")
+    case models.RolePassenger:
+        conditions = append(conditions, "t.driver_id <> $1", "tp.passenger_id IS NOT NULL")
+    }
+    
+    if filter.Status != "" {
+        args = append(args, filter.Status)
+        conditions = append(conditions, fmt.Sprintf("t.status = $%d", len(args)))
+    }
+    
+    if filter.BookingStatus != "" {
+        args = append(args, filter.BookingStatus)
+        conditions = append(conditions, fmt.Sprintf("t.driver_id <> $1 AND tp.status = $%d", len(args)))
+    } else {
+        conditions = append(conditions, "(t.driver_id = $1 OR tp.status <> 'cancelled')")
+    }
+    
+    return r.listTrips(ctx, tripPage{
+        from: `FROM trips t
+        LEFT JOIN users u ON t.driver_id = u.id
+        LEFT JOIN trip_passengers tp ON tp.trip_id = t.id AND tp.passenger_id = $1`,
+        conditions: conditions,
+        args:       args,
+        order:      order,
+        columns:    []string{"CASE WHEN t.driver_id = $1 THEN 'driver' ELSE 'passenger' END", "CASE WHEN t.driver_id = $1 THEN NULL ELSE tp.status END"},
+        extra: func(trip *models.Trip) []interface{} {
+            return []interface{}{&trip.UserRole, &trip.BookingStatus}
+        },
+        cursor: filter.Cursor,
+        limit:  filter.Limit,
+    })
+}
+
+func (r postgresTrips) Search(ctx context.Context, userID int, criteria models.TripSearchCriteria) (*models.TripPage, error) {
+    conditions := []string{
+        "t.status = 'active'",
+        "t.current_passengers + t.held_seats < t.max_passengers",
+        "(NOT t.women_only OR EXISTS (SELECT 1 FROM users WHERE id = $1 AND gender = 'female'))",
+    }
+    args := []interface{}{userID}
+    argIndex := 2
+    
+    if criteria.From != "" {
+        conditions = append(conditions, fmt.Sprintf("t.from_location ILIKE $%d", argIndex))
+        args = append(args, "%"+criteria.From+"%")
+        argIndex++
+    }
+    
+    if criteria.To != "" {
+        conditions = append(conditions, fmt.Sprintf("t.to_location ILIKE $%d", argIndex))
+        args = append(args, "%"+criteria.To+"%")
+        argIndex++
+    }
+    
+    if len(criteria.Departures) > 0 {
+        condition, departureArgs := departureConditions(criteria.Departures, argIndex)
+        conditions = append(conditions, condition)
+        args = append(args, departureArgs...)
+        argIndex += len(departureArgs)
+    }
+    
+    if criteria.MaxPrice != nil {
+        conditions = append(conditions, fmt.Sprintf("t.currency = $%d AND t.price_per_person_minor <= $%d", argIndex, argIndex+1))
+        args = append(args, criteria.MaxPrice.Currency, criteria.MaxPrice.Amount)
+        argIndex += 2
+    }
+    
+    prefConditions, prefArgs := preferenceConditions(criteria.Preferences, argIndex)
+    conditions = append(conditions, prefConditions...)
+    args = append(args, prefArgs...)
+    argIndex += len(prefArgs)
+    
+    var orderArgs []interface{}
+    order, ok := tripOrders[criteria.Sort]
+    switch {
+    case criteria.Sort == "":
+        order = tripOrders[models.SortDeparture]
+    case criteria.Sort == models.SortMatchScore:
+        // Trigram similarity of the route, weighted like MatchingService
+        order = tripOrder{
+            expr: fmt.Sprintf(
+                "(similarity(t.from_location, $%d) * 0.45 + similarity(t.to_location, $%d) * 0.55)",
+                argIndex, argIndex+1,
+            ),
+            sqlType: "double precision",
+            desc:    true,
+        }
+        orderArgs = []interface{}{criteria.From, criteria.To}
+    case !ok:
+        return nil, fmt.Errorf("invalid sort order %q", criteria.Sort)
+    }
+    
+    return r.listTrips(ctx, tripPage{
+        from:       "FROM trips t LEFT JOIN users u ON t.driver_id = u.id",
+        conditions: conditions,
+        args:       args,
+        order:      order,
+        orderArgs:  orderArgs,
+        cursor:     criteria.Cursor,
+        limit:      criteria.Limit,
+    })
+}
+
+func (r postgresTrips) ListBookable(ctx context.Context, userID int) ([]models.Trip, error) {
+    query := `
+        SELECT ` + tripColumns + `
+        FROM trips t
+        WHERE t.status = 'active' 
+        AND t.driver_id != $1
+        AND t.current_passengers + t.held_seats < t.max_passengers
+        AND t.departure_time > NOW()
+        AND (NOT t.women_only OR EXISTS (SELECT 1 FROM users WHERE id = $1 AND gender = 'female'))
+        ORDER BY t.departure_time ASC
+    `
+    
+    rows, err := r.q.QueryContext(ctx, query, userID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query trips: %w", err)
+    }
+    defer rows.Close()
+    
+    var trips []models.Trip
+    for rows.Next() {
+        var trip models.Trip
+        if err := scanTrip(rows, &trip); err != nil {
+            return nil, fmt.Errorf("failed to scan trip: %w", err)
+        }
+        trips = append(trips, trip)
+    }
+    
+    return trips, rows.Err()
+}
+
+func (r postgresTrips) ClaimDueReminders(ctx context.Context, lead time.Duration) ([]models.Trip, error) {
+    query := `
+        UPDATE trips
+        SET reminder_sent_at = NOW()
+        WHERE status = 'active'
+        AND reminder_sent_at IS NULL
+        AND departure_time > NOW()
+        AND departure_time <= NOW() + make_interval(secs => $1)
+        RETURNING id, driver_id, from_location, to_location, departure_time, timezone
+    `
+    
+    rows, err := r.q.QueryContext(ctx, query, lead.Seconds())
+    if err != nil {
+        return nil, fmt.Errorf("failed to claim departure reminders: %w", err)
+    }
+    defer rows.Close()
+    
+    var trips []models.Trip
+    for rows.Next() {
+        var trip models.Trip
+        err := rows.Scan(
+            &trip.ID,
+            &trip.DriverID,
+            &trip.FromLocation,
+            &trip.ToLocation,
+            &trip.DepartureTime,
+            &trip.Timezone,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan trip: %w", err)
+        }
+        trip.DepartureTime = trip.DepartureTime.In(utils.LoadLocation(trip.Timezone))
+        trips = append(trips, trip)
+    }
+    
+    return trips, rows.Err()
+}
+
+func (r postgresTrips) RecordChange(ctx context.Context, change *models.TripChange) error {
+    raw, err := json.Marshal(change.Changes)
+    if err != nil {
+        return fmt.Errorf("failed to encode trip changes: %w", err)
+    }
+    
+    err = r.q.QueryRowContext(ctx, `
+        INSERT INTO trip_changes (trip_id, changed_by, changes, material, created_at)
+        VALUES ($1, $2, $3, $4, NOW())
+        RETURNING id, created_at
+    `, change.TripID, change.ChangedBy, raw, change.Material).Scan(&change.ID, &change.CreatedAt)
+    if err != nil {
+        return fmt.Errorf("failed to record trip change: %w", err)
+    }
+    
+    if !change.Material {
+        return nil
+    }
+    
+    rows, err := r.q.QueryContext(ctx, `
+        INSERT INTO trip_change_responses (change_id, passenger_id)
+        SELECT $1, passenger_id FROM trip_passengers WHERE trip_id = $2 AND status <> 'cancelled'
+        RETURNING passenger_id
+    `, change.ID, change.TripID)
+    if err != nil {
+        return fmt.Errorf("failed to record affected passengers: %w", err)
+    }
+    defer rows.Close()
+    
+    for rows.Next() {
+        var passengerID int
+        if err := rows.Scan(&passengerID); err != nil {
+            return fmt.Errorf("failed to scan passenger: %w", err)
+        }
+        change.Passengers = append(change.Passengers, passengerID)
+    }
+    
+    return rows.Err()
+}
+
+func (r postgresTrips) ListChanges(ctx context.Context, tripID int) ([]models.TripChange, error) {
+    rows, err := r.q.QueryContext(ctx, `
+        SELECT id, trip_id, changed_by, changes, material, created_at
+        FROM trip_changes
+        WHERE trip_id = $1
+        ORDER BY created_at DESC, id DESC
+    `, tripID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get trip changes: %w", err)
+    }
+    defer rows.Close()
+    
+    changes := []models.TripChange{}
+    for rows.Next() {
+        var change models.TripChange
+        var raw []byte
+        if err := rows.Scan(&change.ID, &change.TripID, &change.ChangedBy, &raw, &change.Material, &change.CreatedAt); err != nil {
+            return nil, fmt.Errorf("failed to scan trip change: %w", err)
+        }
+        if err := json.Unmarshal(raw, &change.Changes); err != nil {
+            return nil, fmt.Errorf("failed to decode trip change: %w", err)
+        }
+        changes = append(changes, change)
+    }
+    
+    return changes, rows.Err()
+}
+
+func (r postgresTrips) GetChangeResponse(ctx context.Context, changeID, tripID, passengerID int) (*string, error) {
+    var response sql.NullString
+    err := r.q.QueryRowContext(ctx, `
+        SELECT r.response
+        FROM trip_change_responses r
+        JOIN trip_changes c ON c.id = r.change_id
+        WHERE r.change_id = $1 AND c.trip_id = $2 AND r.passenger_id = $3
+        FOR UPDATE OF r
+    `, changeID, tripID, passengerID).Scan(&response)
+    
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, ErrNotFound
+        }
+        return nil, fmt.Errorf("failed to get change: %w", err)
+    }
+    
+    if !response.Valid {
+        return nil, nil
+    }
+    return &response.String, nil
+}
+
+func (r postgresTrips) SetChangeResponse(ctx context.Context, changeID, passengerID int, response string) error {
+    err := execOne(ctx, r.q,
+        "UPDATE trip_change_responses SET response = $3, responded_at = NOW() WHERE change_id = $1 AND passenger_id = $2",
+        changeID, passengerID, response,
+    )
+    if err != nil {
+        return fmt.Errorf("failed to record response: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresTrips) AwaitingResponse(ctx context.Context, tripID, passengerID int) (bool, error) {
+    var awaiting bool
+    err := r.q.QueryRowContext(ctx, `
+        SELECT EXISTS (
+            SELECT 1
+            FROM trip_change_responses r
+            JOIN trip_changes c ON c.id = r.change_id
+            WHERE c.trip_id = $1 AND r.passenger_id = $2 AND r.response IS NULL
+        )
+    `, tripID, passengerID).Scan(&awaiting)
+    if err != nil {
+        return false, fmt.Errorf("failed to check change responses: %w", err)
+    }
+    
+    return awaiting, nil
+}
+
+// tripOrder is how a trip list is sorted. Ties are broken on the trip ID so
+// a cursor always points at a single row.
+type tripOrder struct {
+    expr    string // SQL expression on trips aliased t
+    sqlType string // type the cursor value is cast back to
+    desc    bool
+}
+
+var tripOrders = map[string]tripOrder{
+    models.SortDeparture: {expr: "t.departure_time", sqlType: "timestamptz"},
+    models.SortPrice:     {expr: "t.price_per_person_minor", sqlType: "bigint"},
+    models.SortSeatsLeft: {expr: "(t.max_passengers - t.current_passengers - t.held_seats)", sqlType: "integer", desc: true},
+}
+
+// tripPage describes one page of a trip listing. from is the FROM clause,
+// which must join the driver as users u. orderArgs are the placeholders used
+// by the order expression, numbered after args. columns are selected after
+// the driver and scanned into the fields extra returns for each trip.
+type tripPage struct {
+    from       string
+    conditions []string
+    args       []interface{}
+    order      tripOrder
+    orderArgs  []interface{}
+    columns    []string
+    extra      func(trip *models.Trip) []interface{}
+    cursor     string
+    limit      int
+}
+
+func (r postgresTrips) listTrips(ctx context.Context, page tripPage) (*models.TripPage, error) {
+    where := ""
+    if len(page.conditions) > 0 {
+        where = " WHERE " + strings.Join(page.conditions, " AND ")
+    }
+
+    result := &models.TripPage{Trips: []models.Trip{}}
+    if err := r.q.QueryRowContext(ctx, "SELECT COUNT(*) "+page.from+where, page.args...).Scan(&result.Total); err != nil {
+        return nil, fmt.Errorf("failed to count trips: %w", err)
+    }
+
+    conditions := append([]string{}, page.conditions...)
+    args := append(append([]interface{}{}, page.args...), page.orderArgs...)
+    if page.cursor != "" {
+        cursor, err := decodeCursor(page.cursor)
+        if err != nil {
+            return nil, err
+        }
+
+        comparison := ">"
+        if page.order.desc {
+            comparison = "<"
+        }
+
+        conditions = append(conditions, fmt.Sprintf(
+            "(%s, t.id) %s ($%d::%s, $%d)",
+            page.order.expr, comparison, len(args)+1, page.order.sqlType, len(args)+2,
+        ))
+        args = append(args, cursor.Value, cursor.ID)
+    }
+
+    direction := "ASC"
+    if page.order.desc {
+        direction = "DESC"
+    }
+
+    columns := ""
+    for _, column := range page.columns {
+        columns += column + ", "
+    }
+
+    limit := pageSize(page.limit, defaultTripPageSize, maxTripPageSize)
+    query := `
+        SELECT ` + tripColumns + `,
+               u.id, u.name, u.email, u.phone, ` + columns + `(` + page.order.expr + `)::text
+        ` + page.from
+
+    if len(conditions) > 0 {
+        query += " WHERE " + strings.Join(conditions, " AND ")
+    }
+    query += fmt.Sprintf(" ORDER BY %s %s, t.id %s LIMIT $%d", page.order.expr, direction, direction, len(args)+1)
+
+    // Fetch one extra row to know whether another page exists
+    rows, err := r.q.QueryContext(ctx, query, append(args, limit+1)...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list trips: %w", err)
+    }
+    defer rows.Close()
+
+    var lastValue string
+    for rows.Next() {
+        if len(result.Trips) == limit {
+            result.HasMore = true
+            break
+        }
+
+        var trip models.Trip
+        var driver nullableDriver
+        var sortValue string
+
+        dest := []interface{}{&driver.id, &driver.name, &driver.email, &driver.phone}
+        if page.extra != nil {
+            dest = append(dest, page.extra(&trip)...)
+        }
+
+        err := scanTrip(rows, &trip, append(dest, &sortValue)...)
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan trip: %w", err)
+        }
+
+        trip.Driver = driver.user()
+        result.Trips = append(result.Trips, trip)
+        lastValue = sortValue
+    }
+
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to list trips: %w", err)
+    }
+
+    if result.HasMore {
+        last := result.Trips[len(result.Trips)-1]
+        next := encodeCursor(pageCursor{Value: lastValue, ID: last.ID})
+        result.NextCursor = &next
+    }
+
+    return result, nil
+}
+
+// departureConditions builds a sargable predicate on t.departure_time
+// matching any of the ranges, numbering placeholders from argIndex.
+func departureConditions(ranges []models.TimeRange, argIndex int) (string, []interface{}) {
+    var clauses []string
+    var args []interface{}
+
+    for _, r := range ranges {
+        var bounds []string
+        if !r.Start.IsZero() {
+            bounds = append(bounds, fmt.Sprintf("t.departure_time >= $%d", argIndex))
+            args = append(args, r.Start.UTC())
+            argIndex++
+        }
+        if !r.End.IsZero() {
+            bounds = append(bounds, fmt.Sprintf("t.departure_time < $%d", argIndex))
+            args = append(args, r.End.UTC())
+            argIndex++
+        }
+        clauses = append(clauses, "("+strings.Join(bounds, " AND ")+")")
+    }
+
+    return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+// preferenceConditions turns a filter into WHERE conditions on trips aliased
+// t, numbering placeholders from argIndex.
+func preferenceConditions(filter models.PreferenceFilter, argIndex int) ([]string, []interface{}) {
+    var conditions []string
+    var args []interface{}
+
+    flags := []struct {
+        column string
+        value  *bool
+    }{
+        {"t.pets_allowed", filter.PetsAllowed},
+        {"t.smoking_allowed", filter.SmokingAllowed},
+        {"t.music", filter.Music},
+        {"t.women_only", filter.WomenOnly},
+        {"t.instant_booking", filter.InstantBooking},
+    }
+
+    for _, flag := range flags {
+        if flag.value == nil {
+            continue
+        }
+        conditions = append(conditions, fmt.Sprintf("%s = $%d", flag.column, argIndex))
+        args = append(args, *flag.value)
+        argIndex++
+    }
+
+    if filter.LuggageSize != "" {
+        conditions = append(conditions, fmt.Sprintf(
+            "array_position(ARRAY['none', 'small', 'medium', 'large'], t.luggage_size::text) >= array_position(ARRAY['none', 'small', 'medium', 'large'], $%d::text)",
+            argIndex,
+        ))
+        args = append(args, filter.LuggageSize)
+    }
+
+    return conditions, args
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "rideshare-backend/internal/models"
+)
+
+type postgresUsers struct {
+    q querier
+}
+
+func (r postgresUsers) Create(ctx context.Context, user *models.User) error {
+    query := `
+        INSERT INTO users (name, email, password, phone, timezone, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+        RETURNING id, created_at, updated_at
+    `
+    
+    err := r.q.QueryRowContext(ctx,
+        query,
+        user.Name,
+        user.Email,
+        user.Password,
+        user.Phone,
+        user.Timezone,
+    ).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+    
+    if err != nil {
+        return fmt.Errorf("failed to create user: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+    user := &models.User{}
+    query := `
+        SELECT id, name, email, password, phone, profile_image, gender, timezone, is_verified, created_at, updated_at
+        FROM users
+        WHERE email = $1
+    `
+    
+    err := r.q.QueryRowContext(ctx, query, email).Scan(
+        &user.ID,
+        &user.Name,
+        &user.Email,
+        &user.Password,
+        &user.Phone,
+        &user.ProfileImage,
+        &user.Gender,
+        &user.Timezone,
+        &user.IsVerified,
+        &user.CreatedAt,
+        &user.UpdatedAt,
+    )
+    
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, ErrNotFound
+        }
+        return nil, fmt.Errorf("failed to get user: %w", err)
+    }
+    
+    return user, nil
+}
+
+func (r postgresUsers) GetByID(ctx context.Context, id int) (*models.User, error) {
+    return r.get(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id)
+}
+
+func (r postgresUsers) GetForUpdate(ctx context.Context, id int) (*models.User, error) {
+    return r.get(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1 FOR UPDATE", id)
+}
+
+// userColumns lists the user fields loaded by get, in the order it scans
+// them. The password hash is only loaded to sign a user in.
+const userColumns = "id, name, email, phone, profile_image, gender, timezone, is_verified, created_at, updated_at"
+
+func (r postgresUsers) get(ctx context.Context, query string, id int) (*models.User, error) {
+    user := &models.User{}
+    err := r.q.QueryRowContext(ctx, query, id).Scan(
+        &user.ID,
+        &user.Name,
+        &user.Email,
+        &user.Phone,
+        &user.ProfileImage,
+        &user.Gender,
+        &user.Timezone,
+        &user.IsVerified,
+        &user.CreatedAt,
+        &user.UpdatedAt,
+    )
+    
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, ErrNotFound
+        }
+        return nil, fmt.Errorf("failed to get user: %w", err)
+    }
+    
+    return user, nil
+}
+
+func (r postgresUsers) Update(ctx context.Context, user *models.User) error {
+    query := `
+        UPDATE users
+        SET name = $1, phone = $2, profile_image = $3, gender = $4, timezone = $5, updated_at = NOW()
+        WHERE id = $6
+        RETURNING updated_at
+    `
+    
+    err := r.q.QueryRowContext(ctx,
+        query,
+        user.Name,
+        user.Phone,
+        user.ProfileImage,
+        user.Gender,
+        user.Timezone,
+        user.ID,
+    ).Scan(&user.UpdatedAt)
+    
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return ErrNotFound
+        }
+        return fmt.Errorf("failed to update user: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresUsers) IsAdmin(ctx context.Context, id int) (bool, error) {
+    var isAdmin bool
+    err := r.q.QueryRowContext(ctx, "SELECT is_admin FROM users WHERE id = $1", id).Scan(&isAdmin)
+    if err != nil && err != sql.ErrNoRows {
+        return false, fmt.Errorf("failed to check admin rights: %w", err)
+    }
+    
+    return isAdmin, nil
+}
+
+func (r postgresUsers) Preferences(ctx context.Context, id int) (models.PreferenceFilter, error) {
+    var prefs models.PreferenceFilter
+    var luggageSize sql.NullString
+    err := r.q.QueryRowContext(ctx, `
+        SELECT pets_allowed, smoking_allowed, luggage_size, music, women_only, instant_booking
+        FROM user_preferences
+        WHERE user_id = $1
+    `, id).Scan(
+        &prefs.PetsAllowed,
+        &prefs.SmokingAllowed,
+        &luggageSize,
+        &prefs.Music,
+        &prefs.WomenOnly,
+        &prefs.InstantBooking,
+    )
+    if err != nil && err != sql.ErrNoRows {
+        return prefs, fmt.Errorf("failed to get preferences: %w", err)
+    }
+    
+    prefs.LuggageSize = luggageSize.String
+    return prefs, nil
+}
+
+func (r postgresUsers) SavePreferences(ctx context.Context, id int, prefs models.PreferenceFilter) error {
+    var luggageSize *string
+    if prefs.LuggageSize != "" {
+        luggageSize = &prefs.LuggageSize
+    }
+    
+    _, err := r.q.ExecContext(ctx, `
+        INSERT INTO user_preferences (user_id, pets_allowed, smoking_allowed, luggage_size, music, women_only, instant_booking, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+        ON CONFLICT (user_id) DO UPDATE SET
+            pets_allowed = EXCLUDED.pets_allowed,
+            smoking_allowed = EXCLUDED.smoking_allowed,
+            luggage_size = EXCLUDED.luggage_size,
+            music = EXCLUDED.music,
+            women_only = EXCLUDED.women_only,
+            instant_booking = EXCLUDED.instant_booking,
+            updated_at = NOW()
+    `,
+        id,
+        prefs.PetsAllowed,
+        prefs.SmokingAllowed,
+        luggageSize,
+        prefs.Music,
+        prefs.WomenOnly,
+        prefs.InstantBooking,
+    )
+    if err != nil {
+        return fmt.Errorf("failed to save preferences: %w", err)
+    }
+    
+    return nil
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "rideshare-backend/internal/models"
+    
+    "github.com/lib/pq"
+)
+
+type postgresVehicles struct {
+    q querier
+}
+
+const vehicleColumns = `
+    v.id, v.owner_id, v.make, v.model, v.colour, v.plate, v.seats, v.amenities,
+    v.litres_per_100km, v.created_at, v.updated_at`
+
+func scanVehicle(row rowScanner, vehicle *models.Vehicle) error {
+    var amenities pq.StringArray
+    err := row.Scan(
+        &vehicle.ID,
+        &vehicle.OwnerID,
+        &vehicle.Make,
+        &vehicle.Model,
+        &vehicle.Colour,
+        &vehicle.Plate,
+        &vehicle.Seats,
+        &amenities,
+        &vehicle.LitresPer100Km,
+        &vehicle.CreatedAt,
+        &vehicle.UpdatedAt,
+    )
+    if err != nil {
+        return err
+    }
+    
+    vehicle.Amenities = []string(amenities)
+    if vehicle.Amenities == nil {
+        vehicle.Amenities = []string{}
+    }
+    
+    return nil
+}
+
+func (r postgresVehicles) Create(ctx context.Context, vehicle *models.Vehicle) error {
+    query := `
+        INSERT INTO vehicles (owner_id, make, model, colour, plate, seats, amenities, litres_per_100km, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+        RETURNING id, created_at, updated_at
+    `
+    
+    err := r.q.QueryRowContext(ctx,
+        query,
+        vehicle.OwnerID,
+        vehicle.Make,
+        vehicle.Model,
+        vehicle.Colour,
+        vehicle.Plate,
+        vehicle.Seats,
+        pq.Array(vehicle.Amenities),
+        vehicle.LitresPer100Km,
+    ).Scan(&vehicle.ID, &vehicle.CreatedAt, &vehicle.UpdatedAt)
+    
+    if err != nil {
+        return fmt.Errorf("failed to create vehicle: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresVehicles) ListByOwner(ctx context.Context, ownerID int) ([]models.Vehicle, error) {
+    rows, err := r.q.QueryContext(ctx, `
+        SELECT `+vehicleColumns+`
+        FROM vehicles v
+        WHERE v.owner_id = $1
+        ORDER BY v.created_at
+    `, ownerID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get vehicles: %w", err)
+    }
+    defer rows.Close()
+    
+    vehicles := []models.Vehicle{}
+    for rows.Next() {
+        var vehicle models.Vehicle
+        if err := scanVehicle(rows, &vehicle); err != nil {
+            return nil, fmt.Errorf("failed to scan vehicle: %w", err)
+        }
+        vehicles = append(vehicles, vehicle)
+    }
+    
+    return vehicles, rows.Err()
+}
+
+func (r postgresVehicles) Get(ctx context.Context, id, ownerID int) (*models.Vehicle, error) {
+    return r.get(ctx, `
+        SELECT `+vehicleColumns+`
+        FROM vehicles v
+        WHERE v.id = $1 AND v.owner_id = $2
+    `, id, ownerID)
+}
+
+func (r postgresVehicles) GetForTrip(ctx context.Context, tripID int) (*models.Vehicle, error) {
+    return r.get(ctx, `
+        SELECT `+vehicleColumns+`
+        FROM vehicles v
+        JOIN trips t ON t.vehicle_id = v.id
+        WHERE t.id = $1
+    `, tripID)
+}
+
+func (r postgresVehicles) get(ctx context.Context, query string, args ...interface{}) (*models.Vehicle, error) {
+    vehicle := &models.Vehicle{}
+    if err := scanVehicle(r.q.QueryRowContext(ctx, query, args...), vehicle); err != nil {
+        if err == sql.ErrNoRows {
+            return nil, ErrNotFound
+        }
+        return nil, fmt.Errorf("failed to get vehicle: %w", err)
+    }
+    
+    return vehicle, nil
+}
+
+func (r postgresVehicles) Update(ctx context.Context, vehicle *models.Vehicle) error {
+    query := `
+        UPDATE vehicles
+        SET make = $1, model = $2, colour = $3, plate = $4, seats = $5,
+            amenities = $6, litres_per_100km = $7, updated_at = NOW()
+        WHERE id = $8 AND owner_id = $9
+        RETURNING created_at, updated_at
+    `
+    
+    err := r.q.QueryRowContext(ctx,
+        query,
+        vehicle.Make,
+        vehicle.Model,
+        vehicle.Colour,
+        vehicle.Plate,
+        vehicle.Seats,
+        pq.Array(vehicle.Amenities),
+        vehicle.LitresPer100Km,
+        vehicle.ID,
+        vehicle.OwnerID,
+    ).Scan(&vehicle.CreatedAt, &vehicle.UpdatedAt)
+    
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return ErrNotFound
+        }
+        return fmt.Errorf("failed to update vehicle: %w", err)
+    }
+    
+    return nil
+}
+
+func (r postgresVehicles) Delete(ctx context.Context, id, ownerID int) error {
+    err := execOne(ctx, r.q, `
+        DELETE FROM vehicles
+        WHERE id = $1 AND owner_id = $2
+        AND NOT EXISTS (
+            SELECT 1 FROM trips WHERE vehicle_id = $1 AND status IN ('active', 'in_progress')
+        )
+    `, id, ownerID)
+    if err != nil && err != ErrNotFound {
+        return fmt.Errorf("failed to delete vehicle: %w", err)
+    }
+    
+    return err
+}
+
+func (r postgresVehicles) UpcomingSeats(ctx context.Context, id int) (int, error) {
+    var seats int
+    err := r.q.QueryRowContext(ctx, `
+        SELECT COALESCE(MAX(max_passengers), 0) FROM trips
+        WHERE vehicle_id = $1 AND status IN ('active', 'in_progress')
+    `, id).Scan(&seats)
+    if err != nil {
+        return 0, fmt.Errorf("failed to check vehicle trips: %w", err)
+    }
+    
+    return seats, nil
+}
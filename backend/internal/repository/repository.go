@@ -0,0 +1,331 @@
+// Package repository stores users, trips and bookings. Services work against
+// the interfaces here; the PostgreSQL store backs the server and the
+// in-memory store lets services run without a database.
+package repository
+
+import (
+    "context"
+    "errors"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/money"
+    "time"
+)
+
+var (
+    // ErrNotFound is returned when the record asked for doesn't exist.
+    ErrNotFound = errors.New("not found")
+    
+    // ErrDuplicate is returned when adding a record that already exists.
+    ErrDuplicate = errors.New("already exists")
+    
+    // ErrOverCapacity is returned when a change would book or hold more
+    // seats than a trip has.
+    ErrOverCapacity = errors.New("not enough seats")
+    
+    // ErrInvalidCursor is returned for a page cursor that wasn't issued by
+    // the store.
+    ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Store gives access to the repositories. Changes made through a store
+// returned by InTx are applied together or not at all.
+type Store interface {
+    Users() UserRepository
+    Trips() TripRepository
+    Bookings() BookingRepository
+    Audit() AuditRepository
+    Vehicles() VehicleRepository
+    Messages() MessageRepository
+    Locations() LocationRepository
+    Payments() PaymentRepository
+    
+    // InTx runs fn with a store bound to one transaction, committing it if fn
+    // returns nil and rolling it back otherwise. Calling InTx on a store that
+    // is already in a transaction runs fn in that transaction.
+    InTx(ctx context.Context, fn func(tx Store) error) error
+}
+
+type UserRepository interface {
+    // Create stores a new user, setting its ID and timestamps.
+    Create(ctx context.Context, user *models.User) error
+    
+    // GetByEmail loads a user with their password hash, to sign them in.
+    GetByEmail(ctx context.Context, email string) (*models.User, error)
+    GetByID(ctx context.Context, id int) (*models.User, error)
+    
+    // GetForUpdate loads a user and locks them until the transaction ends.
+    GetForUpdate(ctx context.Context, id int) (*models.User, error)
+    
+    // Update saves the user's profile, setting UpdatedAt.
+    Update(ctx context.Context, user *models.User) error
+    IsAdmin(ctx context.Context, id int) (bool, error)
+    
+    // Preferences returns the user's saved search preferences, empty until
+    // they save some.
+    Preferences(ctx context.Context, id int) (models.PreferenceFilter, error)
+    SavePreferences(ctx context.Context, id int, prefs models.PreferenceFilter) error
+}
+
+type TripRepository interface {
+    // Create stores a new trip, setting its ID and timestamps.
+    Create(ctx context.Context, trip *models.Trip) error
+    
+    // Get loads a trip with its driver.
+    Get(ctx context.Context, id int) (*models.Trip, error)
+    
+    // GetForUpdate loads a trip and locks it until the transaction ends.
+    // Bookings, holds and waitlist entries of a trip are only changed with
+    // the trip locked.
+    GetForUpdate(ctx context.Context, id int) (*models.Trip, error)
+    
+    // Update saves the fields a driver may edit. It returns ErrOverCapacity
+    // if the trip would have fewer seats than are booked or held.
+    Update(ctx context.Context, trip *models.Trip) error
+    
+    // SetStatus moves a trip to status. Starting a trip records when.
+    SetStatus(ctx context.Context, id int, status string) error
+    
+    // TakeSeats books seats on a trip if they are free, held ones counting
+    // as taken, and returns ErrOverCapacity otherwise. ReleaseSeats gives
+    // booked seats back. Both reprice a shared-cost trip.
+    TakeSeats(ctx context.Context, id, seats int) error
+    ReleaseSeats(ctx context.Context, id, seats int) error
+    
+    // RepriceSharedFare splits the total cost of a shared-cost trip between
+    // its driver and passengers. Fixed-price trips are left alone.
+    RepriceSharedFare(ctx context.Context, id int) error
+    
+    // DeleteLocations discards the location history of a trip.
+    DeleteLocations(ctx context.Context, id int) error
+    
+    // IsParticipant reports whether the user is the driver or a confirmed
+    // passenger of the trip.
+    IsParticipant(ctx context.Context, tripID, userID int) (bool, error)
+    
+    // DriverStats sums up the trips the user has driven.
+    DriverStats(ctx context.Context, driverID int) (*models.UserStats, error)
+    
+    // ActiveIDsForUser returns the active or in-progress trips the user
+    // drives or has a confirmed seat on.
+    ActiveIDsForUser(ctx context.Context, userID int) ([]int, error)
+    
+    // ListByDriver pages through the trips the user drives, latest departure
+    // first.
+    ListByDriver(ctx context.Context, driverID int, cursor string, limit int) (*models.TripPage, error)
+    
+    // Timeline pages through the trips the user drives or has booked, with
+    // their role and booking status on each. Upcoming trips come soonest
+    // first, past trips latest first. Cancelled bookings are left out unless
+    // asked for.
+    Timeline(ctx context.Context, userID int, filter models.TimelineFilter) (*models.TripPage, error)
+    
+    // Search pages through open trips matching the criteria for the user.
+    // Women-only trips are only shown to women.
+    Search(ctx context.Context, userID int, criteria models.TripSearchCriteria) (*models.TripPage, error)
+    
+    // ListBookable returns the future trips with seats left that the user
+    // could book, soonest first.
+    ListBookable(ctx context.Context, userID int) ([]models.Trip, error)
+    
+    // ClaimDueReminders marks active trips departing within lead as reminded
+    // and returns them, so each trip is only reminded once.
+    ClaimDueReminders(ctx context.Context, lead time.Duration) ([]models.Trip, error)
+    
+    // RecordChange stores a change to a trip, setting its ID and CreatedAt.
+    // A material change asks every passenger with an open booking to
+    // respond, and lists them in Passengers.
+    RecordChange(ctx context.Context, change *models.TripChange) error
+    
+    // ListChanges returns the changes to a trip, latest first.
+    ListChanges(ctx context.Context, tripID int) ([]models.TripChange, error)
+    
+    // GetChangeResponse returns the passenger's response to a change, nil
+    // if they haven't responded yet, and ErrNotFound if none was asked of
+    // them.
+    GetChangeResponse(ctx context.Context, changeID, tripID, passengerID int) (*string, error)
+    SetChangeResponse(ctx context.Context, changeID, passengerID int, response string) error
+    
+    // AwaitingResponse reports whether the passenger has yet to respond to
+    // a material change to the trip.
+    AwaitingResponse(ctx context.Context, tripID, passengerID int) (bool, error)
+}
+
+type BookingRepository interface {
+    // Get loads the passenger's booking on a trip, whatever its status.
+    Get(ctx context.Context, tripID, passengerID int) (*models.TripPassenger, error)
+    
+    // Create books a trip for a passenger, replacing a cancelled booking of
+    // theirs, and sets JoinedAt. A new booking hasn't paid any fare yet.
+    Create(ctx context.Context, booking *models.TripPassenger) error
+    SetStatus(ctx context.Context, tripID, passengerID int, status string) error
+    SetSeats(ctx context.Context, tripID, passengerID, seats int, guests []string) error
+    SetFare(ctx context.Context, tripID, passengerID int, fare money.Amount, seats int) error
+    
+    // Remove cancels a booking on the driver's behalf, for reason. Removed
+    // passengers can't book the trip again.
+    Remove(ctx context.Context, tripID, passengerID int, reason string) error
+    
+    // Manifest lists the pending and confirmed bookings of a trip, with the
+    // passengers' contact details, in the order they were made.
+    Manifest(ctx context.Context, tripID int) ([]models.TripPassenger, error)
+    
+    // CoPassengers lists the confirmed passengers of the trips other than
+    // the user, with their public profile only.
+    CoPassengers(ctx context.Context, tripIDs []int, userID int) ([]models.TripPassenger, error)
+    
+    // GetHold returns the passenger's hold on a trip, expired or not, or
+    // ErrNotFound. CreateHold holds seats on a trip for ttl, setting the
+    // hold's ID and times. ReleaseHolds gives back the seats of the
+    // passenger's hold on the trip if it hasn't expired, returning how many,
+    // and leaves expired holds to ReleaseExpiredHolds, which returns the
+    // holds it released.
+    GetHold(ctx context.Context, tripID, passengerID int) (*models.SeatHold, error)
+    CreateHold(ctx context.Context, hold *models.SeatHold, ttl time.Duration) error
+    ReleaseHolds(ctx context.Context, tripID, passengerID int) (int, error)
+    ReleaseExpiredHolds(ctx context.Context, tripID int) ([]models.SeatHold, error)
+    TripsWithExpiredHolds(ctx context.Context) ([]int, error)
+    
+    // AddToWaitlist puts a passenger in line for a trip, setting the entry's
+    // ID, CreatedAt and Position. It returns ErrDuplicate if they are
+    // already waiting or have been offered seats.
+    AddToWaitlist(ctx context.Context, entry *models.WaitlistEntry) error
+    
+    // GetWaitlistEntry loads the passenger's entry if they are waiting or
+    // have been offered seats.
+    GetWaitlistEntry(ctx context.Context, tripID, passengerID int) (*models.WaitlistEntry, error)
+    
+    // Waiting lists the passengers waiting for a trip, first in line first.
+    Waiting(ctx context.Context, tripID int) ([]models.WaitlistEntry, error)
+    SetWaitlistStatus(ctx context.Context, tripID, passengerID int, status string) error
+    
+    // OfferWaitlist marks the passenger as offered seats until expiresAt.
+    // ExpireWaitlistOffers expires the offers of a trip past their deadline.
+    OfferWaitlist(ctx context.Context, tripID, passengerID int, expiresAt time.Time) error
+    ExpireWaitlistOffers(ctx context.Context, tripID int) error
+}
+
+type AuditRepository interface {
+    // Record appends an event to the audit log, from the request req.
+    Record(ctx context.Context, event models.AuditEvent, req models.RequestInfo) error
+    
+    // List pages through the events matching the filter, most recent first.
+    List(ctx context.Context, filter models.AuditFilter) (*models.AuditPage, error)
+}
+
+type VehicleRepository interface {
+    // Create stores a new vehicle, setting its ID and timestamps.
+    Create(ctx context.Context, vehicle *models.Vehicle) error
+    
+    // ListByOwner returns the owner's vehicles, oldest first.
+    ListByOwner(ctx context.Context, ownerID int) ([]models.Vehicle, error)
+    
+    // Get loads one of the owner's vehicles.
+    Get(ctx context.Context, id, ownerID int) (*models.Vehicle, error)
+    
+    // GetForTrip loads the vehicle assigned to a trip.
+    GetForTrip(ctx context.Context, tripID int) (*models.Vehicle, error)
+    
+    // Update saves the owner's changes to a vehicle, setting UpdatedAt.
+    Update(ctx context.Context, vehicle *models.Vehicle) error
+    
+    // Delete removes one of the owner's vehicles. It returns ErrNotFound if
+    // they have no such vehicle or an active or in-progress trip uses it.
+    Delete(ctx context.Context, id, ownerID int) error
+    
+    // UpcomingSeats returns the most passenger seats offered by an active or
+    // in-progress trip using the vehicle, 0 if it has none.
+    UpcomingSeats(ctx context.Context, id int) (int, error)
+}
+
+type MessageRepository interface {
+    // Create stores a message, setting its ID and CreatedAt.
+    Create(ctx context.Context, message *models.TripMessage) error
+    
+    // List returns up to limit messages of a trip older than beforeID, or
+    // the latest when beforeID is 0, newest first and with their sender.
+    List(ctx context.Context, tripID, beforeID, limit int) ([]models.TripMessage, error)
+    
+    // Receipts returns how far each participant has read a trip's
+    // conversation, for those who read anything.
+    Receipts(ctx context.Context, tripID int) ([]models.MessageReceipt, error)
+    
+    // MarkRead and MarkNotified move up the last message the user read, or
+    // was emailed about. Neither ever moves back. MarkRead returns
+    // ErrNotFound if the message wasn't posted on the trip.
+    MarkRead(ctx context.Context, tripID, userID, messageID int) error
+    MarkNotified(ctx context.Context, tripID, userID, messageID int) error
+    
+    // UnreadDigests lists the participants of active and in-progress trips
+    // with messages from others posted before postedBefore that they have
+    // neither read nor been emailed about.
+    UnreadDigests(ctx context.Context, postedBefore time.Time) ([]models.UnreadDigest, error)
+}
+
+type LocationRepository interface {
+    // Record stores a position of the driver of an in-progress trip,
+    // setting its ID and RecordedAt. It returns ErrNotFound if the trip
+    // isn't theirs or isn't in progress.
+    Record(ctx context.Context, location *models.TripLocation, driverID int) error
+    
+    // Latest returns the latest position of a trip recorded after since.
+    Latest(ctx context.Context, tripID int, since time.Time) (*models.TripLocation, error)
+    
+    // SetPickup stores where a confirmed passenger wants to be picked up.
+    SetPickup(ctx context.Context, tripID, passengerID int, lat, lon float64) error
+    
+    // Pickups lists the pickup points the confirmed passengers of a trip set.
+    Pickups(ctx context.Context, tripID int) ([]models.PickupPoint, error)
+    
+    // PurgeBefore deletes the positions recorded before cutoff and returns
+    // how many it deleted.
+    PurgeBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type PaymentRepository interface {
+    // Record stores a transaction with its ledger entries, setting their IDs
+    // and CreatedAt. The entries must sum to zero.
+    Record(ctx context.Context, transaction *models.PaymentTransaction) error
+    
+    // ChargesForUpdate loads the charges of a booking made since the
+    // passenger last joined, newest first, with their ledger entries, the
+    // platform fee taken and what has been refunded or requested to be
+    // refunded against them. The charges stay locked until the transaction
+    // ends so no other refund can be requested against them.
+    ChargesForUpdate(ctx context.Context, bookingID int) ([]models.BookingCharge, error)
+    
+    // GetCharge loads a charge like ChargesForUpdate does, by its ID.
+    GetCharge(ctx context.Context, id int) (*models.BookingCharge, error)
+    
+    // Balances sums the user's ledger entries per account and currency.
+    Balances(ctx context.Context, userID int) ([]models.AccountBalance, error)
+    
+    // Entries returns the user's ledger entries, newest first.
+    Entries(ctx context.Context, userID, limit, offset int) ([]models.LedgerEntry, error)
+    
+    // RequestRefund stores a pending refund request, setting its ID, Status
+    // and timestamps.
+    RequestRefund(ctx context.Context, request *models.RefundRequest) error
+    
+    // GetRefundRequestForUpdate loads a refund request, locking it until the
+    // transaction ends.
+    GetRefundRequestForUpdate(ctx context.Context, id int) (*models.RefundRequest, error)
+    
+    // PendingRefunds returns up to limit pending refund requests last tried,
+    // or made, before cutoff, oldest first.
+    PendingRefunds(ctx context.Context, cutoff time.Time, limit int) ([]models.RefundRequest, error)
+    
+    // FailRefund records a failed attempt to pay a refund request.
+    FailRefund(ctx context.Context, id int, reason string) error
+    
+    // CompleteRefund marks a refund request paid by the ledger transaction.
+    CompleteRefund(ctx context.Context, id, transactionID int) error
+}
+
+// heldSeats adds up the seats of holds.
+func heldSeats(holds []models.SeatHold) int {
+    seats := 0
+    for _, hold := range holds {
+        seats += hold.Seats
+    }
+    return seats
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+    "context"
+    "log"
+    "sync"
+    "time"
+)
+
+// Router sends reads that can be a little stale to a read replica, when
+// there is one. Users who just changed something read from the primary for a
+// while, so they see their own change before the replica catches up. Recent
+// writes are only remembered by this process. The replica is optional: while
+// it is down every read goes to the primary.
+type Router struct {
+    primary    Store
+    replica    Store
+    stickiness time.Duration
+    
+    mu          sync.Mutex
+    lastWrite   map[int]time.Time
+    pruneAt     time.Time
+    replicaDown bool
+}
+
+// NewRouter routes reads between primary and replica, keeping a user on the
+// primary for stickiness after they write. With a nil replica every read
+// goes to the primary.
+func NewRouter(primary, replica Store, stickiness time.Duration) *Router {
+    return &Router{
+        primary:    primary,
+        replica:    replica,
+        stickiness: stickiness,
+        lastWrite:  map[int]time.Time{},
+    }
+}
+
+// Primary returns the store that takes writes.
+func (r *Router) Primary() Store {
+    return r.primary
+}
+
+// Reader returns the store to read from for the user.
+func (r *Router) Reader(userID int) Store {
+    if r.replica == nil {
+        return r.primary
+    }
+    
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    
+    if r.replicaDown {
+        return r.primary
+    }
+    
+    if wrote, ok := r.lastWrite[userID]; ok {
+        if time.Since(wrote) < r.stickiness {
+            return r.primary
+        }
+        delete(r.lastWrite, userID)
+    }
+    
+    return r.replica
+}
+
+// Wrote records that the users' data just changed on the primary.
+func (r *Router) Wrote(userIDs ...int) {
+    if r.replica == nil || r.stickiness <= 0 {
+        return
+    }
+    
+    now := time.Now()
+    
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    
+    // Forget users whose window has passed now and then, rather than on
+    // every read
+    if now.After(r.pruneAt) {
+        for userID, wrote := range r.lastWrite {
+            if now.Sub(wrote) >= r.stickiness {
+                delete(r.lastWrite, userID)
+            }
+        }
+        r.pruneAt = now.Add(r.stickiness)
+    }
+    
+    for _, userID := range userIDs {
+        r.lastWrite[userID] = now
+    }
+}
+
+// CheckReplica pings the replica, sending reads to the primary while it
+// doesn't answer and back to the replica once it does.
+func (r *Router) CheckReplica(ctx context.Context, ping func(ctx context.Context) error) {
+    if r.replica == nil {
+        return
+    }
+    
+    err := ping(ctx)
+    
+    r.mu.Lock()
+    wasDown := r.replicaDown
+    r.replicaDown = err != nil
+    r.mu.Unlock()
+    
+    if err != nil && !wasDown {
+        log.Printf("Read replica is unreachable, reading from the primary: %v", err)
+    } else if err == nil && wasDown {
+        log.Printf("Read replica is reachable again, reading from it")
+    }
+}
+
+// WatchReplica checks the replica every interval until ctx is cancelled.
+func (r *Router) WatchReplica(ctx context.Context, interval time.Duration, ping func(ctx context.Context) error) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            r.CheckReplica(ctx, ping)
+        }
+    }
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+)
+
+func TestReaderFallsBackToPrimaryWhileReplicaIsDown(t *testing.T) {
+    ctx := context.Background()
+    primary, replica := NewMemoryStore(), NewMemoryStore()
+    router := NewRouter(primary, replica, time.Minute)
+
+    if router.Reader(1) != replica {
+        t.Fatal("reads don't go to the replica")
+    }
+
+    router.CheckReplica(ctx, func(ctx context.Context) error { return errors.New("connection refused") })
+    if router.Reader(1) != primary {
+        t.Error("reads go to the replica while it is down")
+    }
+
+    router.CheckReplica(ctx, func(ctx context.Context) error { return nil })
+    if router.Reader(1) != replica {
+        t.Error("reads don't go back to the replica once it answers")
+    }
+}
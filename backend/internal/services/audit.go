@@ -0,0 +1,52 @@
+package services
+
+import (
+    "context"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
+)
+
+// auditTrip records an action on a trip, or on one of its bookings, by actorID.
+// It is written with the change it describes, in the same transaction, so
+// neither is kept without the other.
+func auditTrip(ctx context.Context, tx repository.Store, req models.RequestInfo, tripID, actorID int, action string, changes map[string]models.FieldChange) error {
+    return tx.Audit().Record(ctx, models.AuditEvent{
+        EntityType: models.AuditEntityTrip,
+        EntityID:   tripID,
+        Action:     action,
+        ActorID:    &actorID,
+        Changes:    changes,
+    }, req)
+}
+
+// createdFields turns the fields of a new record, as diffed against its zero
+// value, into changes from nothing.
+func createdFields(changes map[string]models.FieldChange) map[string]models.FieldChange {
+    for field, change := range changes {
+        changes[field] = models.FieldChange{To: change.To}
+    }
+    return changes
+}
+
+// bookingChanges describes a change to a passenger's booking.
+func bookingChanges(passengerID int, fromStatus, toStatus interface{}, fromSeats, toSeats interface{}) map[string]models.FieldChange {
+    return map[string]models.FieldChange{
+        "passengerId": {From: passengerID, To: passengerID},
+        "status":      {From: fromStatus, To: toStatus},
+        "seats":       {From: fromSeats, To: toSeats},
+    }
+}
+
+type AuditService struct {
+    store repository.Store
+}
+
+func NewAuditService(store repository.Store) *AuditService {
+    return &AuditService{store: store}
+}
+
+// ListEvents pages through the audit log matching the filter, most recent
+// first.
+func (s *AuditService) ListEvents(ctx context.Context, filter models.AuditFilter) (*models.AuditPage, error) {
+    return s.store.Audit().List(ctx, filter)
+}
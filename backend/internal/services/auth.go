@@ -1,126 +1,131 @@
 package services
 
 import (
-    "database/sql"
+    "context"
+    "errors"
     "fmt"
     "rideshare-backend/internal/config"
     "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
 )
 
 type AuthService struct {
-    db     *sql.DB
-    config *config.Config
+    store   repository.Store
+    config  *config.Config
+    request models.RequestInfo
 }
 
-func NewAuthService(db *sql.DB, cfg *config.Config) *AuthService {
+func NewAuthService(store repository.Store, cfg *config.Config) *AuthService {
     return &AuthService{
-        db:     db,
+        store:  store,
         config: cfg,
     }
 }
 
-func (s *AuthService) CreateUser(user *models.User) error {
-    query := `
-        INSERT INTO users (name, email, password, phone, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, NOW(), NOW())
-        RETURNING id, created_at, updated_at
-    `
-    
-    err := s.db.QueryRow(
-        query,
-        user.Name,
-        user.Email,
-        user.Password,
-        user.Phone,
-    ).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
-    
-    if err != nil {
-        return fmt.Errorf("failed to create user: %w", err)
-    }
-    
-    return nil
+// ForRequest returns a copy of the service recording the changes it makes in
+// the audit log as coming from req.
+func (s *AuthService) ForRequest(req models.RequestInfo) *AuthService {
+    clone := *s
+    clone.request = req
+    return &clone
 }
 
-func (s *AuthService) GetUserByEmail(email string) (*models.User, error) {
-    user := &models.User{}
-    query := `
-        SELECT id, name, email, password, phone, profile_image, is_verified, created_at, updated_at
-        FROM users
-        WHERE email = $1
-    `
-    
-    err := s.db.QueryRow(query, email).Scan(
-        &user.ID,
-        &user.Name,
-        &user.Email,
-        &user.Password,
-        &user.Phone,
-        &user.ProfileImage,
-        &user.IsVerified,
-        &user.CreatedAt,
-        &user.UpdatedAt,
-    )
-    
+func (s *AuthService) CreateUser(ctx context.Context, user *models.User) error {
+    return s.store.InTx(ctx, func(tx repository.Store) error {
+        if err := tx.Users().Create(ctx, user); err != nil {
+            return err
+        }
+        
+        changes := diffUser(&models.User{}, user)
+        changes["email"] = models.FieldChange{To: user.Email}
+        return s.auditUser(ctx, tx, user.ID, models.AuditCreate, createdFields(changes))
+    })
+}
+
+func (s *AuthService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+    user, err := s.store.Users().GetByEmail(ctx, email)
     if err != nil {
-        if err == sql.ErrNoRows {
+        if errors.Is(err, repository.ErrNotFound) {
             return nil, fmt.Errorf("user not found")
         }
-        return nil, fmt.Errorf("failed to get user: %w", err)
+        return nil, err
     }
     
     return user, nil
 }
 
-func (s *AuthService) GetUserByID(id int) (*models.User, error) {
-    user := &models.User{}
-    query := `
-        SELECT id, name, email, phone, profile_image, is_verified, created_at, updated_at
-        FROM users
-        WHERE id = $1
-    `
-    
-    err := s.db.QueryRow(query, id).Scan(
-        &user.ID,
-        &user.Name,
-        &user.Email,
-        &user.Phone,
-        &user.ProfileImage,
-        &user.IsVerified,
-        &user.CreatedAt,
-        &user.UpdatedAt,
-    )
-    
+func (s *AuthService) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+    user, err := s.store.Users().GetByID(ctx, id)
     if err != nil {
-        if err == sql.ErrNoRows {
+        if errors.Is(err, repository.ErrNotFound) {
             return nil, fmt.Errorf("user not found")
         }
-        return nil, fmt.Errorf("failed to get user: %w", err)
+        return nil, err
     }
     
     return user, nil
 }
 
-func (s *AuthService) UpdateUser(user *models.User) error {
-    query := `
-        UPDATE users
-        SET name = $1, phone = $2, profile_image = $3, updated_at = NOW()
-        WHERE id = $4
-        RETURNING updated_at
-    `
-    
-    err := s.db.QueryRow(
-        query,
-        user.Name,
-        user.Phone,
-        user.ProfileImage,
-        user.ID,
-    ).Scan(&user.UpdatedAt)
+func (s *AuthService) UpdateUser(ctx context.Context, user *models.User) error {
+    return s.store.InTx(ctx, func(tx repository.Store) error {
+        old, err := tx.Users().GetForUpdate(ctx, user.ID)
+        if err != nil {
+            if errors.Is(err, repository.ErrNotFound) {
+                return fmt.Errorf("user not found")
+            }
+            return err
+        }
+        
+        if err := tx.Users().Update(ctx, user); err != nil {
+            return err
+        }
+        
+        changes := diffUser(old, user)
+        if len(changes) == 0 {
+            return nil
+        }
+        return s.auditUser(ctx, tx, user.ID, models.AuditUpdate, changes)
+    })
+}
+
+// diffUser lists the profile fields that differ between two versions of a
+// user.
+func diffUser(old, updated *models.User) map[string]models.FieldChange {
+    changes := map[string]models.FieldChange{}
     
-    if err != nil {
-        return fmt.Errorf("failed to update user: %w", err)
+    if old.Name != updated.Name {
+        changes["name"] = models.FieldChange{From: old.Name, To: updated.Name}
+    }
+    if old.Phone != updated.Phone {
+        changes["phone"] = models.FieldChange{From: old.Phone, To: updated.Phone}
+    }
+    if stringValue(old.ProfileImage) != stringValue(updated.ProfileImage) {
+        changes["profileImage"] = models.FieldChange{From: old.ProfileImage, To: updated.ProfileImage}
+    }
+    if stringValue(old.Gender) != stringValue(updated.Gender) {
+        changes["gender"] = models.FieldChange{From: old.Gender, To: updated.Gender}
+    }
+    if old.Timezone != updated.Timezone {
+        changes["timezone"] = models.FieldChange{From: old.Timezone, To: updated.Timezone}
     }
     
-    return nil
+    return changes
+}
+
+// auditUser records an action on a user's account, made by the user.
+func (s *AuthService) auditUser(ctx context.Context, tx repository.Store, userID int, action string, changes map[string]models.FieldChange) error {
+    return tx.Audit().Record(ctx, models.AuditEvent{
+        EntityType: models.AuditEntityUser,
+        EntityID:   userID,
+        Action:     action,
+        ActorID:    &userID,
+        Changes:    changes,
+    }, s.request)
+}
+
+// IsAdmin reports whether the user may administer the service.
+func (s *AuthService) IsAdmin(ctx context.Context, userID int) (bool, error) {
+    return s.store.Users().IsAdmin(ctx, userID)
 }
 
 func (s *AuthService) SendWelcomeEmail(email, name string) error {
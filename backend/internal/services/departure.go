@@ -0,0 +1,88 @@
+package services
+
+import (
+    "fmt"
+    "rideshare-backend/internal/models"
+    "time"
+)
+
+// Time-of-day windows without a date range are searched this far ahead, and
+// a date range searched by time of day may span at most this many days.
+const maxWindowDays = 31
+
+// TimeOfDay is a daily departure window in local hours, [FromHour, ToHour).
+// Windows ending at or before they start run past midnight.
+type TimeOfDay struct {
+    FromHour int
+    ToHour   int
+}
+
+// Named departure windows
+var TimesOfDay = map[string]TimeOfDay{
+    "morning":   {FromHour: 5, ToHour: 12},
+    "afternoon": {FromHour: 12, ToHour: 17},
+    "evening":   {FromHour: 17, ToHour: 22},
+    "night":     {FromHour: 22, ToHour: 5},
+}
+
+// DepartureRanges turns a search window into UTC ranges on departure time.
+// after and before bound the search and may be zero; window, if set, keeps
+// only departures at those hours of the day in loc. Days are walked in loc so
+// daylight saving changes are respected.
+func DepartureRanges(after, before time.Time, window *TimeOfDay, loc *time.Location, now time.Time) ([]models.TimeRange, error) {
+    if !after.IsZero() && !before.IsZero() && !before.After(after) {
+        return nil, fmt.Errorf("departure range ends before it starts")
+    }
+
+    if window == nil {
+        if after.IsZero() && before.IsZero() {
+            return nil, nil
+        }
+        return []models.TimeRange{{Start: after, End: before}}, nil
+    }
+
+    start := after
+    if start.IsZero() {
+        start = now
+    }
+    end := before
+    if end.IsZero() {
+        end = start.AddDate(0, 0, maxWindowDays)
+    }
+
+    if end.Sub(start) > maxWindowDays*24*time.Hour {
+        return nil, fmt.Errorf("time-of-day search can span at most %d days", maxWindowDays)
+    }
+
+    // Start a day early so a window running past midnight into the first day
+    // is included
+    local := start.In(loc)
+    day := time.Date(local.Year(), local.Month(), local.Day()-1, 0, 0, 0, 0, loc)
+
+    var ranges []models.TimeRange
+    for !day.After(end) {
+        from := time.Date(day.Year(), day.Month(), day.Day(), window.FromHour, 0, 0, 0, loc)
+        to := time.Date(day.Year(), day.Month(), day.Day(), window.ToHour, 0, 0, 0, loc)
+        if window.ToHour <= window.FromHour {
+            to = time.Date(day.Year(), day.Month(), day.Day()+1, window.ToHour, 0, 0, 0, loc)
+        }
+
+        if from.Before(start) {
+            from = start
+        }
+        if to.After(end) {
+            to = end
+        }
+        if to.After(from) {
+            ranges = append(ranges, models.TimeRange{Start: from.UTC(), End: to.UTC()})
+        }
+
+        day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+    }
+
+    if len(ranges) == 0 {
+        return nil, fmt.Errorf("no departure times match this window")
+    }
+
+    return ranges, nil
+}
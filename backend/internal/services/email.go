@@ -3,6 +3,8 @@ package services
 import (
     "fmt"
     "rideshare-backend/internal/config"
+    "rideshare-backend/internal/models"
+    "time"
     
     "gopkg.in/gomail.v2"
 )
@@ -17,6 +19,12 @@ func NewEmailService(cfg *config.Config) *EmailService {
     }
 }
 
+// localTime renders a departure in its own timezone, which callers set to the
+// trip's origin.
+func localTime(t time.Time) string {
+    return t.Format("Mon 2 Jan 2006, 15:04 MST")
+}
+
 func (s *EmailService) SendWelcomeEmail(email, name string) error {
     m := gomail.NewMessage()
     m.SetHeader("From", s.config.EmailUser)
@@ -53,7 +61,7 @@ func (s *EmailService) SendWelcomeEmail(email, name string) error {
     return nil
 }
 
-func (s *EmailService) SendTripMatchNotification(passengerEmail, passengerName, driverName, from, to string) error {
+func (s *EmailService) SendTripMatchNotification(passengerEmail, passengerName, driverName, from, to string, departure time.Time) error {
     m := gomail.NewMessage()
     m.SetHeader("From", s.config.EmailUser)
     m.SetHeader("To", passengerEmail)
@@ -66,6 +74,7 @@ func (s *EmailService) SendTripMatchNotification(passengerEmail, passengerName,
             <p>You've successfully joined a trip:</p>
             <div style="background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 15px 0;">
                 <p><strong>Route:</strong> %s → %s</p>
+                <p><strong>Departure:</strong> %s</p>
                 <p><strong>Driver:</strong> %s</p>
             </div>
             <p>Please check your dashboard for more details and contact information.</p>
@@ -74,7 +83,7 @@ func (s *EmailService) SendTripMatchNotification(passengerEmail, passengerName,
             <p>The RideShare Team</p>
         </body>
         </html>
-    `, passengerName, from, to, driverName, s.config.FrontendURL)
+    `, passengerName, from, to, localTime(departure), driverName, s.config.FrontendURL)
     
     m.SetBody("text/html", body)
     
@@ -83,7 +92,7 @@ func (s *EmailService) SendTripMatchNotification(passengerEmail, passengerName,
     return d.DialAndSend(m)
 }
 
-func (s *EmailService) SendTripCancellationEmail(email, name, from, to string) error {
+func (s *EmailService) SendTripCancellationEmail(email, name, from, to string, departure time.Time) error {
     m := gomail.NewMessage()
     m.SetHeader("From", s.config.EmailUser)
     m.SetHeader("To", email)
@@ -97,6 +106,7 @@ func (s *EmailService) SendTripCancellationEmail(email, name, from, to string) e
             <p>We regret to inform you that the following trip has been cancelled:</p>
             <div style="background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 15px 0;">
                 <p><strong>Route:</strong> %s → %s</p>
+                <p><strong>Departure:</strong> %s</p>
             </div>
             <p>We apologize for any inconvenience caused. Please search for alternative trips on our platform.</p>
             <p><a href="%s/search" style="background-color: #007bff; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px;">Find Alternative Trips</a></p>
@@ -104,7 +114,126 @@ func (s *EmailService) SendTripCancellationEmail(email, name, from, to string) e
             <p>The RideShare Team</p>
         </body>
         </html>
-    `, name, from, to, s.config.FrontendURL)
+    `, name, from, to, localTime(departure), s.config.FrontendURL)
+    
+    m.SetBody("text/html", body)
+    
+    d := gomail.NewDialer(s.config.EmailHost, s.config.EmailPort, s.config.EmailUser, s.config.EmailPassword)
+    
+    return d.DialAndSend(m)
+}
+
+// SendTripChangeEmail tells a booked passenger the trip changed materially
+// and that they can keep their booking or cancel it with a full refund.
+func (s *EmailService) SendTripChangeEmail(email, name string, trip *models.Trip, change *models.TripChange) error {
+    m := gomail.NewMessage()
+    m.SetHeader("From", s.config.EmailUser)
+    m.SetHeader("To", email)
+    m.SetHeader("Subject", "Your Trip Has Changed")
+    
+    var details string
+    for _, line := range describeChange(change) {
+        details += fmt.Sprintf("<p>%s</p>", line)
+    }
+    
+    body := fmt.Sprintf(`
+        <html>
+        <body>
+            <h2>Trip Change Notice</h2>
+            <p>Dear %s,</p>
+            <p>The driver has changed your trip from %s to %s:</p>
+            <div style="background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 15px 0;">
+                %s
+            </div>
+            <p>You can keep your booking, or cancel it and get a full refund.</p>
+            <p><a href="%s/dashboard/trips/%d" style="background-color: #007bff; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px;">Review Booking</a></p>
+            <p>The RideShare Team</p>
+        </body>
+        </html>
+    `, name, trip.FromLocation, trip.ToLocation, details, s.config.FrontendURL, trip.ID)
+    
+    m.SetBody("text/html", body)
+    
+    d := gomail.NewDialer(s.config.EmailHost, s.config.EmailPort, s.config.EmailUser, s.config.EmailPassword)
+    
+    return d.DialAndSend(m)
+}
+
+// describeChange summarises the changes passengers care about most.
+func describeChange(change *models.TripChange) []string {
+    var lines []string
+    
+    for _, field := range []struct{ key, label string }{{"from", "Pickup"}, {"to", "Destination"}} {
+        if c, ok := change.Changes[field.key]; ok {
+            lines = append(lines, fmt.Sprintf("<strong>%s:</strong> %v → %v", field.label, c.From, c.To))
+        }
+    }
+    
+    if c, ok := change.Changes["departureTime"]; ok {
+        from, _ := c.From.(time.Time)
+        to, _ := c.To.(time.Time)
+        lines = append(lines, fmt.Sprintf("<strong>Departure:</strong> %s → %s", localTime(from), localTime(to)))
+    }
+    
+    if c, ok := change.Changes["pricePerPerson"]; ok {
+        lines = append(lines, fmt.Sprintf("<strong>Price per seat:</strong> %v → %v", c.From, c.To))
+    }
+    
+    return lines
+}
+
+// SendWaitlistOfferEmail tells a waitlisted passenger seats are held for
+// them until the deadline.
+func (s *EmailService) SendWaitlistOfferEmail(email, name, from, to string, departure, deadline time.Time, tripID, seats int) error {
+    m := gomail.NewMessage()
+    m.SetHeader("From", s.config.EmailUser)
+    m.SetHeader("To", email)
+    m.SetHeader("Subject", "Seats Available on Your Waitlisted Trip")
+    
+    body := fmt.Sprintf(`
+        <html>
+        <body>
+            <h2>Good news, %s!</h2>
+            <p>Seats have opened up on a trip you were waiting for:</p>
+            <div style="background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 15px 0;">
+                <p><strong>Route:</strong> %s → %s</p>
+                <p><strong>Departure:</strong> %s</p>
+                <p><strong>Seats held for you:</strong> %d</p>
+            </div>
+            <p>Confirm your booking before <strong>%s</strong>, after which the seats go to the next passenger in line.</p>
+            <p><a href="%s/dashboard/trips/%d" style="background-color: #007bff; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px;">Confirm Booking</a></p>
+            <p>The RideShare Team</p>
+        </body>
+        </html>
+    `, name, from, to, localTime(departure), seats, localTime(deadline), s.config.FrontendURL, tripID)
+    
+    m.SetBody("text/html", body)
+    
+    d := gomail.NewDialer(s.config.EmailHost, s.config.EmailPort, s.config.EmailUser, s.config.EmailPassword)
+    
+    return d.DialAndSend(m)
+}
+
+func (s *EmailService) SendUnreadMessagesEmail(email, name, from, to string, departure time.Time, tripID, unreadCount int) error {
+    m := gomail.NewMessage()
+    m.SetHeader("From", s.config.EmailUser)
+    m.SetHeader("To", email)
+    m.SetHeader("Subject", "New messages about your trip")
+    
+    body := fmt.Sprintf(`
+        <html>
+        <body>
+            <h2>Hi %s,</h2>
+            <p>You have %d unread message(s) about your trip:</p>
+            <div style="background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 15px 0;">
+                <p><strong>Route:</strong> %s → %s</p>
+                <p><strong>Departure:</strong> %s</p>
+            </div>
+            <p><a href="%s/dashboard/trips/%d" style="background-color: #007bff; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px;">Read Messages</a></p>
+            <p>The RideShare Team</p>
+        </body>
+        </html>
+    `, name, unreadCount, from, to, localTime(departure), s.config.FrontendURL, tripID)
     
     m.SetBody("text/html", body)
     
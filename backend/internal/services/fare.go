@@ -0,0 +1,69 @@
+package services
+
+import (
+    "rideshare-backend/internal/config"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/money"
+    "rideshare-backend/internal/utils"
+)
+
+type FareService struct {
+    config *config.Config
+}
+
+func NewFareService(cfg *config.Config) *FareService {
+    return &FareService{config: cfg}
+}
+
+type FareInput struct {
+    DistanceKm     float64
+    FuelPrice      money.Rate // per litre
+    LitresPer100Km float64
+    Seats          int
+}
+
+// RouteDistanceKm estimates the driving distance between two points from the
+// straight-line distance and the configured route factor.
+func (s *FareService) RouteDistanceKm(fromLat, fromLon, toLat, toLon float64) float64 {
+    return utils.HaversineKm(fromLat, fromLon, toLat, toLon) * s.config.RouteDistanceFactor
+}
+
+// Suggest proposes a per-seat cost share for a trip. The fuel cost is split
+// evenly between the driver and a full car of passengers.
+func (s *FareService) Suggest(input FareInput) models.FareSuggestion {
+    fuelPrice := input.FuelPrice
+    if fuelPrice.Currency == "" {
+        fuelPrice = s.config.FuelPrice
+    }
+    
+    efficiency := input.LitresPer100Km
+    if efficiency <= 0 {
+        efficiency = s.config.LitresPer100Km
+    }
+    
+    litres := input.DistanceKm * efficiency / 100
+    
+    // Distance and consumption are estimates anyway; round once to whole
+    // minor units and keep every later step exact
+    total := fuelPrice.Times(litres)
+    
+    return models.FareSuggestion{
+        DistanceKm:     input.DistanceKm,
+        FuelLitres:     litres,
+        LitresPer100Km: efficiency,
+        FuelPrice:      fuelPrice,
+        TotalCost:      money.New(total, fuelPrice.Currency),
+        Seats:          input.Seats,
+        PerSeat:        money.New(SharedFare(total, input.Seats), fuelPrice.Currency),
+    }
+}
+
+// SharedFare is what each of the given number of passengers pays when the
+// total cost is split between them and the driver, rounded up to the next
+// minor unit. With no passengers yet it quotes the price for the first one.
+func SharedFare(total money.Amount, passengers int) money.Amount {
+    if passengers < 1 {
+        passengers = 1
+    }
+    return total.Split(passengers + 1)[0]
+}
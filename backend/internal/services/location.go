@@ -0,0 +1,107 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "rideshare-backend/internal/config"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
+    "rideshare-backend/internal/utils"
+    "time"
+)
+
+// Below this speed a reported position is treated as stationary and the
+// configured average speed is used for ETAs instead.
+const minETASpeedKmh = 5.0
+
+type LocationService struct {
+    store  repository.Store
+    config *config.Config
+}
+
+func NewLocationService(store repository.Store, cfg *config.Config) *LocationService {
+    return &LocationService{
+        store:  store,
+        config: cfg,
+    }
+}
+
+// RecordPosition stores a driver position for a trip that is in progress.
+func (s *LocationService) RecordPosition(ctx context.Context, location *models.TripLocation, driverID int) error {
+    if err := s.store.Locations().Record(ctx, location, driverID); err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            return fmt.Errorf("trip not found, unauthorized or not in progress")
+        }
+        return err
+    }
+
+    return nil
+}
+
+func (s *LocationService) GetLatestPosition(ctx context.Context, tripID int) (*models.TripLocation, error) {
+    location, err := s.store.Locations().Latest(ctx, tripID, time.Now().Add(-s.config.LocationRetention))
+    if err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            return nil, nil
+        }
+        return nil, err
+    }
+
+    return location, nil
+}
+
+// SetPickupPoint stores where a confirmed passenger wants to be picked up.
+func (s *LocationService) SetPickupPoint(ctx context.Context, tripID, passengerID int, lat, lon float64) error {
+    if err := s.store.Locations().SetPickup(ctx, tripID, passengerID, lat, lon); err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            return fmt.Errorf("you are not a confirmed passenger of this trip")
+        }
+        return err
+    }
+
+    return nil
+}
+
+func (s *LocationService) GetPickupPoints(ctx context.Context, tripID int) ([]models.PickupPoint, error) {
+    return s.store.Locations().Pickups(ctx, tripID)
+}
+
+// EstimateArrivals computes the ETA from the driver's position to each pickup
+// point using straight-line distance scaled by the configured route factor.
+func (s *LocationService) EstimateArrivals(position *models.TripLocation, pickups []models.PickupPoint) []models.PickupETA {
+    speed := s.config.AverageDriverSpeedKmh
+    if position.SpeedKmh != nil && *position.SpeedKmh >= minETASpeedKmh {
+        speed = *position.SpeedKmh
+    }
+
+    etas := make([]models.PickupETA, 0, len(pickups))
+    for _, pickup := range pickups {
+        distance := utils.HaversineKm(position.Latitude, position.Longitude, pickup.Latitude, pickup.Longitude) * s.config.RouteDistanceFactor
+        minutes := distance / speed * 60
+
+        etas = append(etas, models.PickupETA{
+            PassengerID: pickup.PassengerID,
+            DistanceKm:  distance,
+            ETAMinutes:  minutes,
+            ArrivesAt:   position.RecordedAt.Add(time.Duration(minutes * float64(time.Minute))),
+        })
+    }
+
+    return etas
+}
+
+// PurgeExpired deletes positions older than the retention window.
+func (s *LocationService) PurgeExpired(ctx context.Context) (int64, error) {
+    return s.store.Locations().PurgeBefore(ctx, time.Now().Add(-s.config.LocationRetention))
+}
+
+// RunRetention blocks until ctx is cancelled, purging expired positions every interval.
+func (s *LocationService) RunRetention(ctx context.Context, interval time.Duration) {
+    runEvery(ctx, interval, func(ctx context.Context) {
+        if _, err := s.PurgeExpired(ctx); err != nil {
+            log.Printf("Failed to purge expired locations: %v", err)
+        }
+    })
+}
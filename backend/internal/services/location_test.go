@@ -0,0 +1,74 @@
+package services
+
+import (
+    "context"
+    "rideshare-backend/internal/config"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
+    "testing"
+    "time"
+)
+
+func TestRecordPositionOnlyWhileInProgress(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    trips := newTestTripService(store)
+    locations := NewLocationService(store, &config.Config{LocationRetention: time.Hour})
+
+    driver := newTestUser(t, store, "driver")
+    passenger := newTestUser(t, store, "passenger")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+
+    position := &models.TripLocation{TripID: trip.ID, Latitude: 52.52, Longitude: 13.40}
+    if err := locations.RecordPosition(ctx, position, driver.ID); err == nil {
+        t.Fatal("recording a position before the trip started succeeded")
+    }
+
+    if err := trips.StartTrip(ctx, trip.ID, driver.ID); err != nil {
+        t.Fatalf("StartTrip: %v", err)
+    }
+    if err := locations.RecordPosition(ctx, position, passenger.ID); err == nil {
+        t.Error("a passenger recorded the driver's position")
+    }
+    if err := locations.RecordPosition(ctx, position, driver.ID); err != nil {
+        t.Fatalf("RecordPosition: %v", err)
+    }
+
+    latest, err := locations.GetLatestPosition(ctx, trip.ID)
+    if err != nil {
+        t.Fatalf("GetLatestPosition: %v", err)
+    }
+    if latest == nil || latest.ID != position.ID {
+        t.Errorf("latest position = %+v, want %+v", latest, position)
+    }
+}
+
+func TestSetPickupPointNeedsConfirmedBooking(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    trips := newTestTripService(store)
+    locations := NewLocationService(store, &config.Config{LocationRetention: time.Hour})
+
+    driver := newTestUser(t, store, "driver")
+    passenger := newTestUser(t, store, "passenger")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+
+    if err := locations.SetPickupPoint(ctx, trip.ID, passenger.ID, 52.5, 13.4); err == nil {
+        t.Fatal("setting a pickup point without a booking succeeded")
+    }
+
+    if _, err := trips.JoinTrip(ctx, trip.ID, passenger.ID, 1, nil); err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+    if err := locations.SetPickupPoint(ctx, trip.ID, passenger.ID, 52.5, 13.4); err != nil {
+        t.Fatalf("SetPickupPoint: %v", err)
+    }
+
+    pickups, err := locations.GetPickupPoints(ctx, trip.ID)
+    if err != nil {
+        t.Fatalf("GetPickupPoints: %v", err)
+    }
+    if len(pickups) != 1 || pickups[0].PassengerID != passenger.ID {
+        t.Errorf("pickups = %+v, want the passenger's", pickups)
+    }
+}
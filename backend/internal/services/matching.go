@@ -1,93 +1,79 @@
 package services
 
 import (
-    "database/sql"
-    "fmt"
+    "context"
     "math"
     "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
     "sort"
     "strings"
 )
 
+// Share of a match's score given to the passenger's saved preferences; the
+// rest comes from how well the route matches.
+const preferenceWeight = 0.2
+
 type MatchingService struct {
-    db *sql.DB
+    router      *repository.Router
+    preferences *PreferenceService
 }
 
-func NewMatchingService(db *sql.DB) *MatchingService {
-    return &MatchingService{db: db}
+// NewMatchingService looks for matches on the router's replica.
+func NewMatchingService(router *repository.Router, preferences *PreferenceService) *MatchingService {
+    return &MatchingService{
+        router:      router,
+        preferences: preferences,
+    }
 }
 
 
 // Enhanced FindMatchingTrips with better filtering and scoring
-func (s *MatchingService) FindMatchingTrips(userID int, from, to string, maxDistance float64) ([]models.TripMatch, error) {
+func (s *MatchingService) FindMatchingTrips(ctx context.Context, userID int, from, to string, maxDistance float64) ([]models.TripMatch, error) {
     // Set minimum similarity threshold to filter out irrelevant results
     minSimilarity := 0.3 // 30% minimum similarity
     
-    query := `
-        SELECT t.id, t.driver_id, t.from_location, t.to_location, t.departure_time,
-               t.max_passengers, t.current_passengers, t.price_per_person, t.description,
-               t.status, t.created_at, t.updated_at
-        FROM trips t
-        WHERE t.status = 'active' 
-        AND t.driver_id != $1
-        AND t.current_passengers < t.max_passengers
-        AND t.departure_time > NOW()
-        ORDER BY t.departure_time ASC
-    `
+    // Saved preferences only reorder matches, they never hide a trip
+    prefs, err := s.preferences.GetUserPreferences(ctx, userID)
+    if err != nil {
+        return nil, err
+    }
     
-    rows, err := s.db.Query(query, userID)
+    trips, err := s.router.Reader(userID).Trips().ListBookable(ctx, userID)
     if err != nil {
-        return nil, fmt.Errorf("failed to query trips: %w", err)
+        return nil, err
     }
-    defer rows.Close()
     
     var allMatches []models.TripMatch
     
-    for rows.Next() {
-        var trip models.Trip
-        
-        err := rows.Scan(
-            &trip.ID,
-            &trip.DriverID,
-            &trip.FromLocation,
-            &trip.ToLocation,
-            &trip.DepartureTime,
-            &trip.MaxPassengers,
-            &trip.CurrentPassengers,
-            &trip.PricePerPerson,
-            &trip.Description,
-            &trip.Status,
-            &trip.CreatedAt,
-            &trip.UpdatedAt,
-        )
-        if err != nil {
-            continue
-        }
-        
+    for _, trip := range trips {
         // Calculate comprehensive similarity and distance scores
         similarity := s.calculateEnhancedSimilarity(from, to, trip.FromLocation, trip.ToLocation)
         distance := s.calculateDistance(from, to, trip.FromLocation, trip.ToLocation)
         
         // Include all trips that meet minimum criteria
         if similarity >= minSimilarity && distance <= maxDistance {
+            preferenceScore := PreferenceScore(trip.Preferences, prefs)
+            
             match := models.TripMatch{
-                TripID:      trip.ID,
-                PassengerID: userID,
-                Similarity:  similarity,
-                Distance:    distance,
+                TripID:          trip.ID,
+                PassengerID:     userID,
+                Similarity:      similarity,
+                Distance:        distance,
+                PreferenceScore: preferenceScore,
+                Score:           similarity*(1-preferenceWeight) + preferenceScore*preferenceWeight,
             }
             
             allMatches = append(allMatches, match)
         }
     }
     
-    // Sort by similarity (highest first), then by distance (lowest first)
+    // Sort by score (highest first), then by distance (lowest first)
     sort.Slice(allMatches, func(i, j int) bool {
-        if math.Abs(allMatches[i].Similarity - allMatches[j].Similarity) < 0.01 {
-            // If similarity is very close, prefer lower distance
+        if math.Abs(allMatches[i].Score - allMatches[j].Score) < 0.01 {
+            // If the score is very close, prefer lower distance
             return allMatches[i].Distance < allMatches[j].Distance
         }
-        return allMatches[i].Similarity > allMatches[j].Similarity
+        return allMatches[i].Score > allMatches[j].Score
     })
     
     return allMatches, nil
@@ -281,8 +267,8 @@ func min(a, b, c int) int {
 }
 
 // Get top N matches
-func (s *MatchingService) GetTopMatches(userID int, from, to string, maxDistance float64, limit int) ([]models.TripMatch, error) {
-    allMatches, err := s.FindMatchingTrips(userID, from, to, maxDistance)
+func (s *MatchingService) GetTopMatches(ctx context.Context, userID int, from, to string, maxDistance float64, limit int) ([]models.TripMatch, error) {
+    allMatches, err := s.FindMatchingTrips(ctx, userID, from, to, maxDistance)
     if err != nil {
         return nil, err
     }
@@ -0,0 +1,70 @@
+package services
+
+import (
+    "context"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
+    "testing"
+)
+
+func TestFindMatchingTripsRanksSavedPreferences(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    trips := newTestTripService(store)
+    preferences := NewPreferenceService(store)
+    matching := NewMatchingService(repository.NewRouter(store, nil, 0), preferences)
+
+    driver := newTestUser(t, store, "driver")
+    passenger := newTestUser(t, store, "passenger")
+
+    noPets := newTestTrip(t, trips, driver.ID, 3, 1500)
+    pets := newTestTrip(t, trips, driver.ID, 3, 1500)
+    pets.Preferences.PetsAllowed = true
+    if err := store.Trips().Update(ctx, pets); err != nil {
+        t.Fatalf("update trip: %v", err)
+    }
+
+    wantPets := true
+    if err := preferences.SaveUserPreferences(ctx, passenger.ID, models.PreferenceFilter{PetsAllowed: &wantPets}); err != nil {
+        t.Fatalf("SaveUserPreferences: %v", err)
+    }
+
+    matches, err := matching.FindMatchingTrips(ctx, passenger.ID, "Berlin", "Hamburg", 100)
+    if err != nil {
+        t.Fatalf("FindMatchingTrips: %v", err)
+    }
+    if len(matches) != 2 {
+        t.Fatalf("%d matches, want 2", len(matches))
+    }
+    if matches[0].TripID != pets.ID || matches[1].TripID != noPets.ID {
+        t.Errorf("matches ranked %d, %d; want the trip allowing pets first", matches[0].TripID, matches[1].TripID)
+    }
+    if matches[1].PreferenceScore != 0 {
+        t.Errorf("preference score of the trip without pets = %v, want 0", matches[1].PreferenceScore)
+    }
+}
+
+func TestFindMatchingTripsSkipsOwnAndFullTrips(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    trips := newTestTripService(store)
+    matching := NewMatchingService(repository.NewRouter(store, nil, 0), NewPreferenceService(store))
+
+    driver := newTestUser(t, store, "driver")
+    passenger := newTestUser(t, store, "passenger")
+    other := newTestUser(t, store, "other")
+
+    full := newTestTrip(t, trips, driver.ID, 1, 1500)
+    if _, err := trips.JoinTrip(ctx, full.ID, other.ID, 1, nil); err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+    newTestTrip(t, trips, passenger.ID, 3, 1500)
+
+    matches, err := matching.FindMatchingTrips(ctx, passenger.ID, "Berlin", "Hamburg", 100)
+    if err != nil {
+        t.Fatalf("FindMatchingTrips: %v", err)
+    }
+    if len(matches) != 0 {
+        t.Errorf("%d matches, want none", len(matches))
+    }
+}
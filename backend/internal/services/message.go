@@ -0,0 +1,108 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
+    "time"
+)
+
+const (
+    defaultMessagePageSize = 50
+    maxMessagePageSize     = 100
+)
+
+// ErrMessageNotFound is returned when marking a message read that wasn't
+// posted on the trip.
+var ErrMessageNotFound = errors.New("message not found")
+
+type MessageService struct {
+    store repository.Store
+}
+
+func NewMessageService(store repository.Store) *MessageService {
+    return &MessageService{store: store}
+}
+
+func (s *MessageService) PostMessage(ctx context.Context, message *models.TripMessage) error {
+    err := s.store.InTx(ctx, func(tx repository.Store) error {
+        if err := tx.Messages().Create(ctx, message); err != nil {
+            return err
+        }
+
+        // The sender has obviously read their own message
+        return tx.Messages().MarkRead(ctx, message.TripID, message.SenderID, message.ID)
+    })
+    if err != nil {
+        return err
+    }
+
+    message.ReadBy = []int{message.SenderID}
+    return nil
+}
+
+// GetMessages returns up to limit messages older than beforeID, newest first.
+// A beforeID of zero starts from the most recent message.
+func (s *MessageService) GetMessages(ctx context.Context, tripID, beforeID, limit int) (*models.MessagePage, error) {
+    if limit <= 0 {
+        limit = defaultMessagePageSize
+    }
+    if limit > maxMessagePageSize {
+        limit = maxMessagePageSize
+    }
+
+    // Fetch one extra message to know whether another page exists
+    messages, err := s.store.Messages().List(ctx, tripID, beforeID, limit+1)
+    if err != nil {
+        return nil, err
+    }
+
+    page := &models.MessagePage{Messages: messages}
+    if len(page.Messages) > limit {
+        page.Messages = page.Messages[:limit]
+        nextBefore := page.Messages[limit-1].ID
+        page.NextBefore = &nextBefore
+    }
+
+    receipts, err := s.GetReceipts(ctx, tripID)
+    if err != nil {
+        return nil, err
+    }
+
+    for i := range page.Messages {
+        page.Messages[i].ReadBy = []int{}
+        for _, receipt := range receipts {
+            if receipt.LastReadMessageID >= page.Messages[i].ID {
+                page.Messages[i].ReadBy = append(page.Messages[i].ReadBy, receipt.UserID)
+            }
+        }
+    }
+
+    return page, nil
+}
+
+func (s *MessageService) GetReceipts(ctx context.Context, tripID int) ([]models.MessageReceipt, error) {
+    return s.store.Messages().Receipts(ctx, tripID)
+}
+
+// MarkRead records that the user has read every message up to and including
+// messageID, which must have been posted on the trip. Receipts never move
+// backwards.
+func (s *MessageService) MarkRead(ctx context.Context, tripID, userID, messageID int) error {
+    err := s.store.Messages().MarkRead(ctx, tripID, userID, messageID)
+    if errors.Is(err, repository.ErrNotFound) {
+        return ErrMessageNotFound
+    }
+    return err
+}
+
+// FindUnreadDigests lists participants that have unread messages from other
+// users which are older than delay and have not been emailed about yet.
+func (s *MessageService) FindUnreadDigests(ctx context.Context, delay time.Duration) ([]models.UnreadDigest, error) {
+    return s.store.Messages().UnreadDigests(ctx, time.Now().Add(-delay))
+}
+
+func (s *MessageService) MarkNotified(ctx context.Context, tripID, userID, messageID int) error {
+    return s.store.Messages().MarkNotified(ctx, tripID, userID, messageID)
+}
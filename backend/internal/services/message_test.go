@@ -0,0 +1,146 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
+    "testing"
+)
+
+func TestGetMessagesPagesWithReceipts(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    trips := newTestTripService(store)
+    messages := NewMessageService(store)
+
+    driver := newTestUser(t, store, "driver")
+    passenger := newTestUser(t, store, "passenger")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+    if _, err := trips.JoinTrip(ctx, trip.ID, passenger.ID, 1, nil); err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+
+    var posted []*models.TripMessage
+    for _, body := range []string{"Hi", "Where do we meet?", "At the station"} {
+        sender := driver.ID
+        if len(posted) == 1 {
+            sender = passenger.ID
+        }
+        message := &models.TripMessage{TripID: trip.ID, SenderID: sender, Body: body}
+        if err := messages.PostMessage(ctx, message); err != nil {
+            t.Fatalf("PostMessage: %v", err)
+        }
+        posted = append(posted, message)
+    }
+
+    page, err := messages.GetMessages(ctx, trip.ID, 0, 2)
+    if err != nil {
+        t.Fatalf("GetMessages: %v", err)
+    }
+    if len(page.Messages) != 2 || page.Messages[0].ID != posted[2].ID || page.Messages[1].ID != posted[1].ID {
+        t.Fatalf("first page = %+v, want the two latest messages newest first", page.Messages)
+    }
+    if page.NextBefore == nil || *page.NextBefore != posted[1].ID {
+        t.Fatalf("next before = %v, want %d", page.NextBefore, posted[1].ID)
+    }
+    if page.Messages[0].Sender == nil || page.Messages[0].Sender.Name != "driver" {
+        t.Errorf("sender = %+v, want the driver", page.Messages[0].Sender)
+    }
+
+    // The passenger read up to their own message, the driver everything
+    if got := page.Messages[0].ReadBy; len(got) != 1 || got[0] != driver.ID {
+        t.Errorf("latest message read by %v, want only the driver", got)
+    }
+
+    page, err = messages.GetMessages(ctx, trip.ID, *page.NextBefore, 2)
+    if err != nil {
+        t.Fatalf("GetMessages: %v", err)
+    }
+    if len(page.Messages) != 1 || page.Messages[0].ID != posted[0].ID || page.NextBefore != nil {
+        t.Errorf("last page = %+v, want the first message only", page)
+    }
+}
+
+func TestFindUnreadDigests(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    trips := newTestTripService(store)
+    messages := NewMessageService(store)
+
+    driver := newTestUser(t, store, "driver")
+    passenger := newTestUser(t, store, "passenger")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+    if _, err := trips.JoinTrip(ctx, trip.ID, passenger.ID, 1, nil); err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+
+    for _, body := range []string{"Hi", "Running late"} {
+        if err := messages.PostMessage(ctx, &models.TripMessage{TripID: trip.ID, SenderID: driver.ID, Body: body}); err != nil {
+            t.Fatalf("PostMessage: %v", err)
+        }
+    }
+
+    digests, err := messages.FindUnreadDigests(ctx, 0)
+    if err != nil {
+        t.Fatalf("FindUnreadDigests: %v", err)
+    }
+    if len(digests) != 1 || digests[0].UserID != passenger.ID || digests[0].UnreadCount != 2 {
+        t.Fatalf("digests = %+v, want two unread messages for the passenger", digests)
+    }
+
+    if err := messages.MarkNotified(ctx, trip.ID, passenger.ID, digests[0].LatestMessageID); err != nil {
+        t.Fatalf("MarkNotified: %v", err)
+    }
+    digests, err = messages.FindUnreadDigests(ctx, 0)
+    if err != nil {
+        t.Fatalf("FindUnreadDigests: %v", err)
+    }
+    if len(digests) != 0 {
+        t.Errorf("digests after notifying = %+v, want none", digests)
+    }
+}
+
+func TestMarkReadOnlyAcceptsMessagesOfTheTrip(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    trips := newTestTripService(store)
+    messages := NewMessageService(store)
+
+    driver := newTestUser(t, store, "driver")
+    passenger := newTestUser(t, store, "passenger")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+    other := newTestTrip(t, trips, driver.ID, 3, 1500)
+    if _, err := trips.JoinTrip(ctx, trip.ID, passenger.ID, 1, nil); err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+
+    message := &models.TripMessage{TripID: trip.ID, SenderID: driver.ID, Body: "Hi"}
+    if err := messages.PostMessage(ctx, message); err != nil {
+        t.Fatalf("PostMessage: %v", err)
+    }
+    elsewhere := &models.TripMessage{TripID: other.ID, SenderID: driver.ID, Body: "Hello"}
+    if err := messages.PostMessage(ctx, elsewhere); err != nil {
+        t.Fatalf("PostMessage: %v", err)
+    }
+
+    for _, messageID := range []int{elsewhere.ID, 1 << 30} {
+        if err := messages.MarkRead(ctx, trip.ID, passenger.ID, messageID); !errors.Is(err, ErrMessageNotFound) {
+            t.Errorf("MarkRead(%d) = %v, want ErrMessageNotFound", messageID, err)
+        }
+    }
+
+    receipts, err := messages.GetReceipts(ctx, trip.ID)
+    if err != nil {
+        t.Fatalf("GetReceipts: %v", err)
+    }
+    for _, receipt := range receipts {
+        if receipt.UserID == passenger.ID {
+            t.Errorf("passenger has a receipt up to %d without reading anything", receipt.LastReadMessageID)
+        }
+    }
+
+    if err := messages.MarkRead(ctx, trip.ID, passenger.ID, message.ID); err != nil {
+        t.Errorf("MarkRead: %v", err)
+    }
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+    "context"
+    "log"
+    "time"
+)
+
+// MessageNotifier periodically emails participants about trip messages they
+// have left unread for longer than the configured delay.
+type MessageNotifier struct {
+    messageService *MessageService
+    emailService   *EmailService
+    delay          time.Duration
+    interval       time.Duration
+}
+
+func NewMessageNotifier(messageService *MessageService, emailService *EmailService, delay, interval time.Duration) *MessageNotifier {
+    return &MessageNotifier{
+        messageService: messageService,
+        emailService:   emailService,
+        delay:          delay,
+        interval:       interval,
+    }
+}
+
+// Run blocks until ctx is cancelled, checking for unread messages every interval.
+func (n *MessageNotifier) Run(ctx context.Context) {
+    runEvery(ctx, n.interval, n.notifyUnread)
+}
+
+func (n *MessageNotifier) notifyUnread(ctx context.Context) {
+    digests, err := n.messageService.FindUnreadDigests(ctx, n.delay)
+    if err != nil {
+        log.Printf("Failed to find unread messages: %v", err)
+        return
+    }
+    
+    for _, digest := range digests {
+        err := n.emailService.SendUnreadMessagesEmail(
+            digest.Email,
+            digest.Name,
+            digest.FromLocation,
+            digest.ToLocation,
+            digest.DepartureTime,
+            digest.TripID,
+            digest.UnreadCount,
+        )
+        if err != nil {
+            log.Printf("Failed to send unread messages email to user %d: %v", digest.UserID, err)
+            continue
+        }
+        
+        if err := n.messageService.MarkNotified(ctx, digest.TripID, digest.UserID, digest.LatestMessageID); err != nil {
+            log.Printf("Failed to record message notification: %v", err)
+        }
+    }
+}
@@ -0,0 +1,531 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "rideshare-backend/internal/config"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/money"
+    "rideshare-backend/internal/repository"
+    "time"
+)
+
+// Ledger entry types
+const (
+    EntryFareCharge      = "fare_charge"
+    EntryDriverEarning   = "driver_earning"
+    EntryPlatformFee     = "platform_fee"
+    EntryFareRefund      = "fare_refund"
+    EntryEarningReversal = "earning_reversal"
+    EntryFeeReversal     = "fee_reversal"
+)
+
+// RefundPolicy decides how much of a fare is returned when a booking is
+// cancelled, based on how long before departure the cancellation happens.
+type RefundPolicy struct {
+    FullRefundBefore     time.Duration
+    PartialRefundBefore  time.Duration
+    PartialRefundPercent int
+}
+
+// RefundPercent returns the share of the fare to refund. Cancellations by
+// the driver, or forced ones, are always refunded in full.
+func (p RefundPolicy) RefundPercent(departure, cancelledAt time.Time, full bool) int {
+    if full {
+        return 100
+    }
+
+    notice := departure.Sub(cancelledAt)
+    switch {
+    case notice >= p.FullRefundBefore:
+        return 100
+    case notice >= p.PartialRefundBefore:
+        return p.PartialRefundPercent
+    default:
+        return 0
+    }
+}
+
+type PaymentService struct {
+    store      repository.Store
+    provider   PaymentProvider
+    policy     RefundPolicy
+    feePercent int
+}
+
+func NewPaymentService(store repository.Store, cfg *config.Config, provider PaymentProvider) *PaymentService {
+    return &PaymentService{
+        store:    store,
+        provider: provider,
+        policy: RefundPolicy{
+            FullRefundBefore:     cfg.FullRefundBefore,
+            PartialRefundBefore:  cfg.PartialRefundBefore,
+            PartialRefundPercent: cfg.PartialRefundPercent,
+        },
+        feePercent: cfg.PlatformFeePercent,
+    }
+}
+
+// ChargeBooking charges the passenger the trip fare for each seat booked and
+// records the fare,
+// the driver's earnings and the platform fee in the ledger. Free trips are
+// not charged and return a nil transaction.
+func (s *PaymentService) ChargeBooking(ctx context.Context, tripID, passengerID int) (*models.PaymentTransaction, error) {
+    booking, err := s.store.Bookings().Get(ctx, tripID, passengerID)
+    if err != nil && !errors.Is(err, repository.ErrNotFound) {
+        return nil, err
+    }
+    if booking == nil || booking.Status == "cancelled" {
+        return nil, fmt.Errorf("booking not found")
+    }
+
+    trip, err := s.store.Trips().Get(ctx, tripID)
+    if err != nil {
+        return nil, err
+    }
+
+    amount := trip.PricePerPerson.Amount * money.Amount(booking.Seats)
+    if amount == 0 {
+        return nil, nil
+    }
+
+    description := fmt.Sprintf("Fare for trip #%d", tripID)
+    return s.charge(ctx, booking, trip, amount, description, func(tx repository.Store) error {
+        return tx.Bookings().SetFare(ctx, tripID, passengerID, amount, booking.Seats)
+    })
+}
+
+// charge has the provider charge the passenger and records the charge in the
+// ledger, together with whatever update does to the booking.
+func (s *PaymentService) charge(ctx context.Context, booking *models.TripPassenger, trip *models.Trip, amount money.Amount, description string, update func(tx repository.Store) error) (*models.PaymentTransaction, error) {
+    bookingID, tripID, passengerID, driverID := booking.ID, trip.ID, booking.PassengerID, trip.DriverID
+    currency := trip.PricePerPerson.Currency
+    fee := amount.Percent(s.feePercent)
+
+    reference, err := s.provider.Charge(ctx, passengerID, amount, currency, description)
+    if err != nil {
+        return nil, fmt.Errorf("payment failed: %w", err)
+    }
+
+    transaction := &models.PaymentTransaction{
+        BookingID:         &bookingID,
+        TripID:            &tripID,
+        Kind:              "charge",
+        Amount:            amount,
+        Currency:          currency,
+        ProviderReference: &reference,
+        Description:       &description,
+        Entries: []models.LedgerEntry{
+            {Account: "passenger", UserID: &passengerID, EntryType: EntryFareCharge, Amount: -amount},
+            {Account: "driver", UserID: &driverID, EntryType: EntryDriverEarning, Amount: amount - fee},
+            {Account: "platform", EntryType: EntryPlatformFee, Amount: fee},
+        },
+    }
+
+    err = s.store.InTx(ctx, func(tx repository.Store) error {
+        if err := tx.Payments().Record(ctx, transaction); err != nil {
+            return err
+        }
+        return update(tx)
+    })
+    if err != nil {
+        // Don't keep the passenger's money if we couldn't book it, even if
+        // the request was cancelled
+        if _, refundErr := s.provider.Refund(context.WithoutCancel(ctx), reference, amount, "reverse-"+reference); refundErr != nil {
+            log.Printf("Failed to reverse charge %s after ledger error: %v", reference, refundErr)
+        }
+        return nil, err
+    }
+
+    return transaction, nil
+}
+
+// bookingCharges are the charges of a booking since the passenger joined,
+// newest first: the fare, then any shared fare increases.
+type bookingCharges []models.BookingCharge
+
+// Remaining is what is left of the charges to refund.
+func (c bookingCharges) Remaining() money.Amount {
+    var remaining money.Amount
+    for i := range c {
+        remaining += c[i].Remaining()
+    }
+    return remaining
+}
+
+// RefundBooking refunds the fare of a cancelled booking according to the
+// refund policy. A full refund, for cancellations by the driver, also returns
+// fees kept when seats were given up earlier.
+func (s *PaymentService) RefundBooking(ctx context.Context, tripID, passengerID int, full bool) ([]models.PaymentTransaction, error) {
+    return s.refund(ctx, tripID, passengerID, fmt.Sprintf("Refund for trip #%d", tripID), func(booking *models.TripPassenger, charges bookingCharges, trip *models.Trip) (money.Amount, money.Amount, int) {
+        if full {
+            return charges.Remaining(), 0, 0
+        }
+        return booking.Fare.Percent(s.policy.RefundPercent(trip.DepartureTime, time.Now(), false)), 0, 0
+    })
+}
+
+// RefundSeats refunds, according to the refund policy, the share of the fare
+// paid for seats the passenger has given up. Seats already refunded aren't
+// refunded again.
+func (s *PaymentService) RefundSeats(ctx context.Context, tripID, passengerID int) ([]models.PaymentTransaction, error) {
+    description := fmt.Sprintf("Refund for seats given up on trip #%d", tripID)
+    return s.refund(ctx, tripID, passengerID, description, func(booking *models.TripPassenger, charges bookingCharges, trip *models.Trip) (money.Amount, money.Amount, int) {
+        if booking.Seats >= booking.FareSeats {
+            return 0, booking.Fare, booking.FareSeats
+        }
+
+        share := booking.Fare.Scale(int64(booking.FareSeats-booking.Seats), int64(booking.FareSeats))
+        amount := share.Percent(s.policy.RefundPercent(trip.DepartureTime, time.Now(), false))
+        return amount, booking.Fare - share, booking.Seats
+    })
+}
+
+// SettleSharedFare brings what the passengers of a shared-cost trip have paid
+// in line with the trip's current per-person price, which drops as
+// passengers join and rises as they leave. Each passenger is settled for the
+// seats they paid for: overpayments are refunded in full and the difference
+// is charged when the price went up, once the passenger has kept their
+// booking if the rise came with a material change.
+func (s *PaymentService) SettleSharedFare(ctx context.Context, tripID int) error {
+    trip, err := s.store.Trips().Get(ctx, tripID)
+    if err != nil {
+        return err
+    }
+    if trip.FareMode != models.FareModeShared {
+        return nil
+    }
+
+    manifest, err := s.store.Bookings().Manifest(ctx, tripID)
+    if err != nil {
+        return err
+    }
+
+    var errs []error
+    for _, booking := range manifest {
+        if booking.Status != "confirmed" {
+            continue
+        }
+        if err := s.settleFare(ctx, tripID, booking.PassengerID); err != nil {
+            errs = append(errs, fmt.Errorf("failed to settle fare for passenger %d: %w", booking.PassengerID, err))
+        }
+    }
+
+    return errors.Join(errs...)
+}
+
+func (s *PaymentService) settleFare(ctx context.Context, tripID, passengerID int) error {
+    description := fmt.Sprintf("Shared fare adjustment for trip #%d", tripID)
+
+    // A passenger asked to keep or cancel their booking after a material
+    // change isn't charged more until they keep it
+    awaiting, err := s.store.Trips().AwaitingResponse(ctx, tripID, passengerID)
+    if err != nil {
+        return err
+    }
+
+    // The fare is raised, with the charges locked, before the passenger is
+    // charged the increase, so no concurrent settlement charges it too
+    var booking *models.TripPassenger
+    var trip *models.Trip
+    var increase money.Amount
+    _, err = s.refund(ctx, tripID, passengerID, description, func(b *models.TripPassenger, charges bookingCharges, t *models.Trip) (money.Amount, money.Amount, int) {
+        booking, trip = b, t
+        fare := t.PricePerPerson.Amount * money.Amount(b.FareSeats)
+        if b.Status != "confirmed" || fare == b.Fare || (fare > b.Fare && awaiting) {
+            return 0, b.Fare, b.FareSeats
+        }
+        if fare > b.Fare {
+            increase = fare - b.Fare
+            return 0, fare, b.FareSeats
+        }
+        return b.Fare - fare, fare, b.FareSeats
+    })
+    if err != nil || increase == 0 {
+        return err
+    }
+
+    ctx = context.WithoutCancel(ctx)
+    _, err = s.charge(ctx, booking, trip, increase, description, func(tx repository.Store) error {
+        // A passenger who left meanwhile was refunded the raised fare
+        current, _, err := lockCharges(ctx, tx, tripID, passengerID)
+        if err != nil {
+            return err
+        }
+        if current == nil || current.Status != "confirmed" {
+            return fmt.Errorf("booking was cancelled")
+        }
+        return nil
+    })
+    if err != nil {
+        // Take back the increase, the passenger hasn't paid it
+        restoreErr := s.store.InTx(ctx, func(tx repository.Store) error {
+            current, _, err := lockCharges(ctx, tx, tripID, passengerID)
+            if err != nil || current == nil || current.Status != "confirmed" || current.Fare < increase {
+                return err
+            }
+            return tx.Bookings().SetFare(ctx, tripID, passengerID, current.Fare-increase, current.FareSeats)
+        })
+        if restoreErr != nil {
+            log.Printf("Failed to restore fare of passenger %d on trip %d: %v", passengerID, tripID, restoreErr)
+        }
+        return err
+    }
+
+    return nil
+}
+
+// lockCharges loads a passenger's booking and its charges, locking the
+// charges until the transaction ends. Fares only change with the charges
+// locked. It returns a nil booking if there is none.
+func lockCharges(ctx context.Context, tx repository.Store, tripID, passengerID int) (*models.TripPassenger, bookingCharges, error) {
+    booking, err := tx.Bookings().Get(ctx, tripID, passengerID)
+    if err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            return nil, nil, nil
+        }
+        return nil, nil, err
+    }
+
+    charges, err := tx.Payments().ChargesForUpdate(ctx, booking.ID)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    // Read the booking again, its fare may have changed while waiting for
+    // the lock
+    booking, err = tx.Bookings().Get(ctx, tripID, passengerID)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    return booking, bookingCharges(charges), nil
+}
+
+// refundDue works out, from a booking, its charges and the trip, what to
+// refund and the fare the booking is left with for how many seats.
+type refundDue func(booking *models.TripPassenger, charges bookingCharges, trip *models.Trip) (amount, fare money.Amount, fareSeats int)
+
+// refund requests a refund of what due works out, then pays it. The
+// booking's charges stay locked while the amount is worked out and the
+// request stored, so concurrent refunds each see what the others took. A
+// refund the provider fails to pay stays pending for RetryRefunds. The
+// refund is taken from the newest charges first.
+func (s *PaymentService) refund(ctx context.Context, tripID, passengerID int, description string, due refundDue) ([]models.PaymentTransaction, error) {
+    // The booking is already cancelled, its refund must not be lost with the
+    // request
+    ctx = context.WithoutCancel(ctx)
+
+    var requests []models.RefundRequest
+    err := s.store.InTx(ctx, func(tx repository.Store) error {
+        booking, charges, err := lockCharges(ctx, tx, tripID, passengerID)
+        if err != nil || booking == nil || len(charges) == 0 {
+            return err
+        }
+
+        trip, err := tx.Trips().Get(ctx, tripID)
+        if err != nil {
+            return err
+        }
+
+        amount, fare, fareSeats := due(booking, charges, trip)
+        if fare != booking.Fare || fareSeats != booking.FareSeats {
+            if err := tx.Bookings().SetFare(ctx, tripID, passengerID, fare, fareSeats); err != nil {
+                return err
+            }
+        }
+
+        for i := range charges {
+            if amount <= 0 {
+                break
+            }
+
+            part := charges[i].Remaining()
+            if part > amount {
+                part = amount
+            }
+            if part <= 0 {
+                continue
+            }
+
+            request := models.RefundRequest{ChargeID: charges[i].ID, Amount: part, Description: &description}
+            if err := tx.Payments().RequestRefund(ctx, &request); err != nil {
+                return err
+            }
+            requests = append(requests, request)
+            amount -= part
+        }
+
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    var refunds []models.PaymentTransaction
+    var errs []error
+    for i := range requests {
+        refund, err := s.payRefund(ctx, &requests[i])
+        if err != nil {
+            errs = append(errs, err)
+            continue
+        }
+        if refund != nil {
+            refunds = append(refunds, *refund)
+        }
+    }
+
+    return refunds, errors.Join(errs...)
+}
+
+// payRefund has the provider pay a pending refund request and records it in
+// the ledger, returning the driver's earnings and the platform fee in
+// proportion. It returns a nil transaction if the request was paid meanwhile.
+func (s *PaymentService) payRefund(ctx context.Context, request *models.RefundRequest) (*models.PaymentTransaction, error) {
+    charge, err := s.store.Payments().GetCharge(ctx, request.ChargeID)
+    if err != nil {
+        return nil, err
+    }
+
+    var reference string
+    if charge.ProviderReference != nil {
+        reference = *charge.ProviderReference
+    }
+
+    // The request ID keeps a retried refund from being paid twice
+    providerReference, err := s.provider.Refund(ctx, reference, request.Amount, fmt.Sprintf("refund-%d", request.ID))
+    if err != nil {
+        if failErr := s.store.Payments().FailRefund(ctx, request.ID, err.Error()); failErr != nil {
+            log.Printf("Failed to record failed refund %d: %v", request.ID, failErr)
+        }
+        return nil, fmt.Errorf("refund failed, it will be retried: %w", err)
+    }
+
+    // Reverse the platform fee in proportion to the refunded share
+    feeRefund := charge.Fee.Scale(int64(request.Amount), int64(charge.Amount))
+
+    transaction := &models.PaymentTransaction{
+        BookingID:         charge.BookingID,
+        TripID:            charge.TripID,
+        ParentID:          &charge.ID,
+        Kind:              "refund",
+        Amount:            request.Amount,
+        Currency:          charge.Currency,
+        ProviderReference: &providerReference,
+        Description:       request.Description,
+        Entries: []models.LedgerEntry{
+            {Account: "passenger", UserID: entryUser(charge.Entries, "passenger"), EntryType: EntryFareRefund, Amount: request.Amount},
+            {Account: "driver", UserID: entryUser(charge.Entries, "driver"), EntryType: EntryEarningReversal, Amount: -(request.Amount - feeRefund)},
+            {Account: "platform", EntryType: EntryFeeReversal, Amount: -feeRefund},
+        },
+    }
+
+    err = s.store.InTx(ctx, func(tx repository.Store) error {
+        current, err := tx.Payments().GetRefundRequestForUpdate(ctx, request.ID)
+        if err != nil {
+            return err
+        }
+        if current.Status != models.RefundPending {
+            transaction = nil
+            return nil
+        }
+
+        if err := tx.Payments().Record(ctx, transaction); err != nil {
+            return err
+        }
+        return tx.Payments().CompleteRefund(ctx, request.ID, transaction.ID)
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return transaction, nil
+}
+
+// entryUser returns the user holding the account among a charge's entries.
+func entryUser(entries []models.LedgerEntry, account string) *int {
+    for _, entry := range entries {
+        if entry.Account == account {
+            return entry.UserID
+        }
+    }
+    return nil
+}
+
+// RetryRefunds pays the refunds still pending that were last tried before
+// after ago, returning how many it paid.
+func (s *PaymentService) RetryRefunds(ctx context.Context, after time.Duration) (int, error) {
+    requests, err := s.store.Payments().PendingRefunds(ctx, time.Now().Add(-after), 100)
+    if err != nil {
+        return 0, err
+    }
+
+    paid := 0
+    for i := range requests {
+        if _, err := s.payRefund(ctx, &requests[i]); err != nil {
+            log.Printf("Failed to retry refund %d: %v", requests[i].ID, err)
+            continue
+        }
+        paid++
+    }
+
+    return paid, nil
+}
+
+// RunRefundRetries blocks until ctx is cancelled, retrying failed refunds
+// every interval.
+func (s *PaymentService) RunRefundRetries(ctx context.Context, interval time.Duration) {
+    runEvery(ctx, interval, func(ctx context.Context) {
+        if _, err := s.RetryRefunds(ctx, interval); err != nil {
+            log.Printf("Failed to retry refunds: %v", err)
+        }
+    })
+}
+
+// RefundTrip fully refunds every passenger of a trip the driver cancelled.
+// One passenger's failed refund does not hold up the others'.
+func (s *PaymentService) RefundTrip(ctx context.Context, tripID int) error {
+    trip, err := s.store.Trips().Get(ctx, tripID)
+    if err != nil {
+        return err
+    }
+    if trip.Status != "cancelled" {
+        return fmt.Errorf("trip %d is %s, only cancelled trips are refunded in full", tripID, trip.Status)
+    }
+
+    manifest, err := s.store.Bookings().Manifest(ctx, tripID)
+    if err != nil {
+        return err
+    }
+
+    var errs []error
+    for _, booking := range manifest {
+        if _, err := s.RefundBooking(ctx, tripID, booking.PassengerID, true); err != nil {
+            errs = append(errs, fmt.Errorf("failed to refund passenger %d: %w", booking.PassengerID, err))
+        }
+    }
+
+    return errors.Join(errs...)
+}
+
+// GetBalances returns the user's balance per account and currency. Driver
+// balances are earnings owed, passenger balances are net fares paid.
+func (s *PaymentService) GetBalances(ctx context.Context, userID int) ([]models.AccountBalance, error) {
+    balances, err := s.store.Payments().Balances(ctx, userID)
+    if err != nil {
+        return nil, err
+    }
+
+    for i := range balances {
+        balances[i].Formatted = money.Format(balances[i].Balance, balances[i].Currency)
+    }
+
+    return balances, nil
+}
+
+// GetTransactions returns the user's ledger entries, newest first.
+func (s *PaymentService) GetTransactions(ctx context.Context, userID, limit, offset int) ([]models.LedgerEntry, error) {
+    return s.store.Payments().Entries(ctx, userID, limit, offset)
+}
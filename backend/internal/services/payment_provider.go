@@ -0,0 +1,104 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "rideshare-backend/internal/money"
+    "sync"
+)
+
+// PaymentProvider moves real money. Amounts are in minor units. Refunds
+// repeating the idempotency key of an earlier one are not paid again, they
+// return the earlier refund's reference.
+type PaymentProvider interface {
+    Charge(ctx context.Context, customerID int, amount money.Amount, currency, description string) (string, error)
+    Refund(ctx context.Context, reference string, amount money.Amount, idempotencyKey string) (string, error)
+}
+
+type fakeCharge struct {
+    customerID int
+    amount     money.Amount
+    refunded   money.Amount
+    currency   string
+}
+
+// FakePaymentProvider keeps charges in memory and never talks to a payment
+// network. It is used in development and tests.
+type FakePaymentProvider struct {
+    mu      sync.Mutex
+    nextID  int
+    charges map[string]*fakeCharge
+    refunds map[string]string // by idempotency key
+
+    // FailCharges makes every charge fail, e.g. to simulate a declined card
+    FailCharges bool
+
+    // FailRefunds makes every refund fail, e.g. to simulate an outage
+    FailRefunds bool
+}
+
+func NewFakePaymentProvider() *FakePaymentProvider {
+    return &FakePaymentProvider{
+        charges: make(map[string]*fakeCharge),
+        refunds: make(map[string]string),
+    }
+}
+
+func (p *FakePaymentProvider) Charge(ctx context.Context, customerID int, amount money.Amount, currency, description string) (string, error) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    if p.FailCharges {
+        return "", fmt.Errorf("card declined")
+    }
+
+    p.nextID++
+    reference := fmt.Sprintf("fake_ch_%d", p.nextID)
+    p.charges[reference] = &fakeCharge{
+        customerID: customerID,
+        amount:     amount,
+        currency:   currency,
+    }
+
+    return reference, nil
+}
+
+func (p *FakePaymentProvider) Refund(ctx context.Context, reference string, amount money.Amount, idempotencyKey string) (string, error) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    if p.FailRefunds {
+        return "", fmt.Errorf("payment provider unavailable")
+    }
+
+    if refund, ok := p.refunds[idempotencyKey]; ok {
+        return refund, nil
+    }
+
+    charge, ok := p.charges[reference]
+    if !ok {
+        return "", fmt.Errorf("charge %s not found", reference)
+    }
+
+    if charge.refunded+amount > charge.amount {
+        return "", fmt.Errorf("refund exceeds charged amount")
+    }
+
+    charge.refunded += amount
+    p.nextID++
+
+    refund := fmt.Sprintf("fake_re_%d", p.nextID)
+    p.refunds[idempotencyKey] = refund
+    return refund, nil
+}
+
+// Refunded returns how much of a charge has been refunded so far.
+func (p *FakePaymentProvider) Refunded(reference string) money.Amount {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    if charge, ok := p.charges[reference]; ok {
+        return charge.refunded
+    }
+    return 0
+}
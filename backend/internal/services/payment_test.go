@@ -0,0 +1,526 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "rideshare-backend/internal/config"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/money"
+    "rideshare-backend/internal/repository"
+    "sync"
+    "testing"
+    "time"
+)
+
+func newTestPaymentService(store repository.Store, provider PaymentProvider) *PaymentService {
+    return NewPaymentService(store, &config.Config{
+        PlatformFeePercent:   10,
+        FullRefundBefore:     48 * time.Hour,
+        PartialRefundBefore:  12 * time.Hour,
+        PartialRefundPercent: 50,
+    }, provider)
+}
+
+// chargedBooking books seats on a fresh trip departing tomorrow and charges
+// the passenger for them.
+func chargedBooking(t *testing.T, store repository.Store, payments *PaymentService, seats int, price money.Amount) (*models.Trip, *models.User, *models.PaymentTransaction) {
+    t.Helper()
+
+    ctx := context.Background()
+    trips := newTestTripService(store)
+    driver := newTestUser(t, store, "driver")
+    passenger := newTestUser(t, store, "passenger")
+    trip := newTestTrip(t, trips, driver.ID, 4, price)
+
+    var guests []string
+    for i := 1; i < seats; i++ {
+        guests = append(guests, "Guest")
+    }
+    if _, err := trips.JoinTrip(ctx, trip.ID, passenger.ID, seats, guests); err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+
+    charge, err := payments.ChargeBooking(ctx, trip.ID, passenger.ID)
+    if err != nil {
+        t.Fatalf("ChargeBooking: %v", err)
+    }
+    return trip, passenger, charge
+}
+
+// assertBalanced fails unless the transaction's ledger entries sum to zero.
+func assertBalanced(t *testing.T, transaction *models.PaymentTransaction) {
+    t.Helper()
+
+    var sum money.Amount
+    for _, entry := range transaction.Entries {
+        sum += entry.Amount
+    }
+    if sum != 0 {
+        t.Errorf("%s entries sum to %d, want 0: %+v", transaction.Kind, sum, transaction.Entries)
+    }
+}
+
+// refunded adds up the refunds, checking each balances.
+func refunded(t *testing.T, refunds []models.PaymentTransaction) money.Amount {
+    t.Helper()
+
+    var total money.Amount
+    for i := range refunds {
+        assertBalanced(t, &refunds[i])
+        total += refunds[i].Amount
+    }
+    return total
+}
+
+func passengerBalance(t *testing.T, payments *PaymentService, userID int) money.Amount {
+    t.Helper()
+
+    balances, err := payments.GetBalances(context.Background(), userID)
+    if err != nil {
+        t.Fatalf("GetBalances: %v", err)
+    }
+    for _, balance := range balances {
+        if balance.Account == "passenger" {
+            return balance.Balance
+        }
+    }
+    return 0
+}
+
+func TestRefundPercent(t *testing.T) {
+    policy := RefundPolicy{FullRefundBefore: 48 * time.Hour, PartialRefundBefore: 12 * time.Hour, PartialRefundPercent: 50}
+    departure := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+    tests := []struct {
+        name   string
+        notice time.Duration
+        full   bool
+        want   int
+    }{
+        {"early", 72 * time.Hour, false, 100},
+        {"at full refund cutoff", 48 * time.Hour, false, 100},
+        {"partial", 24 * time.Hour, false, 50},
+        {"at partial refund cutoff", 12 * time.Hour, false, 50},
+        {"late", time.Hour, false, 0},
+        {"after departure", -time.Hour, false, 0},
+        {"forced", time.Hour, true, 100},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := policy.RefundPercent(departure, departure.Add(-tt.notice), tt.full); got != tt.want {
+                t.Errorf("RefundPercent = %d, want %d", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestRefundBookingFollowsPolicyAndBalances(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    provider := NewFakePaymentProvider()
+    payments := newTestPaymentService(store, provider)
+
+    // The trip leaves in a day, inside the partial refund window
+    trip, passenger, charge := chargedBooking(t, store, payments, 2, 1500)
+    assertBalanced(t, charge)
+
+    refunds, err := payments.RefundBooking(ctx, trip.ID, passenger.ID, false)
+    if err != nil {
+        t.Fatalf("RefundBooking: %v", err)
+    }
+    if got := refunded(t, refunds); got != 1500 {
+        t.Fatalf("refunded %d, want 1500", got)
+    }
+
+    if got := provider.Refunded(*charge.ProviderReference); got != 1500 {
+        t.Errorf("provider refunded %d, want 1500", got)
+    }
+    if got := passengerBalance(t, payments, passenger.ID); got != -1500 {
+        t.Errorf("passenger balance = %d, want -1500", got)
+    }
+
+    // A policy refund doesn't pay out twice
+    refunds, err = payments.RefundBooking(ctx, trip.ID, passenger.ID, false)
+    if err != nil {
+        t.Fatalf("RefundBooking: %v", err)
+    }
+    if got := refunded(t, refunds); got != 0 {
+        t.Fatalf("refunded %d again", got)
+    }
+
+    // A forced refund returns the fee kept too, and only what is still
+    // outstanding
+    refunds, err = payments.RefundBooking(ctx, trip.ID, passenger.ID, true)
+    if err != nil {
+        t.Fatalf("RefundBooking: %v", err)
+    }
+    if got := refunded(t, refunds); got != 1500 {
+        t.Fatalf("forced refund = %d, want 1500", got)
+    }
+
+    refunds, err = payments.RefundBooking(ctx, trip.ID, passenger.ID, true)
+    if err != nil {
+        t.Fatalf("RefundBooking: %v", err)
+    }
+    if got := refunded(t, refunds); got != 0 {
+        t.Errorf("refunded %d of a fully refunded charge", got)
+    }
+    if got := provider.Refunded(*charge.ProviderReference); got != 3000 {
+        t.Errorf("provider refunded %d, want 3000", got)
+    }
+    if got := passengerBalance(t, payments, passenger.ID); got != 0 {
+        t.Errorf("passenger balance = %d, want 0", got)
+    }
+}
+
+func TestRefundSeatsRefundsShare(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    provider := NewFakePaymentProvider()
+    payments := newTestPaymentService(store, provider)
+
+    trip, passenger, charge := chargedBooking(t, store, payments, 3, 1000)
+
+    trips := newTestTripService(store)
+    if _, _, err := trips.CancelSeats(ctx, trip.ID, passenger.ID, 1, []string{"Guest"}); err != nil {
+        t.Fatalf("CancelSeats: %v", err)
+    }
+
+    refunds, err := payments.RefundSeats(ctx, trip.ID, passenger.ID)
+    if err != nil {
+        t.Fatalf("RefundSeats: %v", err)
+    }
+    // Half of one seat's fare, inside the partial refund window
+    if got := refunded(t, refunds); got != 500 {
+        t.Fatalf("refunded %d, want 500", got)
+    }
+
+    refunds, err = payments.RefundSeats(ctx, trip.ID, passenger.ID)
+    if err != nil {
+        t.Fatalf("RefundSeats: %v", err)
+    }
+    if got := refunded(t, refunds); got != 0 {
+        t.Errorf("refunded the same seat again: %d", got)
+    }
+
+    if got := provider.Refunded(*charge.ProviderReference); got != 500 {
+        t.Errorf("provider refunded %d, want 500", got)
+    }
+
+    // Leaving refunds half the fare of the seats still held, not the fee
+    // kept for the seat given up
+    refunds, err = payments.RefundBooking(ctx, trip.ID, passenger.ID, false)
+    if err != nil {
+        t.Fatalf("RefundBooking: %v", err)
+    }
+    if got := refunded(t, refunds); got != 1000 {
+        t.Errorf("refunded %d on leaving, want 1000", got)
+    }
+}
+
+func TestConcurrentRefundsPayOnce(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    provider := NewFakePaymentProvider()
+    payments := newTestPaymentService(store, provider)
+
+    trip, passenger, charge := chargedBooking(t, store, payments, 2, 1500)
+
+    const refunds = 10
+    var wg sync.WaitGroup
+    for i := 0; i < refunds; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if _, err := payments.RefundBooking(ctx, trip.ID, passenger.ID, true); err != nil {
+                t.Errorf("RefundBooking: %v", err)
+            }
+        }()
+    }
+    wg.Wait()
+
+    if got := provider.Refunded(*charge.ProviderReference); got != charge.Amount {
+        t.Errorf("provider refunded %d, want %d", got, charge.Amount)
+    }
+    if got := passengerBalance(t, payments, passenger.ID); got != 0 {
+        t.Errorf("passenger balance = %d, want 0", got)
+    }
+}
+
+func TestFailedRefundIsRetried(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    provider := NewFakePaymentProvider()
+    payments := newTestPaymentService(store, provider)
+
+    trip, passenger, charge := chargedBooking(t, store, payments, 1, 1500)
+
+    provider.FailRefunds = true
+    if _, err := payments.RefundBooking(ctx, trip.ID, passenger.ID, true); err == nil {
+        t.Fatal("refund succeeded while the provider was down")
+    }
+
+    // The failed refund is still owed, so another request adds nothing
+    refunds, err := payments.RefundBooking(ctx, trip.ID, passenger.ID, true)
+    if len(refunds) != 0 || err != nil {
+        t.Errorf("refunded %d more while a refund was pending (err %v)", refunded(t, refunds), err)
+    }
+
+    provider.FailRefunds = false
+    paid, err := payments.RetryRefunds(ctx, 0)
+    if err != nil {
+        t.Fatalf("RetryRefunds: %v", err)
+    }
+    if paid != 1 {
+        t.Errorf("retried %d refunds, want 1", paid)
+    }
+
+    paid, err = payments.RetryRefunds(ctx, 0)
+    if err != nil {
+        t.Fatalf("RetryRefunds: %v", err)
+    }
+    if paid != 0 {
+        t.Errorf("retried %d refunds again, want 0", paid)
+    }
+
+    if got := provider.Refunded(*charge.ProviderReference); got != 1500 {
+        t.Errorf("provider refunded %d, want 1500", got)
+    }
+    if got := passengerBalance(t, payments, passenger.ID); got != 0 {
+        t.Errorf("passenger balance = %d, want 0", got)
+    }
+}
+
+// sharedTrip splits a total cost of 3000 between the driver and up to four
+// passengers.
+func sharedTrip(t *testing.T, store repository.Store, trips *TripService, driverID int) *models.Trip {
+    t.Helper()
+
+    ctx := context.Background()
+    trip := newTestTrip(t, trips, driverID, 4, 1500)
+    total := money.New(3000, "EUR")
+    trip.FareMode = models.FareModeShared
+    trip.TotalCost = &total
+    if err := store.Trips().Update(ctx, trip); err != nil {
+        t.Fatalf("update trip: %v", err)
+    }
+    if err := store.Trips().RepriceSharedFare(ctx, trip.ID); err != nil {
+        t.Fatalf("reprice trip: %v", err)
+    }
+    return trip
+}
+
+// joinAndPay books and charges seats on a trip, settling the shared fare.
+func joinAndPay(t *testing.T, trips *TripService, payments *PaymentService, tripID, passengerID, seats int) {
+    t.Helper()
+
+    ctx := context.Background()
+    var guests []string
+    for i := 1; i < seats; i++ {
+        guests = append(guests, "Guest")
+    }
+    if _, err := trips.JoinTrip(ctx, tripID, passengerID, seats, guests); err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+    if _, err := payments.ChargeBooking(ctx, tripID, passengerID); err != nil {
+        t.Fatalf("ChargeBooking: %v", err)
+    }
+    if err := payments.SettleSharedFare(ctx, tripID); err != nil {
+        t.Fatalf("SettleSharedFare: %v", err)
+    }
+}
+
+func TestSharedFareFollowsPrice(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    provider := NewFakePaymentProvider()
+    payments := newTestPaymentService(store, provider)
+    trips := newTestTripService(store)
+
+    driver := newTestUser(t, store, "driver")
+    first := newTestUser(t, store, "first")
+    second := newTestUser(t, store, "second")
+    trip := sharedTrip(t, store, trips, driver.ID)
+
+    joinAndPay(t, trips, payments, trip.ID, first.ID, 1)
+    if got := passengerBalance(t, payments, first.ID); got != -1500 {
+        t.Fatalf("first passenger paid %d, want 1500", -got)
+    }
+
+    // A second passenger lowers the price to 1000
+    joinAndPay(t, trips, payments, trip.ID, second.ID, 1)
+    if got := passengerBalance(t, payments, first.ID); got != -1000 {
+        t.Errorf("first passenger paid %d after the price dropped, want 1000", -got)
+    }
+    if got := passengerBalance(t, payments, second.ID); got != -1000 {
+        t.Errorf("second passenger paid %d, want 1000", -got)
+    }
+
+    // Leaving raises it back, and the passenger who stays pays the difference
+    if err := trips.LeaveTrip(ctx, trip.ID, second.ID); err != nil {
+        t.Fatalf("LeaveTrip: %v", err)
+    }
+    if _, err := payments.RefundBooking(ctx, trip.ID, second.ID, false); err != nil {
+        t.Fatalf("RefundBooking: %v", err)
+    }
+    if err := payments.SettleSharedFare(ctx, trip.ID); err != nil {
+        t.Fatalf("SettleSharedFare: %v", err)
+    }
+    if got := passengerBalance(t, payments, first.ID); got != -1500 {
+        t.Errorf("first passenger paid %d after the price rose, want 1500", -got)
+    }
+
+    // Settling again changes nothing
+    if err := payments.SettleSharedFare(ctx, trip.ID); err != nil {
+        t.Fatalf("SettleSharedFare: %v", err)
+    }
+    if got := passengerBalance(t, payments, first.ID); got != -1500 {
+        t.Errorf("first passenger paid %d after settling again, want 1500", -got)
+    }
+}
+
+func TestSharedFareKeepsCancellationFee(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    provider := NewFakePaymentProvider()
+    payments := newTestPaymentService(store, provider)
+    trips := newTestTripService(store)
+
+    driver := newTestUser(t, store, "driver")
+    first := newTestUser(t, store, "first")
+    second := newTestUser(t, store, "second")
+    trip := sharedTrip(t, store, trips, driver.ID)
+
+    // Two seats at 1000 each
+    joinAndPay(t, trips, payments, trip.ID, first.ID, 2)
+
+    // Giving one up refunds half its fare, and the price rises to 1500 for
+    // the seat kept
+    if _, _, err := trips.CancelSeats(ctx, trip.ID, first.ID, 1, nil); err != nil {
+        t.Fatalf("CancelSeats: %v", err)
+    }
+    if _, err := payments.RefundSeats(ctx, trip.ID, first.ID); err != nil {
+        t.Fatalf("RefundSeats: %v", err)
+    }
+    if err := payments.SettleSharedFare(ctx, trip.ID); err != nil {
+        t.Fatalf("SettleSharedFare: %v", err)
+    }
+    // 1500 for the seat and 500 kept of the other
+    if got := passengerBalance(t, payments, first.ID); got != -2000 {
+        t.Fatalf("first passenger paid %d, want 2000", -got)
+    }
+
+    // Another passenger lowers the price back to 1000, which refunds the
+    // seat's overpayment but not the fee kept
+    joinAndPay(t, trips, payments, trip.ID, second.ID, 1)
+    if got := passengerBalance(t, payments, first.ID); got != -1500 {
+        t.Errorf("first passenger paid %d, want 1500", -got)
+    }
+}
+
+func TestSharedFareIncreaseIsRefundedOnLeaving(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    provider := NewFakePaymentProvider()
+    payments := newTestPaymentService(store, provider)
+    trips := newTestTripService(store)
+
+    driver := newTestUser(t, store, "driver")
+    first := newTestUser(t, store, "first")
+    second := newTestUser(t, store, "second")
+    trip := sharedTrip(t, store, trips, driver.ID)
+
+    joinAndPay(t, trips, payments, trip.ID, first.ID, 1)
+    joinAndPay(t, trips, payments, trip.ID, second.ID, 1)
+
+    if err := trips.LeaveTrip(ctx, trip.ID, second.ID); err != nil {
+        t.Fatalf("LeaveTrip: %v", err)
+    }
+    if err := payments.SettleSharedFare(ctx, trip.ID); err != nil {
+        t.Fatalf("SettleSharedFare: %v", err)
+    }
+
+    // The fare and its increase are charged separately; a forced refund
+    // returns both
+    refunds, err := payments.RefundBooking(ctx, trip.ID, first.ID, true)
+    if err != nil {
+        t.Fatalf("RefundBooking: %v", err)
+    }
+    if len(refunds) != 2 {
+        t.Errorf("refunded %d charges, want 2", len(refunds))
+    }
+    if got := refunded(t, refunds); got != 1500 {
+        t.Errorf("refunded %d, want 1500", got)
+    }
+    if got := passengerBalance(t, payments, first.ID); got != 0 {
+        t.Errorf("first passenger balance = %d, want 0", got)
+    }
+}
+
+func TestCompletedTripIsNotCancelledOrRefunded(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    payments := newTestPaymentService(store, NewFakePaymentProvider())
+    trips := newTestTripService(store)
+
+    trip, passenger, charge := chargedBooking(t, store, payments, 1, 1500)
+    if err := trips.StartTrip(ctx, trip.ID, trip.DriverID); err != nil {
+        t.Fatalf("StartTrip: %v", err)
+    }
+    if err := trips.CompleteTrip(ctx, trip.ID, trip.DriverID); err != nil {
+        t.Fatalf("CompleteTrip: %v", err)
+    }
+
+    if err := trips.DeleteTrip(ctx, trip.ID, trip.DriverID); !errors.Is(err, ErrTripNotActive) {
+        t.Errorf("DeleteTrip = %v, want ErrTripNotActive", err)
+    }
+    if err := payments.RefundTrip(ctx, trip.ID); err == nil {
+        t.Error("RefundTrip refunded a completed trip")
+    }
+
+    if got := passengerBalance(t, payments, passenger.ID); got != -charge.Amount {
+        t.Errorf("passenger balance = %d, want the %d they paid", got, charge.Amount)
+    }
+}
+
+func TestMaterialFareIncreaseWaitsForResponse(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    provider := NewFakePaymentProvider()
+    payments := newTestPaymentService(store, provider)
+    trips := newTestTripService(store)
+
+    driver := newTestUser(t, store, "driver")
+    passenger := newTestUser(t, store, "passenger")
+    trip := sharedTrip(t, store, trips, driver.ID)
+    joinAndPay(t, trips, payments, trip.ID, passenger.ID, 1)
+
+    // Doubling the total cost doubles the price, a material change
+    total := money.New(6000, "EUR")
+    trip.TotalCost = &total
+    change, err := trips.UpdateTrip(ctx, trip, time.Hour)
+    if err != nil {
+        t.Fatalf("UpdateTrip: %v", err)
+    }
+    if change == nil || !change.Material {
+        t.Fatalf("change = %+v, want a material one", change)
+    }
+
+    if err := payments.SettleSharedFare(ctx, trip.ID); err != nil {
+        t.Fatalf("SettleSharedFare: %v", err)
+    }
+    if got := passengerBalance(t, payments, passenger.ID); got != -1500 {
+        t.Errorf("passenger paid %d before keeping their booking, want 1500", -got)
+    }
+
+    if _, err := trips.RespondToChange(ctx, trip.ID, change.ID, passenger.ID, models.ChangeResponseKeep); err != nil {
+        t.Fatalf("RespondToChange: %v", err)
+    }
+    if err := payments.SettleSharedFare(ctx, trip.ID); err != nil {
+        t.Fatalf("SettleSharedFare: %v", err)
+    }
+    if got := passengerBalance(t, payments, passenger.ID); got != -3000 {
+        t.Errorf("passenger paid %d after keeping their booking, want 3000", -got)
+    }
+}
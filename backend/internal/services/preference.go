@@ -0,0 +1,61 @@
+package services
+
+import (
+    "context"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
+)
+
+type PreferenceService struct {
+    store repository.Store
+}
+
+func NewPreferenceService(store repository.Store) *PreferenceService {
+    return &PreferenceService{store: store}
+}
+
+// GetUserPreferences returns the user's saved search preferences, which are
+// empty until they save some.
+func (s *PreferenceService) GetUserPreferences(ctx context.Context, userID int) (models.PreferenceFilter, error) {
+    return s.store.Users().Preferences(ctx, userID)
+}
+
+func (s *PreferenceService) SaveUserPreferences(ctx context.Context, userID int, prefs models.PreferenceFilter) error {
+    return s.store.Users().SavePreferences(ctx, userID, prefs)
+}
+
+// PreferenceScore rates how well a trip suits a passenger, from 0 to 1. Trips
+// are scored on the preferences the passenger has set; with none set every
+// trip scores 1.
+func PreferenceScore(trip models.TripPreferences, want models.PreferenceFilter) float64 {
+    considered, matched := 0, 0
+
+    check := func(want *bool, got bool) {
+        if want == nil {
+            return
+        }
+        considered++
+        if *want == got {
+            matched++
+        }
+    }
+
+    check(want.PetsAllowed, trip.PetsAllowed)
+    check(want.SmokingAllowed, trip.SmokingAllowed)
+    check(want.Music, trip.Music)
+    check(want.WomenOnly, trip.WomenOnly)
+    check(want.InstantBooking, trip.InstantBooking)
+
+    if want.LuggageSize != "" {
+        considered++
+        if models.LuggageRank(trip.LuggageSize) >= models.LuggageRank(want.LuggageSize) {
+            matched++
+        }
+    }
+
+    if considered == 0 {
+        return 1
+    }
+
+    return float64(matched) / float64(considered)
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+    "context"
+    "log"
+    "rideshare-backend/internal/events"
+    "time"
+)
+
+// DepartureReminder publishes a reminder event once for every trip that is
+// about to depart.
+type DepartureReminder struct {
+    tripService *TripService
+    broker      events.Broker
+    lead        time.Duration
+    interval    time.Duration
+}
+
+func NewDepartureReminder(tripService *TripService, broker events.Broker, lead, interval time.Duration) *DepartureReminder {
+    return &DepartureReminder{
+        tripService: tripService,
+        broker:      broker,
+        lead:        lead,
+        interval:    interval,
+    }
+}
+
+// Run blocks until ctx is cancelled, checking for departing trips every interval.
+func (r *DepartureReminder) Run(ctx context.Context) {
+    runEvery(ctx, r.interval, r.sendReminders)
+}
+
+func (r *DepartureReminder) sendReminders(ctx context.Context) {
+    trips, err := r.tripService.ClaimDueReminders(ctx, r.lead)
+    if err != nil {
+        log.Printf("Failed to claim departure reminders: %v", err)
+        return
+    }
+    
+    for _, trip := range trips {
+        event := events.New(events.TypeDepartureReminder, trip.ID, trip.DriverID, map[string]interface{}{
+            "from":          trip.FromLocation,
+            "to":            trip.ToLocation,
+            "departureTime": trip.DepartureTime,
+        })
+        if err := r.broker.Publish(events.TripTopic(trip.ID), event); err != nil {
+            log.Printf("Failed to publish departure reminder for trip %d: %v", trip.ID, err)
+        }
+    }
+}
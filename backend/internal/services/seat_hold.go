@@ -0,0 +1,215 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
+    "time"
+)
+
+// HoldSeats keeps seats on a trip for the passenger while they book, for ttl.
+// Holding again replaces the passenger's previous hold on the trip but keeps
+// its expiry, so seats can't be held forever. Holds that have expired no
+// longer count, whether or not they have been swept.
+func (s *TripService) HoldSeats(ctx context.Context, tripID, passengerID, seats int, ttl time.Duration) (*models.SeatHold, error) {
+    hold := &models.SeatHold{TripID: tripID, PassengerID: passengerID, Seats: seats}
+    var lapsed []models.SeatHold
+    err := s.write(ctx, []int{passengerID}, func(tx repository.Store) error {
+        trip, err := lockBookableTrip(ctx, tx, tripID, passengerID)
+        if err != nil {
+            return err
+        }
+        
+        lapsed, err = releaseLapsedHolds(ctx, tx, trip)
+        if err != nil {
+            return err
+        }
+        
+        holdFor := ttl
+        previous, err := tx.Bookings().GetHold(ctx, tripID, passengerID)
+        if err == nil {
+            holdFor = time.Until(previous.ExpiresAt)
+            if holdFor <= 0 {
+                return fmt.Errorf("your hold on this trip has expired")
+            }
+        } else if !errors.Is(err, repository.ErrNotFound) {
+            return err
+        }
+        
+        held, err := tx.Bookings().ReleaseHolds(ctx, tripID, passengerID)
+        if err != nil {
+            return err
+        }
+        
+        if err := checkSeatsLeft(trip.CurrentPassengers, trip.MaxPassengers-trip.HeldSeats+held, seats); err != nil {
+            return err
+        }
+        
+        return tx.Bookings().CreateHold(ctx, hold, holdFor)
+    })
+    if err != nil {
+        return nil, err
+    }
+    
+    s.router.Wrote(holders(lapsed)...)
+    
+    return hold, nil
+}
+
+// ReleaseHold gives up the passenger's hold on a trip.
+func (s *TripService) ReleaseHold(ctx context.Context, tripID, passengerID int) error {
+    return s.write(ctx, []int{passengerID}, func(tx repository.Store) error {
+        if err := lockTrip(ctx, tx, tripID); err != nil {
+            return err
+        }
+        
+        held, err := tx.Bookings().ReleaseHolds(ctx, tripID, passengerID)
+        if err != nil {
+            return err
+        }
+        
+        if held == 0 {
+            return fmt.Errorf("you have no seats held on this trip")
+        }
+        
+        // Releasing seats offered from the waitlist turns the offer down
+        entry, err := tx.Bookings().GetWaitlistEntry(ctx, tripID, passengerID)
+        if err != nil {
+            if errors.Is(err, repository.ErrNotFound) {
+                return nil
+            }
+            return err
+        }
+        
+        if entry.Status != models.WaitlistOffered {
+            return nil
+        }
+        return tx.Bookings().SetWaitlistStatus(ctx, tripID, passengerID, models.WaitlistCancelled)
+    })
+}
+
+// ReleaseExpiredHolds gives back the seats of every expired hold, expiring
+// waitlist offers that weren't taken up, and returns the trips that got
+// seats back.
+func (s *TripService) ReleaseExpiredHolds(ctx context.Context) ([]int, error) {
+    tripIDs, err := s.store.Bookings().TripsWithExpiredHolds(ctx)
+    if err != nil {
+        return nil, err
+    }
+    
+    var released []int
+    for _, tripID := range tripIDs {
+        holds, err := s.releaseExpiredHolds(ctx, tripID)
+        if err != nil {
+            return released, err
+        }
+        if len(holds) > 0 {
+            released = append(released, tripID)
+        }
+    }
+    
+    return released, nil
+}
+
+// releaseExpiredHolds releases the expired holds of one trip. Like bookings
+// it locks the trip before its holds so the two can't deadlock.
+func (s *TripService) releaseExpiredHolds(ctx context.Context, tripID int) ([]models.SeatHold, error) {
+    var holds []models.SeatHold
+    err := s.store.InTx(ctx, func(tx repository.Store) error {
+        if err := lockTrip(ctx, tx, tripID); err != nil {
+            return err
+        }
+        
+        var err error
+        holds, err = tx.Bookings().ReleaseExpiredHolds(ctx, tripID)
+        if err != nil {
+            return err
+        }
+        
+        return tx.Bookings().ExpireWaitlistOffers(ctx, tripID)
+    })
+    if err != nil {
+        return nil, err
+    }
+    
+    // The passengers whose holds lapsed see it straight away
+    s.router.Wrote(holders(holds)...)
+    return holds, nil
+}
+
+// releaseLapsedHolds gives back the seats of the trip's expired holds, which
+// the sweeper may not have got to yet, and expires the waitlist offers that
+// lapsed with them. The trip must be locked; its held seats are brought up
+// to date.
+func releaseLapsedHolds(ctx context.Context, tx repository.Store, trip *models.Trip) ([]models.SeatHold, error) {
+    lapsed, err := tx.Bookings().ReleaseExpiredHolds(ctx, trip.ID)
+    if err != nil {
+        return nil, err
+    }
+    for _, hold := range lapsed {
+        trip.HeldSeats -= hold.Seats
+    }
+    
+    if err := tx.Bookings().ExpireWaitlistOffers(ctx, trip.ID); err != nil {
+        return nil, err
+    }
+    
+    return lapsed, nil
+}
+
+// holders returns the passengers holding seats.
+func holders(holds []models.SeatHold) []int {
+    passengerIDs := make([]int, 0, len(holds))
+    for _, hold := range holds {
+        passengerIDs = append(passengerIDs, hold.PassengerID)
+    }
+    return passengerIDs
+}
+
+func lockTrip(ctx context.Context, tx repository.Store, tripID int) error {
+    if _, err := tx.Trips().GetForUpdate(ctx, tripID); err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            return fmt.Errorf("trip not found")
+        }
+        return err
+    }
+    
+    return nil
+}
+
+// SeatHoldSweeper releases seat holds that expired before the passenger
+// finished booking, offering the seats to the waitlist.
+type SeatHoldSweeper struct {
+    tripService *TripService
+    waitlist    *WaitlistService
+    interval    time.Duration
+}
+
+func NewSeatHoldSweeper(tripService *TripService, waitlist *WaitlistService, interval time.Duration) *SeatHoldSweeper {
+    return &SeatHoldSweeper{
+        tripService: tripService,
+        waitlist:    waitlist,
+        interval:    interval,
+    }
+}
+
+// Run blocks until ctx is cancelled, sweeping expired holds every interval.
+func (w *SeatHoldSweeper) Run(ctx context.Context) {
+    runEvery(ctx, w.interval, w.sweep)
+}
+
+func (w *SeatHoldSweeper) sweep(ctx context.Context) {
+    tripIDs, err := w.tripService.ReleaseExpiredHolds(ctx)
+    if err != nil {
+        log.Printf("Failed to release expired seat holds: %v", err)
+    }
+    
+    for _, tripID := range tripIDs {
+        if err := w.waitlist.Promote(ctx, tripID); err != nil {
+            log.Printf("Failed to promote waitlist of trip %d: %v", tripID, err)
+        }
+    }
+}
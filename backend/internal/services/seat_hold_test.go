@@ -0,0 +1,95 @@
+package services
+
+import (
+    "context"
+    "rideshare-backend/internal/repository"
+    "testing"
+    "time"
+)
+
+func TestHoldingAgainKeepsExpiry(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    trips := newTestTripService(store)
+
+    driver := newTestUser(t, store, "driver")
+    passenger := newTestUser(t, store, "passenger")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+
+    first, err := trips.HoldSeats(ctx, trip.ID, passenger.ID, 1, time.Minute)
+    if err != nil {
+        t.Fatalf("HoldSeats: %v", err)
+    }
+    second, err := trips.HoldSeats(ctx, trip.ID, passenger.ID, 2, time.Hour)
+    if err != nil {
+        t.Fatalf("HoldSeats again: %v", err)
+    }
+    if second.ExpiresAt.Sub(first.ExpiresAt) > time.Second {
+        t.Errorf("holding again moved the expiry from %v to %v", first.ExpiresAt, second.ExpiresAt)
+    }
+
+    stored, _ := store.Trips().Get(ctx, trip.ID)
+    if stored.HeldSeats != 2 {
+        t.Errorf("held seats = %d, want 2", stored.HeldSeats)
+    }
+}
+
+func TestExpiredHoldFreesSeatsBeforeSweep(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    trips := newTestTripService(store)
+
+    driver := newTestUser(t, store, "driver")
+    passenger := newTestUser(t, store, "passenger")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+
+    if _, err := trips.HoldSeats(ctx, trip.ID, passenger.ID, 2, time.Millisecond); err != nil {
+        t.Fatalf("HoldSeats: %v", err)
+    }
+    time.Sleep(5 * time.Millisecond)
+
+    // The expired hold isn't swept yet, but its seats are free again
+    hold, err := trips.HoldSeats(ctx, trip.ID, passenger.ID, 3, time.Hour)
+    if err != nil {
+        t.Fatalf("HoldSeats after expiry: %v", err)
+    }
+    if time.Until(hold.ExpiresAt) < 59*time.Minute {
+        t.Errorf("new hold expires at %v, want a full hour", hold.ExpiresAt)
+    }
+    if _, err := trips.JoinTrip(ctx, trip.ID, passenger.ID, 3, nil); err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+
+    stored, _ := store.Trips().Get(ctx, trip.ID)
+    if stored.CurrentPassengers != 3 || stored.HeldSeats != 0 {
+        t.Errorf("current passengers = %d, held seats = %d, want 3 and 0", stored.CurrentPassengers, stored.HeldSeats)
+    }
+}
+
+func TestJoinTripBooksSeatsOfExpiredHold(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    trips := newTestTripService(store)
+
+    driver := newTestUser(t, store, "driver")
+    holder := newTestUser(t, store, "holder")
+    passenger := newTestUser(t, store, "passenger")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+
+    if _, err := trips.HoldSeats(ctx, trip.ID, holder.ID, 2, time.Millisecond); err != nil {
+        t.Fatalf("HoldSeats: %v", err)
+    }
+    time.Sleep(5 * time.Millisecond)
+
+    if _, err := trips.JoinTrip(ctx, trip.ID, passenger.ID, 2, nil); err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+
+    released, err := trips.ReleaseExpiredHolds(ctx)
+    if err != nil {
+        t.Fatalf("ReleaseExpiredHolds: %v", err)
+    }
+    if len(released) != 0 {
+        t.Errorf("released trips = %v, want the hold already released", released)
+    }
+}
@@ -1,347 +1,663 @@
 package services
 
 import (
-    "database/sql"
+    "context"
+    "errors"
     "fmt"
     "rideshare-backend/internal/models"
-    "strings"
-   
+    "rideshare-backend/internal/repository"
+    "time"
 )
 
+// ErrInvalidCursor is returned for a page cursor that wasn't issued by us.
+var ErrInvalidCursor = repository.ErrInvalidCursor
+
 type TripService struct {
-    db *sql.DB
+    store   repository.Store
+    router  *repository.Router
+    request models.RequestInfo
 }
 
-func NewTripService(db *sql.DB) *TripService {
-    return &TripService{db: db}
+// NewTripService makes changes on the router's primary and runs searches and
+// trip listings on its replica.
+func NewTripService(router *repository.Router) *TripService {
+    return &TripService{store: router.Primary(), router: router}
 }
 
-func (s *TripService) CreateTrip(trip *models.Trip) error {
-    query := `
-        INSERT INTO trips (driver_id, from_location, to_location, departure_time, max_passengers, price_per_person, description, status, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
-        RETURNING id, created_at, updated_at
-    `
-    
-    err := s.db.QueryRow(
-        query,
-        trip.DriverID,
-        trip.FromLocation,
-        trip.ToLocation,
-        trip.DepartureTime,
-        trip.MaxPassengers,
-        trip.PricePerPerson,
-        trip.Description,
-        "active",
-    ).Scan(&trip.ID, &trip.CreatedAt, &trip.UpdatedAt)
-    
-    if err != nil {
-        return fmt.Errorf("failed to create trip: %w", err)
+// ForRequest returns a copy of the service recording the changes it makes in
+// the audit log as coming from req.
+func (s *TripService) ForRequest(req models.RequestInfo) *TripService {
+    clone := *s
+    clone.request = req
+    return &clone
+}
+
+// write runs fn in a transaction and, once it commits, has the users read
+// from the primary for a while so they see what changed.
+func (s *TripService) write(ctx context.Context, userIDs []int, fn func(tx repository.Store) error) error {
+    if err := s.store.InTx(ctx, fn); err != nil {
+        return err
     }
     
+    s.router.Wrote(userIDs...)
     return nil
 }
 
-func (s *TripService) GetUserTrips(userID int) ([]models.Trip, error) {
-    query := `
-        SELECT 
-            t.id, t.driver_id, t.from_location, t.to_location, 
-            t.departure_time, t.max_passengers, t.current_passengers,
-            t.price_per_person, t.description, t.status,
-            t.created_at, t.updated_at,
-            u.name, u.email, u.phone
-        FROM trips t
-        LEFT JOIN users u ON t.driver_id = u.id
-        WHERE t.driver_id = $1
-        ORDER BY t.departure_time DESC
-    `
-    
-    rows, err := s.db.Query(query, userID)
-    if err != nil {
-        fmt.Printf("Database query error: %v", err)
-        return nil, fmt.Errorf("failed to get user trips: %w", err)
-    }
-    defer rows.Close()
-    
-    var trips []models.Trip
-    for rows.Next() {
-        var trip models.Trip
-        var driverName, driverEmail, driverPhone sql.NullString
-        
-        err := rows.Scan(
-            &trip.ID,
-            &trip.DriverID,
-            &trip.FromLocation,
-            &trip.ToLocation,
-            &trip.DepartureTime,
-            &trip.MaxPassengers,
-            &trip.CurrentPassengers,
-            &trip.PricePerPerson,
-            &trip.Description,
-            &trip.Status,
-            &trip.CreatedAt,
-            &trip.UpdatedAt,
-            &driverName,
-            &driverEmail,
-            &driverPhone,
-        )
-        if err != nil {
-            fmt.Printf("Row scan error: %v", err)
-            return nil, fmt.Errorf("failed to scan trip: %w", err)
-        }
-        
-        // Create driver info if available
-        if driverName.Valid {
-            trip.Driver = &models.User{
-                ID:    trip.DriverID,
-                Name:  driverName.String,
-                Email: driverEmail.String,
-                Phone: driverPhone.String,
-            }
+func (s *TripService) CreateTrip(ctx context.Context, trip *models.Trip) error {
+    trip.Status = "active"
+    
+    return s.write(ctx, []int{trip.DriverID}, func(tx repository.Store) error {
+        if err := tx.Trips().Create(ctx, trip); err != nil {
+            return err
         }
         
-        trips = append(trips, trip)
-    }
-    
-    return trips, nil
+        changes, _ := diffTrip(&models.Trip{}, trip, 0)
+        return auditTrip(ctx, tx, s.request, trip.ID, trip.DriverID, models.AuditCreate, createdFields(changes))
+    })
 }
 
-func (s *TripService) GetTripByID(id int) (*models.Trip, error) {
-    trip := &models.Trip{}
-    driver := &models.User{}
-    
-    query := `
-        SELECT t.id, t.driver_id, t.from_location, t.to_location, t.departure_time,
-               t.max_passengers, t.current_passengers, t.price_per_person, t.description,
-               t.status, t.created_at, t.updated_at,
-               u.id, u.name, u.email, u.phone
-        FROM trips t
-        LEFT JOIN users u ON t.driver_id = u.id
-        WHERE t.id = $1
-    `
-    
-    err := s.db.QueryRow(query, id).Scan(
-        &trip.ID,
-        &trip.DriverID,
-        &trip.FromLocation,
-        &trip.ToLocation,
-        &trip.DepartureTime,
-        &trip.MaxPassengers,
-        &trip.CurrentPassengers,
-        &trip.PricePerPerson,
-        &trip.Description,
-        &trip.Status,
-        &trip.CreatedAt,
-        &trip.UpdatedAt,
-        &driver.ID,
-        &driver.Name,
-        &driver.Email,
-        &driver.Phone,
-    )
-    
+// GetUserTrips pages through the trips the user drives, latest departure
+// first.
+func (s *TripService) GetUserTrips(ctx context.Context, userID int, cursor string, limit int) (*models.TripPage, error) {
+    return s.router.Reader(userID).Trips().ListByDriver(ctx, userID, cursor, limit)
+}
+
+// GetTimeline pages through the trips the user drives or has booked, with
+// their role and booking status on each and the other confirmed passengers.
+// Upcoming trips come soonest first, past trips latest first. Cancelled
+// bookings are left out unless asked for.
+func (s *TripService) GetTimeline(ctx context.Context, userID int, filter models.TimelineFilter) (*models.TripPage, error) {
+    store := s.router.Reader(userID)
+    page, err := store.Trips().Timeline(ctx, userID, filter)
     if err != nil {
-        if err == sql.ErrNoRows {
-            return nil, fmt.Errorf("trip not found")
-        }
-        return nil, fmt.Errorf("failed to get trip: %w", err)
+        return nil, err
     }
     
-    trip.Driver = driver
-    return trip, nil
+    if err := attachCoPassengers(ctx, store, page.Trips, userID); err != nil {
+        return nil, err
+    }
+    
+    return page, nil
 }
 
-func (s *TripService) SearchTrips(from, to, departureDate string, maxPrice float64) ([]models.Trip, error) {
-    var conditions []string
-    var args []interface{}
-    argIndex := 1
+// attachCoPassengers fills in the confirmed passengers of each trip other
+// than the user, showing only their public profile.
+func attachCoPassengers(ctx context.Context, store repository.Store, trips []models.Trip, userID int) error {
+    if len(trips) == 0 {
+        return nil
+    }
     
-    baseQuery := `
-        SELECT t.id, t.driver_id, t.from_location, t.to_location, t.departure_time,
-               t.max_passengers, t.current_passengers, t.price_per_person, t.description,
-               t.status, t.created_at, t.updated_at,
-               u.id, u.name, u.email, u.phone
-        FROM trips t
-        LEFT JOIN users u ON t.driver_id = u.id
-        WHERE t.status = 'active' AND t.current_passengers < t.max_passengers
-    `
+    index := make(map[int]*models.Trip, len(trips))
+    tripIDs := make([]int, len(trips))
+    for i := range trips {
+        index[trips[i].ID] = &trips[i]
+        tripIDs[i] = trips[i].ID
+    }
     
-    if from != "" {
-        conditions = append(conditions, fmt.Sprintf("LOWER(t.from_location) LIKE LOWER($%d)", argIndex))
-        args = append(args, "%"+from+"%")
-        argIndex++
+    passengers, err := store.Bookings().CoPassengers(ctx, tripIDs, userID)
+    if err != nil {
+        return err
     }
     
-    if to != "" {
-        conditions = append(conditions, fmt.Sprintf("LOWER(t.to_location) LIKE LOWER($%d)", argIndex))
-        args = append(args, "%"+to+"%")
-        argIndex++
+    for _, passenger := range passengers {
+        trip := index[passenger.TripID]
+        trip.Passengers = append(trip.Passengers, models.TripPassenger{PassengerID: passenger.PassengerID, Passenger: passenger.Passenger})
     }
     
-    if departureDate != "" {
-        conditions = append(conditions, fmt.Sprintf("DATE(t.departure_time) = $%d", argIndex))
-        args = append(args, departureDate)
-        argIndex++
+    return nil
+}
+
+// GetTripByID loads a trip with the manifest the viewer may see: every open
+// booking for the driver, the other confirmed passengers for a confirmed
+// passenger and none for anyone else.
+func (s *TripService) GetTripByID(ctx context.Context, id, viewerID int) (*models.Trip, error) {
+    trip, err := s.store.Trips().Get(ctx, id)
+    if err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            return nil, fmt.Errorf("trip not found")
+        }
+        return nil, err
     }
     
-    if maxPrice > 0 {
-        conditions = append(conditions, fmt.Sprintf("t.price_per_person <= $%d", argIndex))
-        args = append(args, maxPrice)
-        argIndex++
+    if trip.DriverID == viewerID {
+        trip.Passengers, err = s.store.Bookings().Manifest(ctx, id)
+        if err != nil {
+            return nil, err
+        }
+        return trip, nil
     }
     
-    if len(conditions) > 0 {
-        baseQuery += " AND " + strings.Join(conditions, " AND ")
+    ok, err := s.IsParticipant(ctx, id, viewerID)
+    if err != nil {
+        return nil, err
     }
     
-    baseQuery += " ORDER BY t.departure_time ASC"
+    if ok {
+        trips := []models.Trip{*trip}
+        if err := attachCoPassengers(ctx, s.store, trips, viewerID); err != nil {
+            return nil, err
+        }
+        trip.Passengers = trips[0].Passengers
+    }
     
-    rows, err := s.db.Query(baseQuery, args...)
-    if err != nil {
-        return nil, fmt.Errorf("failed to search trips: %w", err)
-    }
-    defer rows.Close()
-    
-    var trips []models.Trip
-    for rows.Next() {
-        var trip models.Trip
-        var driver models.User
-        
-        err := rows.Scan(
-            &trip.ID,
-            &trip.DriverID,
-            &trip.FromLocation,
-            &trip.ToLocation,
-            &trip.DepartureTime,
-            &trip.MaxPassengers,
-            &trip.CurrentPassengers,
-            &trip.PricePerPerson,
-            &trip.Description,
-            &trip.Status,
-            &trip.CreatedAt,
-            &trip.UpdatedAt,
-            &driver.ID,
-            &driver.Name,
-            &driver.Email,
-            &driver.Phone,
-        )
+    return trip, nil
+}
+
+// SearchTrips pages through open trips matching the criteria, sorted by
+// departure unless asked otherwise. Women-only trips are only shown to women.
+func (s *TripService) SearchTrips(ctx context.Context, userID int, criteria models.TripSearchCriteria) (*models.TripPage, error) {
+    return s.router.Reader(userID).Trips().Search(ctx, userID, criteria)
+}
+
+// JoinTrip books seats for the passenger and any guests travelling with them.
+// A seat hold the passenger has on the trip, such as seats offered from the
+// waitlist, is turned into the booking unless it has expired, and seats 0
+// books the held seats, or one without a hold. Trips without instant
+// booking only record a pending request for the driver to approve, and the
+// returned status tells the two apart.
+func (s *TripService) JoinTrip(ctx context.Context, tripID, passengerID, seats int, guests []string) (string, error) {
+    var status string
+    var lapsed []models.SeatHold
+    err := s.write(ctx, []int{passengerID}, func(tx repository.Store) error {
+        trip, err := lockBookableTrip(ctx, tx, tripID, passengerID)
+        if err != nil {
+            return err
+        }
+        
+        // Seats of holds that expired are free, swept or not
+        lapsed, err = releaseLapsedHolds(ctx, tx, trip)
+        if err != nil {
+            return err
+        }
+        
+        held, err := tx.Bookings().ReleaseHolds(ctx, tripID, passengerID)
         if err != nil {
-            return nil, fmt.Errorf("failed to scan trip: %w", err)
+            return err
+        }
+        
+        // Booking takes the passenger off the waitlist, bringing along the
+        // guests they were waiting with. Only entries still waiting or with
+        // a live offer count, lapsed offers were expired above.
+        entry, err := tx.Bookings().GetWaitlistEntry(ctx, tripID, passengerID)
+        if err != nil && !errors.Is(err, repository.ErrNotFound) {
+            return err
+        }
+        if err == nil {
+            if err := tx.Bookings().SetWaitlistStatus(ctx, tripID, passengerID, models.WaitlistBooked); err != nil {
+                return err
+            }
+            if guests == nil {
+                guests = entry.Guests
+            }
+        }
+        
+        if seats == 0 {
+            seats = held
+        }
+        if seats == 0 {
+            seats = 1
+        }
+        if held > 0 && seats != held {
+            return fmt.Errorf("your hold on this trip is for %d seats", held)
+        }
+        if len(guests) > seats-1 {
+            return fmt.Errorf("%d seats can only take %d guests", seats, seats-1)
+        }
+        
+        // Seats the passenger held are theirs to take
+        if err := checkSeatsLeft(trip.CurrentPassengers, trip.MaxPassengers-trip.HeldSeats+held, seats); err != nil {
+            return err
+        }
+        
+        status = "confirmed"
+        if !trip.Preferences.InstantBooking {
+            status = "pending"
+        }
+        
+        if guests == nil {
+            guests = []string{}
         }
         
-        trip.Driver = &driver
-        trips = append(trips, trip)
+        // Add passenger to trip, reviving a previously cancelled booking if any
+        booking := &models.TripPassenger{TripID: tripID, PassengerID: passengerID, Status: status, Seats: seats, Guests: guests}
+        if err := tx.Bookings().Create(ctx, booking); err != nil {
+            return err
+        }
+        
+        changes := bookingChanges(passengerID, nil, status, nil, seats)
+        if len(guests) > 0 {
+            changes["guests"] = models.FieldChange{To: guests}
+        }
+        if err := auditTrip(ctx, tx, s.request, tripID, passengerID, models.AuditJoin, changes); err != nil {
+            return err
+        }
+        
+        // Pending requests don't take seats until the driver approves them
+        if status == "pending" {
+            return nil
+        }
+        
+        return takeSeats(ctx, tx, tripID, seats)
+    })
+    if err != nil {
+        return "", err
     }
     
-    return trips, nil
+    s.router.Wrote(holders(lapsed)...)
+    return status, nil
 }
 
-func (s *TripService) JoinTrip(tripID, passengerID int) error {
-    tx, err := s.db.Begin()
+// lockBookableTrip locks an active trip for the passenger to book or hold
+// seats on, checking they may.
+func lockBookableTrip(ctx context.Context, tx repository.Store, tripID, passengerID int) (*models.Trip, error) {
+    trip, err := tx.Trips().GetForUpdate(ctx, tripID)
+    if err != nil && !errors.Is(err, repository.ErrNotFound) {
+        return nil, fmt.Errorf("failed to check trip availability: %w", err)
+    }
+    if err != nil || trip.Status != "active" {
+        return nil, fmt.Errorf("trip not found or not active")
+    }
+    
+    passenger, err := tx.Users().GetByID(ctx, passengerID)
     if err != nil {
-        return fmt.Errorf("failed to begin transaction: %w", err)
+        if errors.Is(err, repository.ErrNotFound) {
+            return nil, fmt.Errorf("trip not found or not active")
+        }
+        return nil, fmt.Errorf("failed to check trip availability: %w", err)
     }
-    defer tx.Rollback()
     
-    // Check if trip exists and has available seats
-    var currentPassengers, maxPassengers int
-    err = tx.QueryRow(
-        "SELECT current_passengers, max_passengers FROM trips WHERE id = $1 AND status = 'active'",
-        tripID,
-    ).Scan(&currentPassengers, &maxPassengers)
+    if trip.Preferences.WomenOnly && (passenger.Gender == nil || *passenger.Gender != "female") {
+        return nil, fmt.Errorf("this trip is for women only")
+    }
     
-    if err != nil {
-        if err == sql.ErrNoRows {
-            return fmt.Errorf("trip not found or not active")
+    // Check if user already joined this trip, or was removed from it
+    booking, err := tx.Bookings().Get(ctx, tripID, passengerID)
+    if err == nil {
+        if booking.RemovedAt != nil {
+            return nil, fmt.Errorf("you were removed from this trip by the driver")
+        }
+        if booking.Status != "cancelled" {
+            return nil, fmt.Errorf("you have already joined this trip")
         }
-        return fmt.Errorf("failed to check trip availability: %w", err)
+    } else if !errors.Is(err, repository.ErrNotFound) {
+        return nil, fmt.Errorf("failed to check existing participation: %w", err)
     }
     
+    return trip, nil
+}
+
+// lockDriverTrip locks one of the driver's trips in status, returning
+// notFound if there's no such trip.
+func lockDriverTrip(ctx context.Context, tx repository.Store, tripID, driverID int, status string, notFound error) (*models.Trip, error) {
+    trip, err := tx.Trips().GetForUpdate(ctx, tripID)
+    if err != nil && !errors.Is(err, repository.ErrNotFound) {
+        return nil, err
+    }
+    if err != nil || trip.DriverID != driverID || (status != "" && trip.Status != status) {
+        return nil, notFound
+    }
+    
+    return trip, nil
+}
+
+// lockBooking locks the trip, then loads the passenger's booking on it
+// unless it was cancelled, returning notFound if there's none. Bookings are
+// always changed with their trip locked so the two can't deadlock.
+func lockBooking(ctx context.Context, tx repository.Store, tripID, passengerID int, notFound error) (*models.TripPassenger, error) {
+    if _, err := tx.Trips().GetForUpdate(ctx, tripID); err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            return nil, notFound
+        }
+        return nil, err
+    }
+    
+    booking, err := tx.Bookings().Get(ctx, tripID, passengerID)
+    if err != nil && !errors.Is(err, repository.ErrNotFound) {
+        return nil, err
+    }
+    if err != nil || booking.Status == "cancelled" {
+        return nil, notFound
+    }
+    
+    return booking, nil
+}
+
+// ApproveBooking confirms a pending booking request on one of the driver's
+// trips, provided enough seats are still free.
+func (s *TripService) ApproveBooking(ctx context.Context, tripID, driverID, passengerID int) error {
+    return s.write(ctx, []int{driverID, passengerID}, func(tx repository.Store) error {
+        trip, err := lockDriverTrip(ctx, tx, tripID, driverID, "active", fmt.Errorf("trip not found, unauthorized or not active"))
+        if err != nil {
+            return err
+        }
+        
+        booking, err := tx.Bookings().Get(ctx, tripID, passengerID)
+        if err != nil && !errors.Is(err, repository.ErrNotFound) {
+            return err
+        }
+        if err != nil || booking.Status != "pending" {
+            return fmt.Errorf("no pending booking request from this passenger")
+        }
+        
+        if err := checkSeatsLeft(trip.CurrentPassengers, trip.MaxPassengers-trip.HeldSeats, booking.Seats); err != nil {
+            return err
+        }
+        
+        if err := tx.Bookings().SetStatus(ctx, tripID, passengerID, "confirmed"); err != nil {
+            return err
+        }
+        
+        if err := takeSeats(ctx, tx, tripID, booking.Seats); err != nil {
+            return err
+        }
+        
+        return auditTrip(ctx, tx, s.request, tripID, driverID, models.AuditApprove, bookingChanges(passengerID, "pending", "confirmed", booking.Seats, booking.Seats))
+    })
+}
+
+// DeclineBooking turns down a pending booking request on one of the driver's
+// trips.
+func (s *TripService) DeclineBooking(ctx context.Context, tripID, driverID, passengerID int) error {
+    noRequest := fmt.Errorf("no pending booking request from this passenger")
+    
+    return s.write(ctx, []int{driverID, passengerID}, func(tx repository.Store) error {
+        if _, err := lockDriverTrip(ctx, tx, tripID, driverID, "", noRequest); err != nil {
+            return err
+        }
+        
+        booking, err := tx.Bookings().Get(ctx, tripID, passengerID)
+        if err != nil && !errors.Is(err, repository.ErrNotFound) {
+            return err
+        }
+        if err != nil || booking.Status != "pending" {
+            return noRequest
+        }
+        
+        if err := tx.Bookings().SetStatus(ctx, tripID, passengerID, "cancelled"); err != nil {
+            return err
+        }
+        
+        return auditTrip(ctx, tx, s.request, tripID, driverID, models.AuditDecline, bookingChanges(passengerID, "pending", "cancelled", booking.Seats, booking.Seats))
+    })
+}
+
+func checkSeatsLeft(currentPassengers, maxPassengers, seats int) error {
     if currentPassengers >= maxPassengers {
         return fmt.Errorf("trip is full")
     }
     
-    // Check if user already joined this trip
-    var existingID int
-    err = tx.QueryRow(
-        "SELECT id FROM trip_passengers WHERE trip_id = $1 AND passenger_id = $2",
-        tripID, passengerID,
-    ).Scan(&existingID)
-    
-    if err == nil {
-        return fmt.Errorf("you have already joined this trip")
-    } else if err != sql.ErrNoRows {
-        return fmt.Errorf("failed to check existing participation: %w", err)
+    if left := maxPassengers - currentPassengers; seats > left {
+        return fmt.Errorf("only %d seats left on this trip", left)
     }
     
-    // Add passenger to trip
-    _, err = tx.Exec(
-        "INSERT INTO trip_passengers (trip_id, passenger_id, status, joined_at) VALUES ($1, $2, 'confirmed', NOW())",
-        tripID, passengerID,
-    )
-    if err != nil {
-        return fmt.Errorf("failed to join trip: %w", err)
+    return nil
+}
+
+// takeSeats reserves seats on a trip, failing if they were taken meanwhile.
+func takeSeats(ctx context.Context, tx repository.Store, tripID, seats int) error {
+    if err := tx.Trips().TakeSeats(ctx, tripID, seats); err != nil {
+        if errors.Is(err, repository.ErrOverCapacity) {
+            return fmt.Errorf("trip is full")
+        }
+        return err
     }
     
-    // Update current passenger count
-    _, err = tx.Exec(
-        "UPDATE trips SET current_passengers = current_passengers + 1, updated_at = NOW() WHERE id = $1",
-        tripID,
-    )
+    return nil
+}
+
+// LeaveTrip cancels the passenger's booking, or their pending request, and
+// frees the seats if they were taken.
+func (s *TripService) LeaveTrip(ctx context.Context, tripID, passengerID int) error {
+    _, _, err := s.CancelSeats(ctx, tripID, passengerID, 0, nil)
+    return err
+}
+
+// CancelSeats gives up seats of the passenger's booking, all of them when
+// seats is 0, leaving the trip if none are left. guests, if set, names the
+// guests keeping their seats; otherwise the last guests named lose theirs. It
+// returns the status the booking had and the seats it holds now.
+func (s *TripService) CancelSeats(ctx context.Context, tripID, passengerID, seats int, guests []string) (string, int, error) {
+    var status string
+    var remaining int
+    err := s.write(ctx, []int{passengerID}, func(tx repository.Store) error {
+        var err error
+        status, remaining, err = cancelSeats(ctx, tx, s.request, tripID, passengerID, seats, guests)
+        return err
+    })
     if err != nil {
-        return fmt.Errorf("failed to update passenger count: %w", err)
+        return "", 0, err
     }
     
-    return tx.Commit()
+    return status, remaining, nil
 }
 
-func (s *TripService) UpdateTrip(trip *models.Trip) error {
-    query := `
-        UPDATE trips
-        SET from_location = $1, to_location = $2, departure_time = $3,
-            max_passengers = $4, price_per_person = $5, description = $6, updated_at = NOW()
-        WHERE id = $7 AND driver_id = $8
-        RETURNING updated_at
-    `
-    
-    err := s.db.QueryRow(
-        query,
-        trip.FromLocation,
-        trip.ToLocation,
-        trip.DepartureTime,
-        trip.MaxPassengers,
-        trip.PricePerPerson,
-        trip.Description,
-        trip.ID,
-        trip.DriverID,
-    ).Scan(&trip.UpdatedAt)
+func cancelSeats(ctx context.Context, tx repository.Store, req models.RequestInfo, tripID, passengerID, seats int, guests []string) (string, int, error) {
+    booking, err := lockBooking(ctx, tx, tripID, passengerID, fmt.Errorf("you have not joined this trip"))
+    if err != nil {
+        return "", 0, err
+    }
     
+    status, booked := booking.Status, booking.Seats
+    if seats == 0 {
+        seats = booked
+    }
+    if seats > booked {
+        return "", 0, fmt.Errorf("you have only booked %d seats", booked)
+    }
+    
+    remaining := booked - seats
+    if guests == nil {
+        guests = booking.Guests
+        if remaining > 0 && len(guests) > remaining-1 {
+            guests = guests[:remaining-1]
+        }
+    } else if len(guests) > remaining-1 {
+        return "", 0, fmt.Errorf("%d seats can only take %d guests", remaining, remaining-1)
+    }
+    
+    if remaining == 0 {
+        err = tx.Bookings().SetStatus(ctx, tripID, passengerID, "cancelled")
+    } else {
+        err = tx.Bookings().SetSeats(ctx, tripID, passengerID, remaining, guests)
+    }
     if err != nil {
-        if err == sql.ErrNoRows {
-            return fmt.Errorf("trip not found or unauthorized")
+        return "", 0, err
+    }
+    
+    if status == "confirmed" {
+        if err := tx.Trips().ReleaseSeats(ctx, tripID, seats); err != nil {
+            return "", 0, err
         }
-        return fmt.Errorf("failed to update trip: %w", err)
     }
     
-    return nil
+    newStatus := status
+    if remaining == 0 {
+        newStatus = "cancelled"
+    }
+    changes := bookingChanges(passengerID, status, newStatus, booked, remaining)
+    if remaining > 0 {
+        changes["guests"] = models.FieldChange{From: booking.Guests, To: guests}
+    }
+    if err := auditTrip(ctx, tx, req, tripID, passengerID, models.AuditLeave, changes); err != nil {
+        return "", 0, err
+    }
+    
+    return status, remaining, nil
 }
 
-func (s *TripService) DeleteTrip(tripID, driverID int) error {
-    result, err := s.db.Exec(
-        "UPDATE trips SET status = 'cancelled', updated_at = NOW() WHERE id = $1 AND driver_id = $2",
-        tripID, driverID,
-    )
+// RemovePassenger cancels a booking on one of the driver's active trips,
+// recording the reason, and frees its seats if they were taken. The passenger
+// can't book the trip again. It returns the status the booking had.
+func (s *TripService) RemovePassenger(ctx context.Context, tripID, driverID, passengerID int, reason string) (string, error) {
+    var status string
+    err := s.write(ctx, []int{driverID, passengerID}, func(tx repository.Store) error {
+        if _, err := lockDriverTrip(ctx, tx, tripID, driverID, "active", fmt.Errorf("trip not found, unauthorized or not active")); err != nil {
+            return err
+        }
+        
+        booking, err := lockBooking(ctx, tx, tripID, passengerID, fmt.Errorf("this passenger has not booked this trip"))
+        if err != nil {
+            return err
+        }
+        status = booking.Status
+        
+        if err := tx.Bookings().Remove(ctx, tripID, passengerID, reason); err != nil {
+            return err
+        }
+        
+        if status == "confirmed" {
+            if err := tx.Trips().ReleaseSeats(ctx, tripID, booking.Seats); err != nil {
+                return err
+            }
+        }
+        
+        changes := bookingChanges(passengerID, status, "cancelled", booking.Seats, booking.Seats)
+        changes["removalReason"] = models.FieldChange{To: reason}
+        return auditTrip(ctx, tx, s.request, tripID, driverID, models.AuditRemove, changes)
+    })
     if err != nil {
-        return fmt.Errorf("failed to delete trip: %w", err)
+        return "", err
     }
     
-    rowsAffected, err := result.RowsAffected()
+    return status, nil
+}
+
+// IsParticipant reports whether the user is the driver or a confirmed
+// passenger of the trip.
+func (s *TripService) IsParticipant(ctx context.Context, tripID, userID int) (bool, error) {
+    return s.store.Trips().IsParticipant(ctx, tripID, userID)
+}
+
+// StartTrip moves an active trip into progress so the driver can share their
+// location.
+func (s *TripService) StartTrip(ctx context.Context, tripID, driverID int) error {
+    return s.write(ctx, []int{driverID}, func(tx repository.Store) error {
+        if _, err := lockDriverTrip(ctx, tx, tripID, driverID, "active", fmt.Errorf("trip not found, unauthorized or not active")); err != nil {
+            return err
+        }
+        
+        if err := tx.Trips().SetStatus(ctx, tripID, "in_progress"); err != nil {
+            return err
+        }
+        
+        changes := map[string]models.FieldChange{"status": {From: "active", To: "in_progress"}}
+        return auditTrip(ctx, tx, s.request, tripID, driverID, models.AuditStart, changes)
+    })
+}
+
+// CompleteTrip ends an in-progress trip and discards its location history.
+func (s *TripService) CompleteTrip(ctx context.Context, tripID, driverID int) error {
+    return s.write(ctx, []int{driverID}, func(tx repository.Store) error {
+        if _, err := lockDriverTrip(ctx, tx, tripID, driverID, "in_progress", fmt.Errorf("trip not found, unauthorized or not in progress")); err != nil {
+            return err
+        }
+        
+        if err := tx.Trips().SetStatus(ctx, tripID, "completed"); err != nil {
+            return err
+        }
+        
+        if err := tx.Trips().DeleteLocations(ctx, tripID); err != nil {
+            return err
+        }
+        
+        changes := map[string]models.FieldChange{"status": {From: "in_progress", To: "completed"}}
+        return auditTrip(ctx, tx, s.request, tripID, driverID, models.AuditComplete, changes)
+    })
+}
+
+// GetActiveTripIDsForUser returns the active or in-progress trips the user
+// drives or has a confirmed seat on.
+func (s *TripService) GetActiveTripIDsForUser(ctx context.Context, userID int) ([]int, error) {
+    return s.store.Trips().ActiveIDsForUser(ctx, userID)
+}
+
+// GetDriverStats sums up the trips the user has driven.
+func (s *TripService) GetDriverStats(ctx context.Context, userID int) (*models.UserStats, error) {
+    return s.store.Trips().DriverStats(ctx, userID)
+}
+
+// ClaimDueReminders marks active trips departing within lead as reminded and
+// returns them, so each trip is only reminded once.
+func (s *TripService) ClaimDueReminders(ctx context.Context, lead time.Duration) ([]models.Trip, error) {
+    return s.store.Trips().ClaimDueReminders(ctx, lead)
+}
+
+// ErrSeatsBooked is returned when a trip update would leave fewer seats than
+// passengers have booked.
+var ErrSeatsBooked = errors.New("maxPassengers is below the seats already booked")
+
+// UpdateTrip saves the driver's changes to a trip that hasn't started yet and
+// records what changed. Capacity can't drop below the seats booked. Changes moving the departure
+// by more than materialShift, or changing the route or raising the price,
+// are material: every booked passenger is asked to keep or cancel their
+// booking. It returns the change, or nil when nothing changed.
+func (s *TripService) UpdateTrip(ctx context.Context, trip *models.Trip, materialShift time.Duration) (*models.TripChange, error) {
+    var change *models.TripChange
+    err := s.write(ctx, []int{trip.DriverID}, func(tx repository.Store) error {
+        old, err := lockDriverTrip(ctx, tx, trip.ID, trip.DriverID, "active", ErrTripNotActive)
+        if err != nil {
+            return err
+        }
+        
+        if trip.MaxPassengers < old.CurrentPassengers {
+            return ErrSeatsBooked
+        }
+        
+        if err := tx.Trips().Update(ctx, trip); err != nil {
+            // Seats held by passengers checking out count as taken too
+            if errors.Is(err, repository.ErrOverCapacity) {
+                return ErrSeatsBooked
+            }
+            return err
+        }
+        
+        // The handler priced a shared trip without knowing who has booked
+        if err := tx.Trips().RepriceSharedFare(ctx, trip.ID); err != nil {
+            return err
+        }
+        
+        updated, err := tx.Trips().GetForUpdate(ctx, trip.ID)
+        if err != nil {
+            return fmt.Errorf("failed to get trip: %w", err)
+        }
+        *trip = *updated
+        
+        change, err = recordTripChange(ctx, tx, old, trip, materialShift)
+        if err != nil {
+            return err
+        }
+        
+        if change == nil {
+            return nil
+        }
+        return auditTrip(ctx, tx, s.request, trip.ID, trip.DriverID, models.AuditUpdate, change.Changes)
+    })
     if err != nil {
-        return fmt.Errorf("failed to get affected rows: %w", err)
-    }
-    
-    if rowsAffected == 0 {
-        return fmt.Errorf("trip not found or unauthorized")
+        return nil, err
     }
     
-    return nil
-}
\ No newline at end of file
+    return change, nil
+}
+
+// ErrTripNotActive is returned when the driver changes a trip that isn't
+// theirs, or has already started, finished or been cancelled.
+var ErrTripNotActive = errors.New("trip not found, unauthorized or not active")
+
+// DeleteTrip cancels a trip that hasn't started yet. Trips under way or over
+// can't be cancelled, as their passengers would be refunded for a ride they
+// took.
+func (s *TripService) DeleteTrip(ctx context.Context, tripID, driverID int) error {
+    return s.write(ctx, []int{driverID}, func(tx repository.Store) error {
+        trip, err := lockDriverTrip(ctx, tx, tripID, driverID, "active", ErrTripNotActive)
+        if err != nil {
+            return err
+        }
+        
+        if err := tx.Trips().SetStatus(ctx, tripID, "cancelled"); err != nil {
+            return err
+        }
+        
+        changes := map[string]models.FieldChange{"status": {From: trip.Status, To: "cancelled"}}
+        return auditTrip(ctx, tx, s.request, tripID, driverID, models.AuditCancel, changes)
+    })
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
+    "time"
+)
+
+// diffTrip lists the fields that differ between two versions of a trip, and
+// whether the difference is material to passengers who booked the old one.
+func diffTrip(old, updated *models.Trip, materialShift time.Duration) (map[string]models.FieldChange, bool) {
+    changes := map[string]models.FieldChange{}
+    material := false
+    
+    record := func(field string, from, to interface{}) {
+        changes[field] = models.FieldChange{From: from, To: to}
+    }
+    
+    if old.FromLocation != updated.FromLocation {
+        record("from", old.FromLocation, updated.FromLocation)
+        material = true
+    }
+    if old.ToLocation != updated.ToLocation {
+        record("to", old.ToLocation, updated.ToLocation)
+        material = true
+    }
+    
+    if !old.DepartureTime.Equal(updated.DepartureTime) {
+        record("departureTime", old.DepartureTime, updated.DepartureTime)
+        
+        shift := updated.DepartureTime.Sub(old.DepartureTime)
+        if shift < 0 {
+            shift = -shift
+        }
+        if shift > materialShift {
+            material = true
+        }
+    }
+    if old.Timezone != updated.Timezone {
+        record("timezone", old.Timezone, updated.Timezone)
+    }
+    
+    if old.PricePerPerson != updated.PricePerPerson {
+        record("pricePerPerson", old.PricePerPerson, updated.PricePerPerson)
+        if old.PricePerPerson.Currency != updated.PricePerPerson.Currency || updated.PricePerPerson.Amount > old.PricePerPerson.Amount {
+            material = true
+        }
+    }
+    
+    if old.MaxPassengers != updated.MaxPassengers {
+        record("maxPassengers", old.MaxPassengers, updated.MaxPassengers)
+    }
+    if old.FareMode != updated.FareMode {
+        record("fareMode", old.FareMode, updated.FareMode)
+    }
+    if totalCost(old) != totalCost(updated) {
+        record("totalCost", old.TotalCost, updated.TotalCost)
+    }
+    if intValue(old.VehicleID) != intValue(updated.VehicleID) {
+        record("vehicleId", old.VehicleID, updated.VehicleID)
+    }
+    if old.Preferences != updated.Preferences {
+        record("preferences", old.Preferences, updated.Preferences)
+    }
+    if stringValue(old.Description) != stringValue(updated.Description) {
+        record("description", old.Description, updated.Description)
+    }
+    
+    return changes, material
+}
+
+func totalCost(trip *models.Trip) string {
+    if trip.TotalCost == nil {
+        return ""
+    }
+    return trip.TotalCost.String()
+}
+
+func intValue(v *int) int {
+    if v == nil {
+        return 0
+    }
+    return *v
+}
+
+func stringValue(v *string) string {
+    if v == nil {
+        return ""
+    }
+    return *v
+}
+
+// recordTripChange audits an update to a trip. A material change also asks
+// every passenger with an open booking to respond to it. It returns nil if
+// nothing changed.
+func recordTripChange(ctx context.Context, tx repository.Store, old, updated *models.Trip, materialShift time.Duration) (*models.TripChange, error) {
+    changes, material := diffTrip(old, updated, materialShift)
+    if len(changes) == 0 {
+        return nil, nil
+    }
+    
+    driverID := updated.DriverID
+    change := &models.TripChange{TripID: updated.ID, ChangedBy: &driverID, Changes: changes, Material: material}
+    if err := tx.Trips().RecordChange(ctx, change); err != nil {
+        return nil, err
+    }
+    
+    return change, nil
+}
+
+// GetTripChanges lists the recorded updates to a trip, latest first.
+func (s *TripService) GetTripChanges(ctx context.Context, tripID int) ([]models.TripChange, error) {
+    return s.store.Trips().ListChanges(ctx, tripID)
+}
+
+// RespondToChange records whether a passenger keeps their booking after a
+// material change to the trip, cancelling it if not. It returns the status
+// the booking had, so a cancelled confirmed booking can be refunded in full.
+func (s *TripService) RespondToChange(ctx context.Context, tripID, changeID, passengerID int, response string) (string, error) {
+    notAsked := fmt.Errorf("no response is asked of you for this change")
+    
+    status := ""
+    err := s.write(ctx, []int{passengerID}, func(tx repository.Store) error {
+        trip, err := tx.Trips().GetForUpdate(ctx, tripID)
+        if err != nil {
+            if errors.Is(err, repository.ErrNotFound) {
+                return notAsked
+            }
+            return err
+        }
+        
+        previous, err := tx.Trips().GetChangeResponse(ctx, changeID, tripID, passengerID)
+        if err != nil {
+            if errors.Is(err, repository.ErrNotFound) {
+                return notAsked
+            }
+            return err
+        }
+        
+        if previous != nil {
+            return fmt.Errorf("you have already responded to this change")
+        }
+        
+        if trip.Status != "active" {
+            return fmt.Errorf("trip is no longer open to changes")
+        }
+        
+        if err := tx.Trips().SetChangeResponse(ctx, changeID, passengerID, response); err != nil {
+            return err
+        }
+        
+        if response == models.ChangeResponseCancel {
+            status, _, err = cancelSeats(ctx, tx, s.request, tripID, passengerID, 0, nil)
+        }
+        return err
+    })
+    if err != nil {
+        return "", err
+    }
+    
+    return status, nil
+}
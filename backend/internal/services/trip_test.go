@@ -0,0 +1,178 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "rideshare-backend/internal/repository"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestJoinTripTakesSeats(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    trips := newTestTripService(store)
+
+    driver := newTestUser(t, store, "driver")
+    passenger := newTestUser(t, store, "passenger")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+
+    status, err := trips.JoinTrip(ctx, trip.ID, passenger.ID, 2, []string{"Alex"})
+    if err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+    if status != "confirmed" {
+        t.Errorf("status = %q, want confirmed", status)
+    }
+
+    stored, err := store.Trips().Get(ctx, trip.ID)
+    if err != nil {
+        t.Fatalf("get trip: %v", err)
+    }
+    if stored.CurrentPassengers != 2 {
+        t.Errorf("current passengers = %d, want 2", stored.CurrentPassengers)
+    }
+
+    if _, err := trips.JoinTrip(ctx, trip.ID, passenger.ID, 1, nil); err == nil {
+        t.Error("joining twice succeeded")
+    }
+
+    other := newTestUser(t, store, "other")
+    if _, err := trips.JoinTrip(ctx, trip.ID, other.ID, 2, []string{"Sam"}); err == nil {
+        t.Error("booking more seats than left succeeded")
+    }
+}
+
+func TestJoinTripWithoutInstantBookingIsPending(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    trips := newTestTripService(store)
+
+    driver := newTestUser(t, store, "driver")
+    passenger := newTestUser(t, store, "passenger")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+    trip.Preferences.InstantBooking = false
+    if err := store.Trips().Update(ctx, trip); err != nil {
+        t.Fatalf("update trip: %v", err)
+    }
+
+    status, err := trips.JoinTrip(ctx, trip.ID, passenger.ID, 1, nil)
+    if err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+    if status != "pending" {
+        t.Errorf("status = %q, want pending", status)
+    }
+
+    stored, _ := store.Trips().Get(ctx, trip.ID)
+    if stored.CurrentPassengers != 0 {
+        t.Errorf("pending request took %d seats", stored.CurrentPassengers)
+    }
+
+    if err := trips.ApproveBooking(ctx, trip.ID, driver.ID, passenger.ID); err != nil {
+        t.Fatalf("ApproveBooking: %v", err)
+    }
+    stored, _ = store.Trips().Get(ctx, trip.ID)
+    if stored.CurrentPassengers != 1 {
+        t.Errorf("current passengers after approval = %d, want 1", stored.CurrentPassengers)
+    }
+}
+
+func TestLeaveTripFreesSeats(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    trips := newTestTripService(store)
+
+    driver := newTestUser(t, store, "driver")
+    passenger := newTestUser(t, store, "passenger")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+
+    if _, err := trips.JoinTrip(ctx, trip.ID, passenger.ID, 2, []string{"Alex"}); err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+    if err := trips.LeaveTrip(ctx, trip.ID, passenger.ID); err != nil {
+        t.Fatalf("LeaveTrip: %v", err)
+    }
+
+    stored, _ := store.Trips().Get(ctx, trip.ID)
+    if stored.CurrentPassengers != 0 {
+        t.Errorf("current passengers = %d, want 0", stored.CurrentPassengers)
+    }
+
+    ok, err := trips.IsParticipant(ctx, trip.ID, passenger.ID)
+    if err != nil {
+        t.Fatalf("IsParticipant: %v", err)
+    }
+    if ok {
+        t.Error("passenger is still a participant after leaving")
+    }
+}
+
+func TestJoinTripNeverOverbooks(t *testing.T) {
+    testJoinTripNeverOverbooks(t, repository.NewMemoryStore())
+}
+
+func TestJoinTripNeverOverbooksPostgres(t *testing.T) {
+    testJoinTripNeverOverbooks(t, newPostgresTestStore(t))
+}
+
+// testJoinTripNeverOverbooks has more passengers than there are seats join a
+// trip at once.
+func testJoinTripNeverOverbooks(t *testing.T, store repository.Store) {
+    ctx := context.Background()
+    trips := newTestTripService(store)
+
+    driver := newTestUser(t, store, "driver")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+
+    const passengers = 20
+    var wg sync.WaitGroup
+    for i := 0; i < passengers; i++ {
+        passenger := newTestUser(t, store, "passenger")
+        wg.Add(1)
+        go func(passengerID int) {
+            defer wg.Done()
+            trips.JoinTrip(ctx, trip.ID, passengerID, 1, nil)
+        }(passenger.ID)
+    }
+    wg.Wait()
+
+    stored, _ := store.Trips().Get(ctx, trip.ID)
+    if stored.CurrentPassengers != stored.MaxPassengers {
+        t.Errorf("current passengers = %d, want %d", stored.CurrentPassengers, stored.MaxPassengers)
+    }
+
+    manifest, err := store.Bookings().Manifest(ctx, trip.ID)
+    if err != nil {
+        t.Fatalf("Manifest: %v", err)
+    }
+    if len(manifest) != stored.MaxPassengers {
+        t.Errorf("%d bookings, want %d", len(manifest), stored.MaxPassengers)
+    }
+}
+
+func TestUpdateTripOnlyChangesActiveTrips(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    trips := newTestTripService(store)
+
+    driver := newTestUser(t, store, "driver")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+    if err := trips.DeleteTrip(ctx, trip.ID, driver.ID); err != nil {
+        t.Fatalf("DeleteTrip: %v", err)
+    }
+
+    trip.ToLocation = "Munich"
+    if _, err := trips.UpdateTrip(ctx, trip, time.Hour); !errors.Is(err, ErrTripNotActive) {
+        t.Errorf("UpdateTrip on a cancelled trip = %v, want ErrTripNotActive", err)
+    }
+
+    changes, err := trips.GetTripChanges(ctx, trip.ID)
+    if err != nil {
+        t.Fatalf("GetTripChanges: %v", err)
+    }
+    if len(changes) != 0 {
+        t.Errorf("recorded %d changes to a cancelled trip", len(changes))
+    }
+}
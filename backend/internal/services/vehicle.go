@@ -0,0 +1,101 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
+)
+
+type VehicleService struct {
+    store repository.Store
+}
+
+func NewVehicleService(store repository.Store) *VehicleService {
+    return &VehicleService{store: store}
+}
+
+func (s *VehicleService) CreateVehicle(ctx context.Context, vehicle *models.Vehicle) error {
+    return s.store.Vehicles().Create(ctx, vehicle)
+}
+
+func (s *VehicleService) GetUserVehicles(ctx context.Context, ownerID int) ([]models.Vehicle, error) {
+    return s.store.Vehicles().ListByOwner(ctx, ownerID)
+}
+
+// GetVehicle returns one of the owner's vehicles.
+func (s *VehicleService) GetVehicle(ctx context.Context, id, ownerID int) (*models.Vehicle, error) {
+    vehicle, err := s.store.Vehicles().Get(ctx, id, ownerID)
+    if err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            return nil, fmt.Errorf("vehicle not found")
+        }
+        return nil, err
+    }
+
+    return vehicle, nil
+}
+
+// GetTripVehicle returns the vehicle assigned to a trip, or nil if it has none.
+func (s *VehicleService) GetTripVehicle(ctx context.Context, tripID int) (*models.Vehicle, error) {
+    vehicle, err := s.store.Vehicles().GetForTrip(ctx, tripID)
+    if err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            return nil, nil
+        }
+        return nil, fmt.Errorf("failed to get trip vehicle: %w", err)
+    }
+
+    return vehicle, nil
+}
+
+// CheckCapacity verifies the driver owns the vehicle and that it has room for
+// the trip's passengers.
+func (s *VehicleService) CheckCapacity(ctx context.Context, vehicleID, driverID, maxPassengers int) error {
+    vehicle, err := s.GetVehicle(ctx, vehicleID, driverID)
+    if err != nil {
+        return err
+    }
+
+    if maxPassengers > vehicle.PassengerSeats() {
+        return fmt.Errorf("maxPassengers exceeds the %d passenger seats of this vehicle", vehicle.PassengerSeats())
+    }
+
+    return nil
+}
+
+func (s *VehicleService) UpdateVehicle(ctx context.Context, vehicle *models.Vehicle) error {
+    return s.store.InTx(ctx, func(tx repository.Store) error {
+        if err := tx.Vehicles().Update(ctx, vehicle); err != nil {
+            if errors.Is(err, repository.ErrNotFound) {
+                return fmt.Errorf("vehicle not found")
+            }
+            return err
+        }
+
+        // Fewer seats must still fit the upcoming trips using this vehicle
+        seats, err := tx.Vehicles().UpcomingSeats(ctx, vehicle.ID)
+        if err != nil {
+            return err
+        }
+
+        if seats > vehicle.PassengerSeats() {
+            return fmt.Errorf("vehicle has upcoming trips offering more than %d seats", vehicle.PassengerSeats())
+        }
+
+        return nil
+    })
+}
+
+// DeleteVehicle removes a vehicle that isn't assigned to an upcoming trip.
+func (s *VehicleService) DeleteVehicle(ctx context.Context, id, ownerID int) error {
+    if err := s.store.Vehicles().Delete(ctx, id, ownerID); err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            return fmt.Errorf("vehicle not found or assigned to an upcoming trip")
+        }
+        return err
+    }
+
+    return nil
+}
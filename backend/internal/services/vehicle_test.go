@@ -0,0 +1,108 @@
+package services
+
+import (
+    "context"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
+    "testing"
+)
+
+// newTestVehicleTrip gives the driver a car with seats and a trip in it
+// offering passengers seats.
+func newTestVehicleTrip(t *testing.T, store repository.Store, vehicles *VehicleService, seats, offered int) (*models.Vehicle, *models.Trip) {
+    t.Helper()
+    ctx := context.Background()
+
+    driver := newTestUser(t, store, "driver")
+    vehicle := &models.Vehicle{OwnerID: driver.ID, Make: "Skoda", Model: "Octavia", Colour: "grey", Plate: "B-RS 123", Seats: seats}
+    if err := vehicles.CreateVehicle(ctx, vehicle); err != nil {
+        t.Fatalf("CreateVehicle: %v", err)
+    }
+    if err := vehicles.CheckCapacity(ctx, vehicle.ID, driver.ID, offered); err != nil {
+        t.Fatalf("CheckCapacity: %v", err)
+    }
+
+    trip := newTestTrip(t, newTestTripService(store), driver.ID, offered, 1500)
+    trip.VehicleID = &vehicle.ID
+    if err := store.Trips().Update(ctx, trip); err != nil {
+        t.Fatalf("update trip: %v", err)
+    }
+
+    return vehicle, trip
+}
+
+func TestCheckCapacity(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    vehicles := NewVehicleService(store)
+    vehicle, trip := newTestVehicleTrip(t, store, vehicles, 5, 3)
+
+    if err := vehicles.CheckCapacity(ctx, vehicle.ID, trip.DriverID, 5); err == nil {
+        t.Error("offering the driver's seat succeeded")
+    }
+
+    other := newTestUser(t, store, "other")
+    if err := vehicles.CheckCapacity(ctx, vehicle.ID, other.ID, 1); err == nil {
+        t.Error("using someone else's vehicle succeeded")
+    }
+
+    found, err := vehicles.GetTripVehicle(ctx, trip.ID)
+    if err != nil {
+        t.Fatalf("GetTripVehicle: %v", err)
+    }
+    if found == nil || found.ID != vehicle.ID {
+        t.Errorf("trip vehicle = %v, want vehicle %d", found, vehicle.ID)
+    }
+}
+
+func TestUpdateVehicleKeepsUpcomingTripsSeated(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    vehicles := NewVehicleService(store)
+    vehicle, _ := newTestVehicleTrip(t, store, vehicles, 5, 3)
+
+    smaller := *vehicle
+    smaller.Seats = 3
+    if err := vehicles.UpdateVehicle(ctx, &smaller); err == nil {
+        t.Fatal("shrinking the vehicle below its upcoming trip succeeded")
+    }
+
+    stored, err := vehicles.GetVehicle(ctx, vehicle.ID, vehicle.OwnerID)
+    if err != nil {
+        t.Fatalf("GetVehicle: %v", err)
+    }
+    if stored.Seats != 5 {
+        t.Errorf("seats = %d after a failed update, want 5", stored.Seats)
+    }
+
+    smaller.Seats = 4
+    if err := vehicles.UpdateVehicle(ctx, &smaller); err != nil {
+        t.Errorf("UpdateVehicle: %v", err)
+    }
+}
+
+func TestDeleteVehicleAssignedToUpcomingTrip(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    vehicles := NewVehicleService(store)
+    vehicle, trip := newTestVehicleTrip(t, store, vehicles, 5, 3)
+
+    if err := vehicles.DeleteVehicle(ctx, vehicle.ID, vehicle.OwnerID); err == nil {
+        t.Fatal("deleting a vehicle with an upcoming trip succeeded")
+    }
+
+    if err := store.Trips().SetStatus(ctx, trip.ID, "completed"); err != nil {
+        t.Fatalf("complete trip: %v", err)
+    }
+    if err := vehicles.DeleteVehicle(ctx, vehicle.ID, vehicle.OwnerID); err != nil {
+        t.Fatalf("DeleteVehicle: %v", err)
+    }
+
+    left, err := vehicles.GetUserVehicles(ctx, vehicle.OwnerID)
+    if err != nil {
+        t.Fatalf("GetUserVehicles: %v", err)
+    }
+    if len(left) != 0 {
+        t.Errorf("%d vehicles left, want none", len(left))
+    }
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "rideshare-backend/internal/events"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
+    "time"
+)
+
+type WaitlistService struct {
+    store    repository.Store
+    router   *repository.Router
+    email    *EmailService
+    broker   events.Broker
+    offerTTL time.Duration
+}
+
+// NewWaitlistService changes waitlists on the router's primary, keeping the
+// passengers it changes reading from it for a while like TripService does.
+func NewWaitlistService(router *repository.Router, email *EmailService, broker events.Broker, offerTTL time.Duration) *WaitlistService {
+    return &WaitlistService{
+        store:    router.Primary(),
+        router:   router,
+        email:    email,
+        broker:   broker,
+        offerTTL: offerTTL,
+    }
+}
+
+// write runs fn in a transaction and, once it commits, has the users read
+// from the primary for a while so they see what changed.
+func (s *WaitlistService) write(ctx context.Context, userIDs []int, fn func(tx repository.Store) error) error {
+    if err := s.store.InTx(ctx, fn); err != nil {
+        return err
+    }
+    
+    s.router.Wrote(userIDs...)
+    return nil
+}
+
+// JoinWaitlist puts the passenger in line for seats on a trip that has too
+// few left for them.
+func (s *WaitlistService) JoinWaitlist(ctx context.Context, tripID, passengerID, seats int, guests []string) (*models.WaitlistEntry, error) {
+    if len(guests) > seats-1 {
+        return nil, fmt.Errorf("%d seats can only take %d guests", seats, seats-1)
+    }
+    if guests == nil {
+        guests = []string{}
+    }
+    
+    entry := &models.WaitlistEntry{TripID: tripID, PassengerID: passengerID, Seats: seats, Guests: guests, Status: models.WaitlistWaiting}
+    err := s.write(ctx, []int{passengerID}, func(tx repository.Store) error {
+        trip, err := lockBookableTrip(ctx, tx, tripID, passengerID)
+        if err != nil {
+            return err
+        }
+        
+        if trip.CurrentPassengers+trip.HeldSeats+seats <= trip.MaxPassengers {
+            return fmt.Errorf("seats are available on this trip, book them instead")
+        }
+        
+        if err := tx.Bookings().AddToWaitlist(ctx, entry); err != nil {
+            if errors.Is(err, repository.ErrDuplicate) {
+                return fmt.Errorf("you are already on the waitlist for this trip")
+            }
+            return err
+        }
+        
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    
+    return entry, nil
+}
+
+// LeaveWaitlist takes the passenger out of line, giving up seats they were
+// offered. It reports whether seats were given up, so they can be offered
+// to the next passenger.
+func (s *WaitlistService) LeaveWaitlist(ctx context.Context, tripID, passengerID int) (bool, error) {
+    released := 0
+    err := s.write(ctx, []int{passengerID}, func(tx repository.Store) error {
+        if err := lockTrip(ctx, tx, tripID); err != nil {
+            return err
+        }
+        
+        entry, err := tx.Bookings().GetWaitlistEntry(ctx, tripID, passengerID)
+        if err != nil {
+            if errors.Is(err, repository.ErrNotFound) {
+                return fmt.Errorf("you are not on the waitlist for this trip")
+            }
+            return err
+        }
+        
+        if err := tx.Bookings().SetWaitlistStatus(ctx, tripID, passengerID, models.WaitlistCancelled); err != nil {
+            return err
+        }
+        
+        if entry.Status == models.WaitlistOffered {
+            released, err = tx.Bookings().ReleaseHolds(ctx, tripID, passengerID)
+        }
+        return err
+    })
+    if err != nil {
+        return false, err
+    }
+    
+    return released > 0, nil
+}
+
+// waitlistOffer is seats offered to a waiting passenger, with what they need
+// to be told about it.
+type waitlistOffer struct {
+    entry     models.WaitlistEntry
+    name      string
+    email     string
+    from      string
+    to        string
+    departure time.Time
+}
+
+// Promote offers the seats free on a trip to the passengers waiting for it,
+// in the order they joined. It stops at the first passenger who needs more
+// seats than are left, so smaller parties behind them can't keep passing
+// them over. Each offer holds the seats until the confirmation deadline, and
+// the passenger is emailed and sent an event.
+func (s *WaitlistService) Promote(ctx context.Context, tripID int) error {
+    offers, err := s.promote(ctx, tripID)
+    if err != nil {
+        return err
+    }
+    
+    for _, offer := range offers {
+        s.notify(offer)
+    }
+    
+    return nil
+}
+
+func (s *WaitlistService) promote(ctx context.Context, tripID int) ([]waitlistOffer, error) {
+    var offers []waitlistOffer
+    var touched []int
+    err := s.store.InTx(ctx, func(tx repository.Store) error {
+        trip, err := tx.Trips().GetForUpdate(ctx, tripID)
+        if err != nil {
+            if errors.Is(err, repository.ErrNotFound) {
+                return fmt.Errorf("trip not found")
+            }
+            return err
+        }
+        
+        // Expired holds that haven't been swept yet would otherwise keep
+        // their seats, and their owners from being offered new ones
+        lapsed, err := releaseLapsedHolds(ctx, tx, trip)
+        if err != nil {
+            return err
+        }
+        touched = holders(lapsed)
+        
+        free := trip.MaxPassengers - trip.CurrentPassengers - trip.HeldSeats
+        if trip.Status != "active" || free <= 0 || !trip.DepartureTime.After(time.Now()) {
+            return nil
+        }
+        
+        waiting, err := tx.Bookings().Waiting(ctx, tripID)
+        if err != nil {
+            return err
+        }
+        
+        for _, entry := range waiting {
+            if entry.Seats > free {
+                break
+            }
+            free -= entry.Seats
+            
+            passenger, err := tx.Users().GetByID(ctx, entry.PassengerID)
+            if err != nil {
+                return fmt.Errorf("failed to get waiting passenger: %w", err)
+            }
+            
+            // Any hold of their own is replaced by the offer
+            if _, err := tx.Bookings().ReleaseHolds(ctx, tripID, entry.PassengerID); err != nil {
+                return err
+            }
+            
+            hold := &models.SeatHold{TripID: tripID, PassengerID: entry.PassengerID, Seats: entry.Seats}
+            if err := tx.Bookings().CreateHold(ctx, hold, s.offerTTL); err != nil {
+                return fmt.Errorf("failed to hold offered seats: %w", err)
+            }
+            
+            if err := tx.Bookings().OfferWaitlist(ctx, tripID, entry.PassengerID, hold.ExpiresAt); err != nil {
+                return fmt.Errorf("failed to offer seats: %w", err)
+            }
+            
+            touched = append(touched, entry.PassengerID)
+            entry.Status = models.WaitlistOffered
+            entry.OfferExpiresAt = &hold.ExpiresAt
+            offers = append(offers, waitlistOffer{
+                entry:     entry,
+                name:      passenger.Name,
+                email:     passenger.Email,
+                from:      trip.FromLocation,
+                to:        trip.ToLocation,
+                departure: trip.DepartureTime,
+            })
+        }
+        
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    
+    // Passengers offered seats, or whose holds lapsed, read from the primary
+    s.router.Wrote(touched...)
+    return offers, nil
+}
+
+func (s *WaitlistService) notify(offer waitlistOffer) {
+    entry := offer.entry
+    deadline := entry.OfferExpiresAt.In(offer.departure.Location())
+    
+    event := events.New(events.TypeWaitlistOffered, entry.TripID, entry.PassengerID, map[string]interface{}{
+        "seats":          entry.Seats,
+        "offerExpiresAt": deadline,
+    })
+    if err := s.broker.Publish(events.UserTopic(entry.PassengerID), event); err != nil {
+        log.Printf("Failed to publish waitlist offer for trip %d: %v", entry.TripID, err)
+    }
+    
+    err := s.email.SendWaitlistOfferEmail(offer.email, offer.name, offer.from, offer.to, offer.departure, deadline, entry.TripID, entry.Seats)
+    if err != nil {
+        log.Printf("Failed to email waitlist offer for trip %d to user %d: %v", entry.TripID, entry.PassengerID, err)
+    }
+}
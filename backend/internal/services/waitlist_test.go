@@ -0,0 +1,101 @@
+package services
+
+import (
+    "context"
+    "rideshare-backend/internal/config"
+    "rideshare-backend/internal/events"
+    "rideshare-backend/internal/models"
+    "rideshare-backend/internal/repository"
+    "testing"
+    "time"
+)
+
+func TestPromoteKeepsWaitlistOrder(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    router := repository.NewRouter(store, nil, 0)
+    trips := NewTripService(router)
+    waitlist := NewWaitlistService(router, NewEmailService(&config.Config{}), events.NewMemoryBroker(), time.Hour)
+
+    driver := newTestUser(t, store, "driver")
+    first := newTestUser(t, store, "first")
+    second := newTestUser(t, store, "second")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+
+    var booked []*models.User
+    for _, name := range []string{"a", "b", "c"} {
+        passenger := newTestUser(t, store, name)
+        if _, err := trips.JoinTrip(ctx, trip.ID, passenger.ID, 1, nil); err != nil {
+            t.Fatalf("JoinTrip: %v", err)
+        }
+        booked = append(booked, passenger)
+    }
+
+    if _, err := waitlist.JoinWaitlist(ctx, trip.ID, first.ID, 2, nil); err != nil {
+        t.Fatalf("JoinWaitlist: %v", err)
+    }
+    if _, err := waitlist.JoinWaitlist(ctx, trip.ID, second.ID, 1, nil); err != nil {
+        t.Fatalf("JoinWaitlist: %v", err)
+    }
+
+    // One seat frees up, too few for the head of the queue
+    if err := trips.LeaveTrip(ctx, trip.ID, booked[0].ID); err != nil {
+        t.Fatalf("LeaveTrip: %v", err)
+    }
+    offers, err := waitlist.promote(ctx, trip.ID)
+    if err != nil {
+        t.Fatalf("promote: %v", err)
+    }
+    if len(offers) != 0 {
+        t.Fatalf("offered seats to passenger %d ahead of the head of the queue", offers[0].entry.PassengerID)
+    }
+
+    if err := trips.LeaveTrip(ctx, trip.ID, booked[1].ID); err != nil {
+        t.Fatalf("LeaveTrip: %v", err)
+    }
+    offers, err = waitlist.promote(ctx, trip.ID)
+    if err != nil {
+        t.Fatalf("promote: %v", err)
+    }
+    if len(offers) != 1 || offers[0].entry.PassengerID != first.ID {
+        t.Fatalf("offers = %+v, want the head of the queue only", offers)
+    }
+}
+
+func TestJoinTripIgnoresLapsedWaitlistOffer(t *testing.T) {
+    ctx := context.Background()
+    store := repository.NewMemoryStore()
+    router := repository.NewRouter(store, nil, 0)
+    trips := NewTripService(router)
+    waitlist := NewWaitlistService(router, NewEmailService(&config.Config{}), events.NewMemoryBroker(), time.Millisecond)
+
+    driver := newTestUser(t, store, "driver")
+    booked := newTestUser(t, store, "booked")
+    passenger := newTestUser(t, store, "passenger")
+    trip := newTestTrip(t, trips, driver.ID, 3, 1500)
+
+    if _, err := trips.JoinTrip(ctx, trip.ID, booked.ID, 3, []string{"Alex", "Kim"}); err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+    if _, err := waitlist.JoinWaitlist(ctx, trip.ID, passenger.ID, 2, []string{"Sam"}); err != nil {
+        t.Fatalf("JoinWaitlist: %v", err)
+    }
+    if _, _, err := trips.CancelSeats(ctx, trip.ID, booked.ID, 2, nil); err != nil {
+        t.Fatalf("CancelSeats: %v", err)
+    }
+    offers, err := waitlist.promote(ctx, trip.ID)
+    if err != nil || len(offers) != 1 {
+        t.Fatalf("promote = %v, %v, want one offer", offers, err)
+    }
+    time.Sleep(5 * time.Millisecond)
+
+    // With the offer lapsed, the passenger books a seat of their own
+    if _, err := trips.JoinTrip(ctx, trip.ID, passenger.ID, 0, nil); err != nil {
+        t.Fatalf("JoinTrip: %v", err)
+    }
+
+    stored, _ := store.Trips().Get(ctx, trip.ID)
+    if stored.CurrentPassengers != 2 {
+        t.Errorf("current passengers = %d, want the guest of the lapsed offer left out", stored.CurrentPassengers)
+    }
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+    "sync"
+    "time"
+)
+
+var locations sync.Map
+
+// LoadLocation returns the named IANA time zone, falling back to UTC for
+// names that can't be loaded. Zones are cached after the first lookup.
+func LoadLocation(name string) *time.Location {
+    if loc, ok := locations.Load(name); ok {
+        return loc.(*time.Location)
+    }
+
+    loc, err := time.LoadLocation(name)
+    if err != nil {
+        return time.UTC
+    }
+
+    locations.Store(name, loc)
+    return loc
+}
+
+// ValidTimezone reports whether name is a time zone we can load.
+func ValidTimezone(name string) bool {
+    if name == "" || name == "Local" {
+        return false
+    }
+    _, err := time.LoadLocation(name)
+    return err == nil
+}
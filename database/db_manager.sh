@@ -6,18 +6,16 @@ case "$1" in
         ;;
     "migrate")
         echo "Running migrations..."
-        psql $DB_URL -f migrations/001_users.sql
-        psql $DB_URL -f migrations/002_trips.sql
-        psql $DB_URL -f migrations/003_matches.sql
-        echo "Migrations completed!"
+        shift
+        (cd ../backend && DATABASE_URL=$DB_URL go run ./cmd/migrate "${@:-up}")
         ;;
     "reset")
         echo "Resetting database..."
-        psql $DB_URL -c "DROP TABLE IF EXISTS trip_passengers CASCADE;"
-        psql $DB_URL -c "DROP TABLE IF EXISTS trips CASCADE;"
-        psql $DB_URL -c "DROP TABLE IF EXISTS users CASCADE;"
-        psql $DB_URL -c "DROP FUNCTION IF EXISTS update_updated_at_column CASCADE;"
-        echo "Database reset completed!"
+        # Drop the whole schema, schema_migrations included, so that migrate
+        # up starts from scratch, then restore what init sets up
+        psql $DB_URL -v ON_ERROR_STOP=1 -1 -c "DROP SCHEMA IF EXISTS public CASCADE;" -c "CREATE SCHEMA public;" || exit 1
+        psql $DB_URL -v ON_ERROR_STOP=1 -f init.sql || exit 1
+        echo "Database reset completed! Run '$0 migrate' to recreate the tables."
         ;;
     "connect")
         echo "Connecting to database..."
@@ -26,8 +24,8 @@ case "$1" in
     *)
         echo "Usage: $0 {init|migrate|reset|connect}"
         echo "  init     - Initialize database with extensions and permissions"
-        echo "  migrate  - Run all migration files"
-        echo "  reset    - Drop all tables and functions"
+        echo "  migrate  - Run pending migrations, or a migrate command (up, down N, status, create NAME, force VERSION)"
+        echo "  reset    - Drop the schema, migration history included, and re-initialize"
         echo "  connect  - Connect to database shell"
         ;;
 esac